@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/agalue/gominion/api"
+)
+
+// HealthResponse represents the outcome of the health checks
+type HealthResponse struct {
+	Status string            `json:"status"` // Either ok or failed
+	Checks map[string]string `json:"checks"` // The outcome of each check by name
+}
+
+// RegisterHealth adds the liveness and readiness handlers to a given multiplexer, suitable for Kubernetes probes
+func (srv *Server) RegisterHealth(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", srv.getLiveness)
+	mux.HandleFunc("GET /readyz", srv.getReadiness)
+}
+
+// The Minion is alive unless the broker cannot recover without being restarted.
+func (srv *Server) getLiveness(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]error)
+	if checker, ok := srv.broker.(api.LivenessChecker); ok && srv.started.Load() {
+		checks["broker"] = checker.CheckLiveness()
+	}
+	writeHealth(w, checks)
+}
+
+// The Minion is ready when the broker is connected, and all the running Sink modules are ready.
+func (srv *Server) getReadiness(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]error)
+	if !srv.started.Load() {
+		checks["broker"] = errors.New("broker not started")
+	} else if reporter, ok := srv.broker.(api.StateReporter); ok {
+		if state := reporter.GetState(); state.Connected {
+			checks["broker"] = nil
+		} else {
+			checks["broker"] = fmt.Errorf("not connected to %s", state.Endpoint)
+		}
+	}
+	for _, m := range srv.registry.GetRunningModules() {
+		name := "sink/" + m.GetID()
+		if checker, ok := m.(api.ReadinessChecker); ok {
+			checks[name] = checker.CheckReadiness()
+		} else {
+			checks[name] = nil
+		}
+	}
+	writeHealth(w, checks)
+}
+
+func writeHealth(w http.ResponseWriter, checks map[string]error) {
+	response := HealthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for name, err := range checks {
+		if err != nil {
+			response.Checks[name] = err.Error()
+			response.Status = "failed"
+			status = http.StatusServiceUnavailable
+		} else {
+			response.Checks[name] = "ok"
+		}
+	}
+	writeJSON(w, status, response)
+}
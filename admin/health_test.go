@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// mockReadySinkModule represents a Sink module that reports its readiness
+type mockReadySinkModule struct {
+	mockSinkModule
+	err error
+}
+
+func (m *mockReadySinkModule) GetID() string {
+	return "Trap"
+}
+
+func (m *mockReadySinkModule) CheckReadiness() error {
+	return m.err
+}
+
+func TestLiveness(t *testing.T) {
+	broker := &mockBroker{connected: true}
+	server, _, _ := buildTestServerWithBroker(t, broker)
+	defer server.Close()
+
+	health := HealthResponse{}
+	doRequest(t, http.MethodGet, server.URL+"/healthz", http.StatusOK, &health)
+	assert.Equal(t, "ok", health.Status)
+
+	broker.liveness = errors.New("the RPC consumer loop has been stuck for 2m0s")
+	doRequest(t, http.MethodGet, server.URL+"/healthz", http.StatusServiceUnavailable, &health)
+	assert.Equal(t, "failed", health.Status)
+	assert.Equal(t, "the RPC consumer loop has been stuck for 2m0s", health.Checks["broker"])
+}
+
+func TestReadiness(t *testing.T) {
+	broker := &mockBroker{connected: true}
+	server, _, srv := buildTestServerWithBroker(t, broker)
+	defer server.Close()
+	trap := &mockReadySinkModule{err: errors.New("SNMP trap listener not listening yet")}
+	srv.registry.RegisterModule(trap)
+	assert.NilError(t, srv.registry.EnableModule("Trap"))
+
+	health := HealthResponse{}
+	doRequest(t, http.MethodGet, server.URL+"/readyz", http.StatusServiceUnavailable, &health)
+	assert.Equal(t, "ok", health.Checks["broker"])
+	assert.Equal(t, "ok", health.Checks["sink/Syslog"])
+	assert.Equal(t, "SNMP trap listener not listening yet", health.Checks["sink/Trap"])
+
+	// Disabled modules are ignored
+	assert.NilError(t, srv.registry.DisableModule("Trap"))
+	doRequest(t, http.MethodGet, server.URL+"/readyz", http.StatusOK, &health)
+	assert.Equal(t, "ok", health.Status)
+
+	broker.connected = false
+	doRequest(t, http.MethodGet, server.URL+"/readyz", http.StatusServiceUnavailable, &health)
+	assert.Equal(t, "not connected to grpc-server:8990", health.Checks["broker"])
+}
+
+func TestReadinessBeforeStart(t *testing.T) {
+	broker := &mockBroker{connected: true}
+	server, _, srv := buildTestServerWithBroker(t, broker)
+	defer server.Close()
+	srv.started.Store(false)
+
+	health := HealthResponse{}
+	doRequest(t, http.MethodGet, server.URL+"/readyz", http.StatusServiceUnavailable, &health)
+	assert.Equal(t, "broker not started", health.Checks["broker"])
+	doRequest(t, http.MethodGet, server.URL+"/healthz", http.StatusOK, &health)
+}
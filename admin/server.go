@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/collectors"
+	"github.com/agalue/gominion/detectors"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/monitors"
+)
+
+// SinkModuleStatus represents the state of a Sink module
+type SinkModuleStatus struct {
+	ID      string `json:"id"`
+	Running bool   `json:"running"`
+}
+
+// ModulesResponse represents all the registered modules
+type ModulesResponse struct {
+	RPC        []string           `json:"rpc"`
+	Sink       []SinkModuleStatus `json:"sink"`
+	Collectors []string           `json:"collectors"`
+	Detectors  []string           `json:"detectors"`
+	Monitors   []string           `json:"monitors"`
+}
+
+// ErrorResponse represents a failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server represents the local HTTP admin API, to inspect and control the Minion at runtime, and its health checks
+type Server struct {
+	config   atomic.Pointer[api.MinionConfig]
+	registry *api.SinkRegistry
+	broker   api.Broker
+	started  atomic.Bool
+}
+
+// NewServer creates an admin API server for a given broker
+func NewServer(config *api.MinionConfig, registry *api.SinkRegistry, broker api.Broker) *Server {
+	srv := &Server{registry: registry, broker: broker}
+	srv.config.Store(config)
+	return srv
+}
+
+// SetConfig replaces the configuration exposed by the admin API after a reload
+func (srv *Server) SetConfig(config *api.MinionConfig) {
+	srv.config.Store(config)
+}
+
+// BrokerStarted must be called once the broker has been started, as its state is not available before
+func (srv *Server) BrokerStarted() {
+	srv.started.Store(true)
+}
+
+// Register adds the admin API handlers to a given multiplexer under /admin
+// The requests that change the state of the Minion require the admin token as a bearer token when configured.
+func (srv *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/modules", srv.getModules)
+	mux.HandleFunc("GET /admin/broker", srv.getBroker)
+	mux.HandleFunc("GET /admin/config", srv.getConfig)
+	mux.HandleFunc("POST /admin/sink/{id}/enable", srv.authorize(srv.enableSinkModule))
+	mux.HandleFunc("POST /admin/sink/{id}/disable", srv.authorize(srv.disableSinkModule))
+}
+
+// Rejects the requests without the admin token of the current configuration, when configured.
+func (srv *Server) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := srv.config.Load().AdminToken
+		if token == "" {
+			handler(w, r)
+			return
+		}
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gominion"`)
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid or missing admin token"})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (srv *Server) getModules(w http.ResponseWriter, r *http.Request) {
+	response := ModulesResponse{}
+	for _, m := range api.GetAllRPCModules() {
+		response.RPC = append(response.RPC, m.GetID())
+	}
+	for _, m := range srv.registry.GetAllModules() {
+		response.Sink = append(response.Sink, SinkModuleStatus{ID: m.GetID(), Running: srv.registry.IsRunning(m.GetID())})
+	}
+	for _, m := range collectors.GetAllCollectors() {
+		response.Collectors = append(response.Collectors, m.GetID())
+	}
+	for _, m := range detectors.GetAllDetectors() {
+		response.Detectors = append(response.Detectors, m.GetID())
+	}
+	for _, m := range monitors.GetAllMonitors() {
+		response.Monitors = append(response.Monitors, m.GetID())
+	}
+	slices.Sort(response.RPC)
+	slices.SortFunc(response.Sink, func(a, b SinkModuleStatus) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	slices.Sort(response.Collectors)
+	slices.Sort(response.Detectors)
+	slices.Sort(response.Monitors)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (srv *Server) getBroker(w http.ResponseWriter, r *http.Request) {
+	if !srv.started.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "broker not started"})
+		return
+	}
+	if reporter, ok := srv.broker.(api.StateReporter); ok {
+		writeJSON(w, http.StatusOK, reporter.GetState())
+		return
+	}
+	config := srv.config.Load()
+	writeJSON(w, http.StatusOK, api.BrokerState{Type: config.BrokerType, Endpoint: config.BrokerURL})
+}
+
+func (srv *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, srv.config.Load().Redacted())
+}
+
+func (srv *Server) enableSinkModule(w http.ResponseWriter, r *http.Request) {
+	srv.updateSinkModule(w, r.PathValue("id"), srv.registry.EnableModule)
+}
+
+func (srv *Server) disableSinkModule(w http.ResponseWriter, r *http.Request) {
+	srv.updateSinkModule(w, r.PathValue("id"), srv.registry.DisableModule)
+}
+
+// Enables or disables a given Sink module, and responds with its new state.
+func (srv *Server) updateSinkModule(w http.ResponseWriter, id string, update func(id string) error) {
+	found := slices.ContainsFunc(srv.registry.GetAllModules(), func(m api.SinkModule) bool {
+		return m.GetID() == id
+	})
+	if !found {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "sink module " + id + " not found"})
+		return
+	}
+	if err := update(id); err != nil {
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	running := srv.registry.IsRunning(id)
+	log.Warnf("Sink module %s running state changed to %t via the admin API", id, running)
+	writeJSON(w, http.StatusOK, SinkModuleStatus{ID: id, Running: running})
+}
+
+func writeJSON(w http.ResponseWriter, status int, object interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(object); err != nil {
+		log.Errorf("Cannot send admin API response: %v", err)
+	}
+}
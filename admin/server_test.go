@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+type mockSinkModule struct {
+	started int
+	stopped int
+}
+
+func (m *mockSinkModule) GetID() string {
+	return "Syslog"
+}
+
+func (m *mockSinkModule) Start(config *api.MinionConfig, sink api.Sink) error {
+	m.started++
+	return nil
+}
+
+func (m *mockSinkModule) Stop() {
+	m.stopped++
+}
+
+type mockBroker struct {
+	connected bool
+	liveness  error
+}
+
+func (b *mockBroker) Start() error {
+	return nil
+}
+
+func (b *mockBroker) Stop() {}
+
+func (b *mockBroker) GetState() api.BrokerState {
+	return api.BrokerState{Type: "grpc", Connected: b.connected, Endpoint: "grpc-server:8990"}
+}
+
+func (b *mockBroker) CheckLiveness() error {
+	return b.liveness
+}
+
+func buildTestServer(t *testing.T) (*httptest.Server, *mockSinkModule) {
+	server, module, _ := buildTestServerWithBroker(t, &mockBroker{connected: true})
+	return server, module
+}
+
+func buildTestServerWithBroker(t *testing.T, broker *mockBroker) (*httptest.Server, *mockSinkModule, *Server) {
+	config := &api.MinionConfig{
+		ID:               "minion1",
+		Location:         "Test",
+		BrokerURL:        "grpc-server:8990",
+		BrokerProperties: map[string]string{"tls-enabled": "true", "sasl-password": "secret"},
+	}
+	module := new(mockSinkModule)
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	registry.RegisterModule(module)
+	assert.NilError(t, registry.StartModules(config, nil))
+	mux := http.NewServeMux()
+	srv := NewServer(config, registry, broker)
+	srv.Register(mux)
+	srv.RegisterHealth(mux)
+	srv.BrokerStarted()
+	return httptest.NewServer(mux), module, srv
+}
+
+func doRequest(t *testing.T, method string, url string, status int, response interface{}) {
+	doAuthorizedRequest(t, method, url, "", status, response)
+}
+
+func doAuthorizedRequest(t *testing.T, method string, url string, token string, status int, response interface{}) {
+	req, err := http.NewRequest(method, url, nil)
+	assert.NilError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, status, res.StatusCode)
+	assert.NilError(t, json.NewDecoder(res.Body).Decode(response))
+}
+
+func TestGetState(t *testing.T) {
+	server, _ := buildTestServer(t)
+	defer server.Close()
+
+	modules := ModulesResponse{}
+	doRequest(t, http.MethodGet, server.URL+"/admin/modules", http.StatusOK, &modules)
+	assert.DeepEqual(t, []SinkModuleStatus{{ID: "Syslog", Running: true}}, modules.Sink)
+	assert.Assert(t, len(modules.Monitors) > 0)
+
+	state := api.BrokerState{}
+	doRequest(t, http.MethodGet, server.URL+"/admin/broker", http.StatusOK, &state)
+	assert.Assert(t, state.Connected)
+	assert.Equal(t, "grpc-server:8990", state.Endpoint)
+
+	config := api.MinionConfig{}
+	doRequest(t, http.MethodGet, server.URL+"/admin/config", http.StatusOK, &config)
+	assert.Equal(t, "minion1", config.ID)
+	assert.Equal(t, "true", config.BrokerProperties["tls-enabled"])
+	assert.Equal(t, "******", config.BrokerProperties["sasl-password"])
+}
+
+func TestUpdateSinkModule(t *testing.T) {
+	server, module := buildTestServer(t)
+	defer server.Close()
+
+	status := SinkModuleStatus{}
+	doRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/disable", http.StatusOK, &status)
+	assert.Equal(t, SinkModuleStatus{ID: "Syslog", Running: false}, status)
+	assert.Equal(t, 1, module.stopped)
+
+	errorResponse := ErrorResponse{}
+	doRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/disable", http.StatusConflict, &errorResponse)
+	assert.Equal(t, "sink module Syslog is not running", errorResponse.Error)
+
+	doRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/enable", http.StatusOK, &status)
+	assert.Equal(t, SinkModuleStatus{ID: "Syslog", Running: true}, status)
+	assert.Equal(t, 2, module.started)
+
+	doRequest(t, http.MethodPost, server.URL+"/admin/sink/Trap/enable", http.StatusNotFound, &errorResponse)
+	assert.Equal(t, "sink module Trap not found", errorResponse.Error)
+}
+
+func TestUpdateSinkModuleWithToken(t *testing.T) {
+	server, module, srv := buildTestServerWithBroker(t, &mockBroker{connected: true})
+	defer server.Close()
+	config := *srv.config.Load()
+	config.AdminToken = "s3cr3t"
+	srv.SetConfig(&config)
+
+	errorResponse := ErrorResponse{}
+	doRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/disable", http.StatusUnauthorized, &errorResponse)
+	assert.Equal(t, "invalid or missing admin token", errorResponse.Error)
+	doAuthorizedRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/disable", "wrong", http.StatusUnauthorized, &errorResponse)
+	assert.Equal(t, 0, module.stopped)
+
+	status := SinkModuleStatus{}
+	doAuthorizedRequest(t, http.MethodPost, server.URL+"/admin/sink/Syslog/disable", "s3cr3t", http.StatusOK, &status)
+	assert.Equal(t, SinkModuleStatus{ID: "Syslog", Running: false}, status)
+	assert.Equal(t, 1, module.stopped)
+
+	// Read-only requests don't require the token
+	modules := ModulesResponse{}
+	doRequest(t, http.MethodGet, server.URL+"/admin/modules", http.StatusOK, &modules)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	"github.com/agalue/gominion/protobuf/ipc"
 )
 
@@ -11,6 +13,61 @@ type Sink interface {
 	Send(msg *ipc.SinkMessage) error
 }
 
+// DirectSink represents the broker functionality for sending records directly to a given topic,
+// bypassing the Sink API message wrapping and chunking
+type DirectSink interface {
+
+	// Sends a set of records with a given key to a given topic on behalf of a Sink Module
+	SendDirect(moduleID string, topic string, key []byte, values [][]byte) error
+}
+
+// MetricsProvider represents the broker functionality for sharing its metrics with the Sink modules
+type MetricsProvider interface {
+
+	// Gets the metrics of the broker
+	GetMetrics() *Metrics
+}
+
+// TwinSubscriber represents the broker functionality for receiving objects via the Twin API
+type TwinSubscriber interface {
+
+	// Subscribes to a Twin object by its key (non-blocking method)
+	// The handler is called every time a new version of the object is available
+	Subscribe(key string, handler TwinHandler) error
+
+	// Removes all the handlers for a given Twin object key
+	Unsubscribe(key string)
+}
+
+// BrokerState represents the state of the connections of a broker
+type BrokerState struct {
+	Type        string            `json:"type"`
+	Connected   bool              `json:"connected"`
+	Endpoint    string            `json:"endpoint,omitempty"`    // The server in use
+	Connections map[string]string `json:"connections,omitempty"` // The state of each connection by name
+}
+
+// StateReporter represents the broker functionality for reporting the state of its connections
+type StateReporter interface {
+
+	// Gets the current state of the connections with the server
+	GetState() BrokerState
+}
+
+// LivenessChecker represents the functionality for detecting a component that cannot recover without being restarted
+type LivenessChecker interface {
+
+	// Returns an error when the component is not alive
+	CheckLiveness() error
+}
+
+// ReadinessChecker represents the functionality for detecting a component that is not ready to work
+type ReadinessChecker interface {
+
+	// Returns an error when the component is not ready
+	CheckReadiness() error
+}
+
 // Broker represents a broker implementation to control its life cycle
 type Broker interface {
 
@@ -34,6 +91,14 @@ type SinkModule interface {
 	Stop()
 }
 
+// ReloadableSinkModule represents a Sink Module whose configuration can change at runtime
+type ReloadableSinkModule interface {
+	SinkModule
+
+	// Returns true when the differences between the current and the updated configuration require restarting the module
+	RequiresRestart(current *MinionConfig, updated *MinionConfig) bool
+}
+
 // RPCModule represents an implementation of an OpenNMS RPC Module
 type RPCModule interface {
 
@@ -41,8 +106,12 @@ type RPCModule interface {
 	GetID() string
 
 	// Executes an RPC request and returns the response
+	// The context is cancelled when the request expires
 	// The response tells if the operation was successful or not
-	Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto
+	Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto
+
+	// Builds a response for a request that cannot be executed, with the module's error format
+	ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto
 }
 
 // ServiceCollector represents an implementation of a service collector
@@ -53,7 +122,7 @@ type ServiceCollector interface {
 
 	// Executes the data collection operation from the request
 	// The response tells if the operation was successful or not
-	Collect(request *CollectorRequestDTO) *CollectorResponseDTO
+	Collect(ctx context.Context, request *CollectorRequestDTO) *CollectorResponseDTO
 }
 
 // ServiceDetector represents an implementation of a service detector
@@ -64,7 +133,7 @@ type ServiceDetector interface {
 
 	// Executes the detection operation from the request
 	// The response tells if the operation was successful or not
-	Detect(request *DetectorRequestDTO) *DetectorResponseDTO
+	Detect(ctx context.Context, request *DetectorRequestDTO) *DetectorResponseDTO
 }
 
 // ServiceMonitor represents an implementation of a service monitor
@@ -75,5 +144,5 @@ type ServiceMonitor interface {
 
 	// Executes the polling operation from the request
 	// The response tells if the operation was successful or not
-	Poll(request *PollerRequestDTO) *PollerResponseDTO
+	Poll(ctx context.Context, request *PollerRequestDTO) *PollerResponseDTO
 }
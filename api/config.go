@@ -1,9 +1,11 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -47,19 +49,181 @@ type DNSConfig struct {
 	CircuitBreaker       CircuitBreakerConfig `yaml:"circuitBreaker" json:"circuitBreaker"`
 }
 
+// AggregationConfig represents the settings to batch the messages of a Sink module
+type AggregationConfig struct {
+	BatchSize     int `yaml:"batchSize" json:"batchSize"`
+	BatchInterval int `yaml:"batchInterval,omitempty" json:"batchInterval,omitempty"` // In milliseconds
+}
+
+// Rate limiting policies for Sink messages exceeding the module rate
+const (
+	RateLimitDropNewest = "drop-newest" // Discards the new messages when the queue is full
+	RateLimitDropOldest = "drop-oldest" // Discards the oldest queued messages to make room for the new ones
+)
+
+// RateLimitConfig represents the token bucket settings to limit the messages of a Sink module
+type RateLimitConfig struct {
+	Rate        float64 `yaml:"rate,omitempty" json:"rate,omitempty"`               // Messages per second for the module
+	Burst       int     `yaml:"burst,omitempty" json:"burst,omitempty"`             // Defaults to the rate
+	SourceRate  float64 `yaml:"sourceRate,omitempty" json:"sourceRate,omitempty"`   // Messages per second for each source address
+	SourceBurst int     `yaml:"sourceBurst,omitempty" json:"sourceBurst,omitempty"` // Defaults to the source rate
+	Policy      string  `yaml:"policy,omitempty" json:"policy,omitempty"`           // drop-newest (default) or drop-oldest
+	QueueSize   int     `yaml:"queueSize,omitempty" json:"queueSize,omitempty"`     // Messages waiting for the module rate
+}
+
+// IsValid returns an error if the rate limit settings are not valid
+func (rl *RateLimitConfig) IsValid() error {
+	if rl.Rate < 0 || rl.SourceRate < 0 {
+		return fmt.Errorf("rate cannot be negative")
+	}
+	if rl.Burst < 0 || rl.SourceBurst < 0 || rl.QueueSize < 0 {
+		return fmt.Errorf("burst and queue size cannot be negative")
+	}
+	switch rl.Policy {
+	case "", RateLimitDropNewest, RateLimitDropOldest:
+		return nil
+	default:
+		return fmt.Errorf("invalid policy %s, expected %s or %s", rl.Policy, RateLimitDropNewest, RateLimitDropOldest)
+	}
+}
+
+// SNMPv3 authentication protocols for the USM users
+var SnmpV3AuthProtocols = []string{"MD5", "SHA", "SHA-224", "SHA-256", "SHA-384", "SHA-512"}
+
+// SNMPv3 privacy protocols for the USM users
+var SnmpV3PrivProtocols = []string{"DES", "AES", "AES-192", "AES-256", "AES-192C", "AES-256C"}
+
+// SnmpV3User represents an SNMPv3 USM user allowed to send traps and informs (like snmpv3-user on trapd-configuration.xml)
+type SnmpV3User struct {
+	SecurityName      string `yaml:"securityName" json:"securityName"`
+	EngineID          string `yaml:"engineId,omitempty" json:"engineId,omitempty"`                   // Hexadecimal; when set, only traps from that engine are accepted
+	AuthProtocol      string `yaml:"authProtocol,omitempty" json:"authProtocol,omitempty"`           // MD5, SHA, SHA-224, SHA-256, SHA-384 or SHA-512
+	AuthPassphrase    string `yaml:"authPassphrase,omitempty" json:"authPassphrase,omitempty"`       // Required with an authentication protocol
+	PrivacyProtocol   string `yaml:"privacyProtocol,omitempty" json:"privacyProtocol,omitempty"`     // DES, AES, AES-192, AES-256, AES-192C or AES-256C
+	PrivacyPassphrase string `yaml:"privacyPassphrase,omitempty" json:"privacyPassphrase,omitempty"` // Required with a privacy protocol
+}
+
+// IsValid returns an error if the USM user settings are not valid
+func (user *SnmpV3User) IsValid() error {
+	if user.SecurityName == "" {
+		return fmt.Errorf("security name required")
+	}
+	if user.EngineID != "" {
+		if _, err := ParseEngineID(user.EngineID); err != nil {
+			return err
+		}
+	}
+	if user.AuthProtocol != "" {
+		if !slices.ContainsFunc(SnmpV3AuthProtocols, func(p string) bool { return strings.EqualFold(p, user.AuthProtocol) }) {
+			return fmt.Errorf("invalid authentication protocol %s%s", user.AuthProtocol, Suggestion(user.AuthProtocol, SnmpV3AuthProtocols))
+		}
+		if len(user.AuthPassphrase) < 8 {
+			return fmt.Errorf("authentication passphrase must have at least 8 characters")
+		}
+	}
+	if user.PrivacyProtocol != "" {
+		if user.AuthProtocol == "" {
+			return fmt.Errorf("privacy requires an authentication protocol")
+		}
+		if !slices.ContainsFunc(SnmpV3PrivProtocols, func(p string) bool { return strings.EqualFold(p, user.PrivacyProtocol) }) {
+			return fmt.Errorf("invalid privacy protocol %s%s", user.PrivacyProtocol, Suggestion(user.PrivacyProtocol, SnmpV3PrivProtocols))
+		}
+		if len(user.PrivacyPassphrase) < 8 {
+			return fmt.Errorf("privacy passphrase must have at least 8 characters")
+		}
+	}
+	return nil
+}
+
+// TrapForwarder represents a downstream receiver for the SNMP traps
+type TrapForwarder struct {
+	Address   string `yaml:"address" json:"address"`                         // Receiver address as host:port
+	Community string `yaml:"community,omitempty" json:"community,omitempty"` // When set, the traps are re-encoded with it as SNMPv2c; otherwise, the original bytes are forwarded
+}
+
+// IsValid returns an error if the forwarder settings are not valid
+func (fwd *TrapForwarder) IsValid() error {
+	_, port, err := net.SplitHostPort(fwd.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %v", fwd.Address, err)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port on address %s", fwd.Address)
+	}
+	return nil
+}
+
+// TrapConfig represents the settings of the SNMP trap receiver
+type TrapConfig struct {
+	EngineID          string          `yaml:"engineId,omitempty" json:"engineId,omitempty"`                   // Hexadecimal local engine ID, discovered by the agents sending SNMPv3 informs
+	Users             []SnmpV3User    `yaml:"users,omitempty" json:"users,omitempty"`                         // SNMPv3 USM users
+	IncludeRawMessage bool            `yaml:"includeRawMessage,omitempty" json:"includeRawMessage,omitempty"` // Include the original bytes of the traps on the Sink messages
+	Forwarders        []TrapForwarder `yaml:"forwarders,omitempty" json:"forwarders,omitempty"`               // Downstream receivers for the traps
+}
+
+// SyslogConfig represents the settings of the secure and reliable Syslog listeners, besides the plain UDP and TCP ones on syslogPort
+type SyslogConfig struct {
+	TLSPort      int    `yaml:"tlsPort,omitempty" json:"tlsPort,omitempty"`           // TCP port for Syslog over TLS (RFC 5425)
+	RELPPort     int    `yaml:"relpPort,omitempty" json:"relpPort,omitempty"`         // TCP port for RELP
+	RELPTLS      bool   `yaml:"relpTls,omitempty" json:"relpTls,omitempty"`           // Use TLS for RELP
+	CertFile     string `yaml:"certFile,omitempty" json:"certFile,omitempty"`         // Server certificate in PEM format, required for TLS
+	KeyFile      string `yaml:"keyFile,omitempty" json:"keyFile,omitempty"`           // Server private key in PEM format, required for TLS
+	ClientCAFile string `yaml:"clientCaFile,omitempty" json:"clientCaFile,omitempty"` // When set, the clients must present a certificate signed by this CA
+}
+
+// IsTLSEnabled returns true when any of the listeners uses TLS
+func (cfg *SyslogConfig) IsTLSEnabled() bool {
+	return cfg.TLSPort > 0 || (cfg.RELPPort > 0 && cfg.RELPTLS)
+}
+
+// IsValid returns an error if the Syslog listener settings are not valid
+func (cfg *SyslogConfig) IsValid() error {
+	if cfg.TLSPort < 0 || cfg.TLSPort > 65535 {
+		return fmt.Errorf("invalid TLS port %d", cfg.TLSPort)
+	}
+	if cfg.RELPPort < 0 || cfg.RELPPort > 65535 {
+		return fmt.Errorf("invalid RELP port %d", cfg.RELPPort)
+	}
+	if cfg.TLSPort > 0 && cfg.TLSPort == cfg.RELPPort {
+		return fmt.Errorf("the TLS and RELP ports must be different")
+	}
+	if cfg.IsTLSEnabled() && (cfg.CertFile == "" || cfg.KeyFile == "") {
+		return fmt.Errorf("certificate and key files required for TLS")
+	}
+	return nil
+}
+
+// ParseEngineID parses an SNMP engine ID in hexadecimal, with or without the 0x prefix
+func ParseEngineID(engineID string) ([]byte, error) {
+	value := strings.TrimPrefix(strings.ToLower(engineID), "0x")
+	id, err := hex.DecodeString(value)
+	if err != nil || len(id) < 5 || len(id) > 32 {
+		return nil, fmt.Errorf("invalid engine ID %s, expected 5 to 32 bytes in hexadecimal", engineID)
+	}
+	return id, nil
+}
+
 // MinionConfig represents basic Minion Configuration
 type MinionConfig struct {
-	ID               string            `yaml:"id" json:"id"`
-	Location         string            `yaml:"location" json:"location"`
-	BrokerURL        string            `yaml:"brokerUrl" json:"brokerUrl"`
-	BrokerType       string            `yaml:"brokerType" json:"brokerType"`
-	BrokerProperties map[string]string `yaml:"brokerProperties,omitempty" json:"brokerProperties,omitempty"`
-	TrapPort         int               `yaml:"trapPort" json:"trapPort"`
-	SyslogPort       int               `yaml:"syslogPort" json:"syslogPort"`
-	StatsPort        int               `yaml:"statsPort" json:"statsPort"`
-	LogLevel         string            `yaml:"logLevel" json:"logLevel"`
-	DNS              *DNSConfig        `yaml:"dns,omitempty" json:"dns,omitempty"`
-	Listeners        []MinionListener  `yaml:"listeners,omitempty" json:"listeners,omitempty"`
+	ID               string                       `yaml:"id" json:"id"`
+	Location         string                       `yaml:"location" json:"location"`
+	BrokerURL        string                       `yaml:"brokerUrl" json:"brokerUrl"`
+	BrokerType       string                       `yaml:"brokerType" json:"brokerType"`
+	BrokerProperties map[string]string            `yaml:"brokerProperties,omitempty" json:"brokerProperties,omitempty"`
+	TrapPort         int                          `yaml:"trapPort" json:"trapPort"`
+	SyslogPort       int                          `yaml:"syslogPort" json:"syslogPort"`
+	StatsPort        int                          `yaml:"statsPort" json:"statsPort"`
+	AdminPort        int                          `yaml:"adminPort" json:"adminPort"`
+	AdminToken       string                       `yaml:"adminToken,omitempty" json:"adminToken,omitempty"`
+	LogLevel         string                       `yaml:"logLevel" json:"logLevel"`
+	CompactXML       bool                         `yaml:"compactXml" json:"compactXml"`
+	DNS              *DNSConfig                   `yaml:"dns,omitempty" json:"dns,omitempty"`
+	Listeners        []MinionListener             `yaml:"listeners,omitempty" json:"listeners,omitempty"`
+	Aggregation      map[string]AggregationConfig `yaml:"aggregation,omitempty" json:"aggregation,omitempty"`
+	RateLimits       map[string]RateLimitConfig   `yaml:"rateLimits,omitempty" json:"rateLimits,omitempty"`
+	Traps            *TrapConfig                  `yaml:"traps,omitempty" json:"traps,omitempty"`
+	Syslog           *SyslogConfig                `yaml:"syslog,omitempty" json:"syslog,omitempty"`
+	Rules            map[string][]SinkRule        `yaml:"rules,omitempty" json:"rules,omitempty"`
 }
 
 // ParseListeners parses an array of listeners in CSV format
@@ -90,6 +254,26 @@ func (cfg *MinionConfig) GetBrokerProperty(property string) string {
 	return ""
 }
 
+// GetAggregation gets the aggregation settings for a given Sink module by ID; returns nil when not configured
+func (cfg *MinionConfig) GetAggregation(moduleID string) *AggregationConfig {
+	for module, aggregation := range cfg.Aggregation {
+		if strings.EqualFold(module, moduleID) {
+			return &aggregation
+		}
+	}
+	return nil
+}
+
+// GetRateLimit gets the rate limit settings for a given Sink module by ID; returns nil when not configured
+func (cfg *MinionConfig) GetRateLimit(moduleID string) *RateLimitConfig {
+	for module, rateLimit := range cfg.RateLimits {
+		if strings.EqualFold(module, moduleID) {
+			return &rateLimit
+		}
+	}
+	return nil
+}
+
 // GetListener gets a given listener by name
 func (cfg *MinionConfig) GetListener(name string) *MinionListener {
 	for _, listener := range cfg.Listeners {
@@ -115,24 +299,107 @@ func (cfg *MinionConfig) String() string {
 	return string(bytes)
 }
 
+// Redacted returns a copy of the configuration without secrets, like the passwords and tokens on the broker and listener properties
+func (cfg *MinionConfig) Redacted() *MinionConfig {
+	redacted := *cfg
+	redacted.BrokerProperties = redactProperties(cfg.BrokerProperties)
+	if cfg.AdminToken != "" {
+		redacted.AdminToken = redactedValue
+	}
+	redacted.Listeners = make([]MinionListener, len(cfg.Listeners))
+	for i, listener := range cfg.Listeners {
+		listener.Properties = redactProperties(listener.Properties)
+		redacted.Listeners[i] = listener
+	}
+	if cfg.Traps != nil {
+		traps := *cfg.Traps
+		traps.Users = make([]SnmpV3User, len(cfg.Traps.Users))
+		for i, user := range cfg.Traps.Users {
+			if user.AuthPassphrase != "" {
+				user.AuthPassphrase = redactedValue
+			}
+			if user.PrivacyPassphrase != "" {
+				user.PrivacyPassphrase = redactedValue
+			}
+			traps.Users[i] = user
+		}
+		traps.Forwarders = make([]TrapForwarder, len(cfg.Traps.Forwarders))
+		for i, fwd := range cfg.Traps.Forwarders {
+			if fwd.Community != "" {
+				fwd.Community = redactedValue
+			}
+			traps.Forwarders[i] = fwd
+		}
+		redacted.Traps = &traps
+	}
+	return &redacted
+}
+
 // IsValid returns an error if the configuration is not valid
 func (cfg *MinionConfig) IsValid() error {
+	if errs := cfg.getSettingsErrors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Gets the problems found on the mandatory settings, the DNS and the rate limits
+func (cfg *MinionConfig) getSettingsErrors() []error {
+	errs := make([]error, 0)
 	if cfg.ID == "" {
-		return fmt.Errorf("minion ID required")
+		errs = append(errs, fmt.Errorf("minion ID required"))
 	}
 	if cfg.Location == "" {
-		return fmt.Errorf("location required")
+		errs = append(errs, fmt.Errorf("location required"))
 	}
 	if cfg.BrokerURL == "" {
-		return fmt.Errorf("broker URL required")
+		errs = append(errs, fmt.Errorf("broker URL required"))
 	}
 	if cfg.DNS != nil && cfg.DNS.NameServer != "" {
 		ip := net.ParseIP(cfg.DNS.NameServer)
 		if ip == nil {
-			return fmt.Errorf("invalid DNS name server")
+			errs = append(errs, fmt.Errorf("invalid DNS name server"))
 		}
 	}
-	return nil
+	for module, rateLimit := range cfg.RateLimits {
+		if err := rateLimit.IsValid(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid rate limit for %s: %v", module, err))
+		}
+	}
+	if cfg.Traps != nil {
+		if cfg.Traps.EngineID != "" {
+			if _, err := ParseEngineID(cfg.Traps.EngineID); err != nil {
+				errs = append(errs, fmt.Errorf("invalid trap receiver: %v", err))
+			}
+		}
+		for i, user := range cfg.Traps.Users {
+			if err := user.IsValid(); err != nil {
+				errs = append(errs, fmt.Errorf("invalid SNMPv3 user %d (%s): %v", i+1, user.SecurityName, err))
+			}
+		}
+		for i, fwd := range cfg.Traps.Forwarders {
+			if err := fwd.IsValid(); err != nil {
+				errs = append(errs, fmt.Errorf("invalid trap forwarder %d: %v", i+1, err))
+			}
+		}
+	}
+	if cfg.Syslog != nil {
+		if err := cfg.Syslog.IsValid(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid Syslog listeners: %v", err))
+		}
+	}
+	for module, rules := range cfg.Rules {
+		if !slices.ContainsFunc(RuleModules, func(m string) bool { return strings.EqualFold(m, module) }) {
+			errs = append(errs, fmt.Errorf("rules not supported for module %s%s", module, Suggestion(module, RuleModules)))
+			continue
+		}
+		for i, rule := range rules {
+			if err := rule.IsValid(module); err != nil {
+				errs = append(errs, fmt.Errorf("invalid rule %d (%s) for %s: %v", i+1, rule.Name, module, err))
+			}
+		}
+	}
+	return errs
 }
 
 // GetHeaderResponse builds an RPC response with the headers context
@@ -144,3 +411,23 @@ func (cfg *MinionConfig) GetHeaderResponse() *ipc.RpcResponseProto {
 		RpcId:    cfg.ID,
 	}
 }
+
+// redactedValue replaces the value of secret properties
+const redactedValue = "******"
+
+// Returns a copy of a given set of properties, replacing the value of the secret ones
+func redactProperties(properties map[string]string) map[string]string {
+	if properties == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(properties))
+	for key, value := range properties {
+		name := strings.ToLower(key)
+		isSecret := strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")
+		if isSecret && !strings.HasSuffix(name, "path") {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
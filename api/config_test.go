@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -128,3 +129,107 @@ listeners:
 	assert.Equal(t, 4, len(config.Listeners))
 	assert.Assert(t, config.GetListener("Graphite").Is("ForwardParser"))
 }
+
+func TestRateLimitConfiguration(t *testing.T) {
+	config := &MinionConfig{
+		ID:        "minion1",
+		Location:  "Test",
+		BrokerURL: "10.0.0.100:8990",
+		RateLimits: map[string]RateLimitConfig{
+			"trap": {Rate: 100, SourceRate: 10, Policy: RateLimitDropOldest},
+		},
+	}
+	assert.NilError(t, config.IsValid())
+	rateLimit := config.GetRateLimit("Trap")
+	assert.Assert(t, rateLimit != nil)
+	assert.Equal(t, 10.0, rateLimit.SourceRate)
+	assert.Assert(t, config.GetRateLimit("Syslog") == nil)
+
+	config.RateLimits["trap"] = RateLimitConfig{Rate: 100, Policy: "drop-all"}
+	assert.ErrorContains(t, config.IsValid(), "invalid policy drop-all")
+	config.RateLimits["trap"] = RateLimitConfig{Rate: -1}
+	assert.ErrorContains(t, config.IsValid(), "rate cannot be negative")
+}
+
+func TestTrapConfiguration(t *testing.T) {
+	config := &MinionConfig{
+		ID:        "minion1",
+		Location:  "Test",
+		BrokerURL: "10.0.0.100:8990",
+		Traps: &TrapConfig{
+			EngineID: "0x80001f8880e9630000d61ff449",
+			Users: []SnmpV3User{
+				{SecurityName: "admin", AuthProtocol: "SHA", AuthPassphrase: "0p3nNMSv3", PrivacyProtocol: "aes", PrivacyPassphrase: "0p3nNMSv3"},
+				{SecurityName: "legacy", EngineID: "8000000001020304"},
+			},
+		},
+	}
+	assert.NilError(t, config.IsValid())
+
+	config.Traps.EngineID = "0x8000"
+	assert.ErrorContains(t, config.IsValid(), "invalid engine ID 0x8000")
+	config.Traps.EngineID = ""
+
+	config.Traps.Forwarders = []TrapForwarder{{Address: "siem.example.com:162", Community: "public"}, {Address: "10.0.0.1"}}
+	assert.ErrorContains(t, config.IsValid(), "invalid trap forwarder 2: invalid address 10.0.0.1")
+	config.Traps.Forwarders[1].Address = "10.0.0.1:70000"
+	assert.ErrorContains(t, config.IsValid(), "invalid port on address 10.0.0.1:70000")
+	config.Traps.Forwarders = config.Traps.Forwarders[:1]
+	assert.NilError(t, config.IsValid())
+	assert.Equal(t, "******", config.Redacted().Traps.Forwarders[0].Community)
+
+	user := &config.Traps.Users[0]
+	user.AuthProtocol = "SHA-257"
+	assert.ErrorContains(t, config.IsValid(), "invalid authentication protocol SHA-257 (did you mean SHA-256?)")
+	user.AuthProtocol = "SHA"
+	user.PrivacyPassphrase = "short"
+	assert.ErrorContains(t, config.IsValid(), "privacy passphrase must have at least 8 characters")
+	user.AuthProtocol = ""
+	assert.ErrorContains(t, config.IsValid(), "privacy requires an authentication protocol")
+	user.SecurityName = ""
+	assert.ErrorContains(t, config.IsValid(), "security name required")
+}
+
+func TestSyslogConfiguration(t *testing.T) {
+	config := &MinionConfig{
+		ID:         "minion1",
+		Location:   "Test",
+		BrokerURL:  "10.0.0.100:8990",
+		SyslogPort: 1514,
+		Syslog:     &SyslogConfig{RELPPort: 2514},
+	}
+	assert.NilError(t, config.IsValid())
+	config.Syslog.RELPTLS = true
+	assert.ErrorContains(t, config.IsValid(), "certificate and key files required for TLS")
+	config.Syslog = &SyslogConfig{TLSPort: 6514, RELPPort: 6514, CertFile: "/etc/tls.crt", KeyFile: "/etc/tls.key"}
+	assert.ErrorContains(t, config.IsValid(), "the TLS and RELP ports must be different")
+	config.Syslog.RELPPort = 2514
+	assert.NilError(t, config.IsValid())
+	assert.ErrorContains(t, errors.Join(config.Validate()...), "invalid Syslog TLS file")
+}
+
+func TestRedactedConfiguration(t *testing.T) {
+	config := &MinionConfig{
+		ID:               "minion1",
+		AdminToken:       "s3cr3t",
+		BrokerProperties: map[string]string{"sasl-username": "minion", "sasl-password": "secret", "sasl-token": "token", "sasl-token-path": "/etc/token"},
+		Listeners:        []MinionListener{{Name: "Graphite", Properties: map[string]string{"apiSecret": "secret", "workers": "4"}}},
+		Traps:            &TrapConfig{Users: []SnmpV3User{{SecurityName: "admin", AuthProtocol: "SHA", AuthPassphrase: "0p3nNMSv3"}}},
+	}
+	redacted := config.Redacted()
+	assert.Equal(t, "******", redacted.AdminToken)
+	assert.Equal(t, "minion", redacted.BrokerProperties["sasl-username"])
+	assert.Equal(t, "******", redacted.BrokerProperties["sasl-password"])
+	assert.Equal(t, "******", redacted.BrokerProperties["sasl-token"])
+	assert.Equal(t, "/etc/token", redacted.BrokerProperties["sasl-token-path"])
+	assert.Equal(t, "******", redacted.Listeners[0].Properties["apiSecret"])
+	assert.Equal(t, "4", redacted.Listeners[0].Properties["workers"])
+	assert.Equal(t, "******", redacted.Traps.Users[0].AuthPassphrase)
+	assert.Equal(t, "", redacted.Traps.Users[0].PrivacyPassphrase)
+
+	// The original configuration is not modified
+	assert.Equal(t, "s3cr3t", config.AdminToken)
+	assert.Equal(t, "secret", config.BrokerProperties["sasl-password"])
+	assert.Equal(t, "secret", config.Listeners[0].Properties["apiSecret"])
+	assert.Equal(t, "0p3nNMSv3", config.Traps.Users[0].AuthPassphrase)
+}
@@ -6,14 +6,32 @@ import (
 
 // Metrics represents the broker metric set per module
 type Metrics struct {
-	SinkMsgDeliverySucceeded *prometheus.CounterVec // Sink messages successfully delivered
-	SinkMsgDeliveryFailed    *prometheus.CounterVec // Failed attempts to send Sink messages
-	RPCReqReceivedSucceeded  *prometheus.CounterVec // RPC requests successfully received
-	RPCReqReceivedFailed     *prometheus.CounterVec // Failed attempts to receive RPC requests
-	RPCReqProcessedSucceeded *prometheus.CounterVec // RPC requests successfully processed
-	RPCReqProcessedFailed    *prometheus.CounterVec // Failed attempts to process RPC requests
-	RPCResSentSucceeded      *prometheus.CounterVec // RPC responses successfully sent
-	RPCResSentFailed         *prometheus.CounterVec // Failed attempts to send RPC responses
+	SinkMsgDeliverySucceeded *prometheus.CounterVec   // Sink messages successfully delivered
+	SinkMsgDeliveryFailed    *prometheus.CounterVec   // Failed attempts to send Sink messages
+	SinkQueueMessages        *prometheus.GaugeVec     // Sink messages waiting on the persistent queue
+	SinkQueueBytes           *prometheus.GaugeVec     // Size of the Sink messages waiting on the persistent queue
+	SinkQueueDropped         *prometheus.CounterVec   // Sink messages discarded because the persistent queue is full
+	SinkMsgShed              *prometheus.CounterVec   // Sink messages discarded by the rate limiters
+	SinkTrapDecodingErrors   *prometheus.CounterVec   // SNMP traps that cannot be decoded or authenticated
+	SinkTrapsReceived        *prometheus.CounterVec   // SNMP traps and informs received
+	SinkInformAckFailed      *prometheus.CounterVec   // Failed attempts to acknowledge SNMP informs
+	SinkRuleMatches          *prometheus.CounterVec   // Sink messages matched by the rules
+	RPCReqReceivedSucceeded  *prometheus.CounterVec   // RPC requests successfully received
+	RPCReqReceivedFailed     *prometheus.CounterVec   // Failed attempts to receive RPC requests
+	RPCReqProcessedSucceeded *prometheus.CounterVec   // RPC requests successfully processed
+	RPCReqProcessedFailed    *prometheus.CounterVec   // Failed attempts to process RPC requests
+	RPCReqExpired            *prometheus.CounterVec   // RPC requests that expired before or during their execution
+	RPCReqRejected           *prometheus.CounterVec   // RPC requests rejected because the worker queue is full
+	RPCReqQueueWait          *prometheus.HistogramVec // Time RPC requests wait for a worker
+	RPCChunksIncomplete      *prometheus.GaugeVec     // RPC messages waiting for missing chunks
+	RPCChunksBytes           *prometheus.GaugeVec     // Size of the RPC messages waiting for missing chunks
+	RPCChunksEvicted         *prometheus.CounterVec   // Incomplete RPC messages discarded because they expired or the buffer is full
+	RPCResSentSucceeded      *prometheus.CounterVec   // RPC responses successfully sent
+	RPCResSentFailed         *prometheus.CounterVec   // Failed attempts to send RPC responses
+	GRPCConnectionState      *prometheus.GaugeVec     // State of the gRPC connections
+	GRPCActiveEndpoint       *prometheus.GaugeVec     // Active gRPC endpoint
+	TwinUpdatesSucceeded     *prometheus.CounterVec   // Twin updates successfully applied
+	TwinUpdatesFailed        *prometheus.CounterVec   // Twin updates that cannot be applied
 }
 
 // Register register all prometheus metrics
@@ -21,12 +39,30 @@ func (m *Metrics) Register() {
 	prometheus.MustRegister(
 		m.SinkMsgDeliverySucceeded,
 		m.SinkMsgDeliveryFailed,
+		m.SinkQueueMessages,
+		m.SinkQueueBytes,
+		m.SinkQueueDropped,
+		m.SinkMsgShed,
+		m.SinkTrapDecodingErrors,
+		m.SinkTrapsReceived,
+		m.SinkInformAckFailed,
+		m.SinkRuleMatches,
 		m.RPCReqReceivedSucceeded,
 		m.RPCReqReceivedFailed,
 		m.RPCReqProcessedSucceeded,
 		m.RPCReqProcessedFailed,
+		m.RPCReqExpired,
+		m.RPCReqRejected,
+		m.RPCReqQueueWait,
+		m.RPCChunksIncomplete,
+		m.RPCChunksBytes,
+		m.RPCChunksEvicted,
 		m.RPCResSentSucceeded,
 		m.RPCResSentFailed,
+		m.GRPCConnectionState,
+		m.GRPCActiveEndpoint,
+		m.TwinUpdatesSucceeded,
+		m.TwinUpdatesFailed,
 	)
 }
 
@@ -41,6 +77,38 @@ func NewMetrics() *Metrics {
 			Name: "onms_sink_messages_delivery_failed",
 			Help: "The total number of failed attempts to send Sink messages per module",
 		}, []string{"minion", "module"}),
+		SinkQueueMessages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_sink_queue_messages",
+			Help: "The number of Sink messages waiting on the persistent queue per module",
+		}, []string{"minion", "module"}),
+		SinkQueueBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_sink_queue_bytes",
+			Help: "The size in bytes of the Sink messages waiting on the persistent queue per module",
+		}, []string{"minion", "module"}),
+		SinkQueueDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_queue_dropped",
+			Help: "The total number of Sink messages discarded because the persistent queue is full per module",
+		}, []string{"minion", "module"}),
+		SinkMsgShed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_messages_shed",
+			Help: "The total number of Sink messages discarded by the rate limiters per module and source",
+		}, []string{"minion", "module", "source"}),
+		SinkTrapDecodingErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_trap_decoding_errors",
+			Help: "The total number of SNMP traps that cannot be decoded or authenticated per SNMPv3 user (or unknown) and reason (decode, unknown_user, auth_failure or not_allowed)",
+		}, []string{"minion", "user", "reason"}),
+		SinkTrapsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_traps_received",
+			Help: "The total number of SNMP traps and informs received per type (trap or inform)",
+		}, []string{"minion", "type"}),
+		SinkInformAckFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_informs_ack_failed",
+			Help: "The total number of failed attempts to acknowledge SNMP informs",
+		}, []string{"minion"}),
+		SinkRuleMatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_sink_rule_matches",
+			Help: "The total number of Sink messages matched by each rule per module, including the dropped ones",
+		}, []string{"minion", "module", "rule", "action"}),
 		RPCReqReceivedSucceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "onms_rpc_requests_received_succeeded",
 			Help: "The total number of RPC requests successfully received per module",
@@ -57,6 +125,31 @@ func NewMetrics() *Metrics {
 			Name: "onms_rpc_requests_processed_failed",
 			Help: "The total number of failed attempts to process RPC messages per module",
 		}, []string{"minion", "module"}),
+		RPCReqExpired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_rpc_requests_expired",
+			Help: "The total number of RPC requests that expired before or during their execution per module",
+		}, []string{"minion", "module"}),
+		RPCReqRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_rpc_requests_rejected",
+			Help: "The total number of RPC requests rejected because the worker queue is full per module",
+		}, []string{"minion", "module"}),
+		RPCReqQueueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "onms_rpc_requests_queue_wait_seconds",
+			Help:    "The time RPC requests wait for an available worker per module",
+			Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+		}, []string{"minion", "module"}),
+		RPCChunksIncomplete: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_rpc_chunks_incomplete_messages",
+			Help: "The number of RPC messages waiting for missing chunks",
+		}, []string{"minion"}),
+		RPCChunksBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_rpc_chunks_incomplete_bytes",
+			Help: "The size in bytes of the RPC messages waiting for missing chunks",
+		}, []string{"minion"}),
+		RPCChunksEvicted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_rpc_chunks_evicted_messages",
+			Help: "The total number of incomplete RPC messages discarded because they expired or the buffer is full per module",
+		}, []string{"minion", "module"}),
 		RPCResSentSucceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "onms_rpc_responses_sent_succeeded",
 			Help: "The total number of RPC responses successfully sent per module",
@@ -65,5 +158,21 @@ func NewMetrics() *Metrics {
 			Name: "onms_rpc_responses_sent_failed",
 			Help: "The total number of failed attempts to send RPC responses per module",
 		}, []string{"minion", "module"}),
+		GRPCConnectionState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_grpc_connection_state",
+			Help: "The state of the gRPC connections: 0=Idle, 1=Connecting, 2=Ready, 3=TransientFailure, 4=Shutdown",
+		}, []string{"minion", "connection"}),
+		GRPCActiveEndpoint: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "onms_grpc_active_endpoint",
+			Help: "The active gRPC endpoint, when using a list of endpoints: 1=Active, 0=Standby",
+		}, []string{"minion", "endpoint"}),
+		TwinUpdatesSucceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_twin_updates_succeeded",
+			Help: "The total number of Twin updates successfully applied per object key",
+		}, []string{"minion", "key"}),
+		TwinUpdatesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "onms_twin_updates_failed",
+			Help: "The total number of Twin updates that cannot be applied per object key",
+		}, []string{"minion", "key"}),
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/xml"
 	"time"
 
@@ -78,7 +79,8 @@ type SNMPAgentDTO struct {
 }
 
 // GetSNMPClient gets an SNMP Client instance
-func (agent *SNMPAgentDTO) GetSNMPClient() SNMPHandler {
+// The context can be used to cancel in-flight requests, including walks
+func (agent *SNMPAgentDTO) GetSNMPClient(ctx context.Context) SNMPHandler {
 	session := &gosnmp.GoSNMP{
 		Target:             agent.Address,
 		Port:               uint16(agent.Port),
@@ -90,6 +92,7 @@ func (agent *SNMPAgentDTO) GetSNMPClient() SNMPHandler {
 		MaxOids:            60, // default
 		Retries:            agent.Retries,
 		MaxRepetitions:     uint32(agent.MaxRepetitions),
+		Context:            ctx,
 	}
 	if agent.Version == 3 {
 		session.SecurityModel = gosnmp.UserSecurityModel
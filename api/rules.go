@@ -0,0 +1,223 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Actions of the Sink rules
+const (
+	RuleActionDrop    = "drop"    // Discards the matching messages
+	RuleActionRewrite = "rewrite" // Replaces fields of the matching messages
+	RuleActionTag     = "tag"     // Adds fields to the matching messages
+)
+
+// RuleActions represents the valid actions of the Sink rules
+var RuleActions = []string{RuleActionDrop, RuleActionRewrite, RuleActionTag}
+
+// RuleModules represents the Sink modules that support rules
+var RuleModules = []string{"Trap", "Syslog"}
+
+// SyslogFacilities represents the Syslog facility names, indexed by their code (RFC 5424)
+var SyslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// SyslogSeverities represents the Syslog severity names, indexed by their code (RFC 5424)
+var SyslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Fields that the rewrite action can replace on traps, besides the varbinds by OID
+var trapRuleFields = []string{"agentAddress", "community", "enterpriseId", "generic", "specific"}
+
+// Fields that the rewrite action can replace on Syslog messages
+var syslogRuleFields = []string{"facility", "severity", "message"}
+
+// SinkRuleMatch represents the conditions of a Sink rule; all the configured ones must match
+type SinkRuleMatch struct {
+	Source       string            `yaml:"source,omitempty" json:"source,omitempty"`             // IP address or CIDR of the sender
+	EnterpriseID string            `yaml:"enterpriseId,omitempty" json:"enterpriseId,omitempty"` // Trap enterprise OID, including the ones under it
+	Generic      *int              `yaml:"generic,omitempty" json:"generic,omitempty"`           // Trap generic type
+	Specific     *int              `yaml:"specific,omitempty" json:"specific,omitempty"`         // Trap specific type
+	Varbinds     map[string]string `yaml:"varbinds,omitempty" json:"varbinds,omitempty"`         // Regular expressions for the trap varbind values by OID
+	Facility     string            `yaml:"facility,omitempty" json:"facility,omitempty"`         // Syslog facility name or code
+	Severity     string            `yaml:"severity,omitempty" json:"severity,omitempty"`         // Syslog severity name or code
+	Host         string            `yaml:"host,omitempty" json:"host,omitempty"`                 // Regular expression for the Syslog host name
+	Message      string            `yaml:"message,omitempty" json:"message,omitempty"`           // Regular expression for the Syslog message
+}
+
+// SinkRule represents a rule to drop, rewrite or tag the traps or Syslog messages received by the Minion
+type SinkRule struct {
+	Name   string            `yaml:"name" json:"name"`
+	Match  SinkRuleMatch     `yaml:"match" json:"match"`
+	Action string            `yaml:"action" json:"action"`                 // drop, rewrite or tag
+	Set    map[string]string `yaml:"set,omitempty" json:"set,omitempty"`   // Fields to replace, for rewrite
+	Tags   map[string]string `yaml:"tags,omitempty" json:"tags,omitempty"` // Fields to add, for tag
+}
+
+// IsValid returns an error if the rule is not valid for a given Sink module
+func (rule *SinkRule) IsValid(moduleID string) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name required")
+	}
+	isTrap := strings.EqualFold(moduleID, "Trap")
+	match := rule.Match
+	if match.Source != "" {
+		if _, err := ParseSource(match.Source); err != nil {
+			return err
+		}
+	}
+	if isTrap {
+		if match.Facility != "" || match.Severity != "" || match.Host != "" || match.Message != "" {
+			return fmt.Errorf("facility, severity, host and message only apply to Syslog")
+		}
+		for oid, expr := range match.Varbinds {
+			if !isOID(oid) {
+				return fmt.Errorf("invalid varbind OID %s", oid)
+			}
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid expression for varbind %s: %v", oid, err)
+			}
+		}
+	} else {
+		if match.EnterpriseID != "" || match.Generic != nil || match.Specific != nil || len(match.Varbinds) > 0 {
+			return fmt.Errorf("enterpriseId, generic, specific and varbinds only apply to traps")
+		}
+		if _, err := ParseSyslogCode(match.Facility, SyslogFacilities); err != nil {
+			return fmt.Errorf("invalid facility: %v", err)
+		}
+		if _, err := ParseSyslogCode(match.Severity, SyslogSeverities); err != nil {
+			return fmt.Errorf("invalid severity: %v", err)
+		}
+		for _, expr := range []string{match.Host, match.Message} {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid expression %s: %v", expr, err)
+			}
+		}
+	}
+	switch rule.Action {
+	case RuleActionDrop:
+		return nil
+	case RuleActionRewrite:
+		if len(rule.Set) == 0 {
+			return fmt.Errorf("the rewrite action requires the fields to set")
+		}
+		for field, value := range rule.Set {
+			if err := validateRuleField(isTrap, field, value); err != nil {
+				return err
+			}
+		}
+	case RuleActionTag:
+		if len(rule.Tags) == 0 {
+			return fmt.Errorf("the tag action requires the tags to add")
+		}
+		for tag := range rule.Tags {
+			if isTrap && !isOID(tag) {
+				return fmt.Errorf("invalid tag %s, the tags of traps must be varbind OIDs", tag)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid action %s%s", rule.Action, Suggestion(rule.Action, RuleActions))
+	}
+	return nil
+}
+
+// GetRules gets the rules for a given Sink module by ID; returns nil when not configured
+func (cfg *MinionConfig) GetRules(moduleID string) []SinkRule {
+	for module, rules := range cfg.Rules {
+		if strings.EqualFold(module, moduleID) {
+			return rules
+		}
+	}
+	return nil
+}
+
+// ParseSource parses an IP address or a CIDR
+func ParseSource(source string) (*net.IPNet, error) {
+	if !strings.Contains(source, "/") {
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source %s, expected an IP address or CIDR", source)
+		}
+		bits := 8 * len(ip.To16())
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source %s, expected an IP address or CIDR", source)
+	}
+	return network, nil
+}
+
+// ParseSyslogCode parses a Syslog facility or severity, by name or code, from a given list; returns -1 when empty
+func ParseSyslogCode(value string, names []string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+	if code, err := strconv.Atoi(value); err == nil {
+		if code < 0 || code >= len(names) {
+			return -1, fmt.Errorf("code %d out of range", code)
+		}
+		return code, nil
+	}
+	if code := slices.IndexFunc(names, func(name string) bool { return strings.EqualFold(name, value) }); code >= 0 {
+		return code, nil
+	}
+	return -1, fmt.Errorf("unknown value %s%s", value, Suggestion(value, names))
+}
+
+// Validates a field replaced by the rewrite action
+func validateRuleField(isTrap bool, field string, value string) error {
+	if !isTrap {
+		switch strings.ToLower(field) {
+		case "facility":
+			_, err := ParseSyslogCode(value, SyslogFacilities)
+			return err
+		case "severity":
+			_, err := ParseSyslogCode(value, SyslogSeverities)
+			return err
+		case "message":
+			return nil
+		}
+		return fmt.Errorf("invalid field %s%s", field, Suggestion(field, syslogRuleFields))
+	}
+	if isOID(field) {
+		return nil
+	}
+	switch strings.ToLower(field) {
+	case "agentaddress":
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("invalid agent address %s", value)
+		}
+	case "generic", "specific":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid %s %s", field, value)
+		}
+	case "community", "enterpriseid":
+	default:
+		return fmt.Errorf("invalid field %s%s", field, Suggestion(field, trapRuleFields))
+	}
+	return nil
+}
+
+// Returns true when a given string is a numeric OID, with or without the leading dot
+func isOID(oid string) bool {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
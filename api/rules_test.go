@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRulesConfiguration(t *testing.T) {
+	generic := 2
+	config := &MinionConfig{
+		ID:        "minion1",
+		Location:  "Test",
+		BrokerURL: "10.0.0.100:8990",
+		Rules: map[string][]SinkRule{
+			"trap": {
+				{Name: "link-down", Match: SinkRuleMatch{Source: "10.0.0.0/8", Generic: &generic}, Action: RuleActionDrop},
+				{Name: "rewrite", Match: SinkRuleMatch{EnterpriseID: ".1.3.6.1.4.1.9"}, Action: RuleActionRewrite, Set: map[string]string{"enterpriseid": ".1.3.6.1.4.1.9.1", "agentaddress": "10.0.0.1"}},
+			},
+			"syslog": {
+				{Name: "debug", Match: SinkRuleMatch{Facility: "local7", Severity: "7"}, Action: RuleActionTag, Tags: map[string]string{"site": "dc1"}},
+			},
+		},
+	}
+	assert.NilError(t, config.IsValid())
+	assert.Equal(t, 2, len(config.GetRules("Trap")))
+	assert.Assert(t, config.GetRules("Netflow-5") == nil)
+
+	syslog := &config.Rules["syslog"][0]
+	syslog.Match.Severity = "warn"
+	assert.ErrorContains(t, config.IsValid(), "invalid rule 1 (debug) for syslog: invalid severity: unknown value warn")
+	syslog.Match.Severity = "8"
+	assert.ErrorContains(t, config.IsValid(), "code 8 out of range")
+	syslog.Match.Severity = ""
+	syslog.Match.Message = "(unclosed"
+	assert.ErrorContains(t, config.IsValid(), "invalid expression (unclosed")
+	syslog.Match.Message = ""
+	syslog.Match.EnterpriseID = ".1.3.6.1.4.1.9"
+	assert.ErrorContains(t, config.IsValid(), "only apply to traps")
+	syslog.Match.EnterpriseID = ""
+
+	trap := &config.Rules["trap"][1]
+	trap.Set["comunity"] = "public"
+	assert.ErrorContains(t, config.IsValid(), "invalid field comunity (did you mean community?)")
+	delete(trap.Set, "comunity")
+	trap.Action = RuleActionTag
+	assert.ErrorContains(t, config.IsValid(), "the tag action requires the tags to add")
+	trap.Tags = map[string]string{"site": "dc1"}
+	assert.ErrorContains(t, config.IsValid(), "invalid tag site, the tags of traps must be varbind OIDs")
+	trap.Action = RuleActionDrop
+	trap.Match.Source = "10.0.0.300"
+	assert.ErrorContains(t, config.IsValid(), "invalid source 10.0.0.300")
+	trap.Match.Source = ""
+
+	config.Rules["Heartbeat"] = []SinkRule{{Name: "all", Action: RuleActionDrop}}
+	assert.ErrorContains(t, config.IsValid(), "rules not supported for module Heartbeat")
+}
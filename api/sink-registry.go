@@ -1,17 +1,28 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 )
 
 // SinkRegistry tracks all the enabled Sink module instances for a given broker.
+// Once started, modules can be disabled and enabled again at runtime.
 type SinkRegistry struct {
 	sinkRegistryMap map[string]SinkModule
+	running         map[string]bool
+	config          *MinionConfig
+	sink            Sink
+	mutex           *sync.Mutex
 }
 
 // Init initializes a new Sink registry
 func (r *SinkRegistry) Init() {
 	r.sinkRegistryMap = make(map[string]SinkModule)
+	r.running = make(map[string]bool)
+	r.mutex = new(sync.Mutex)
 }
 
 // RegisterModule registers a new RPC Module implementation
@@ -33,19 +44,130 @@ func (r *SinkRegistry) GetAllModules() []SinkModule {
 	return modules
 }
 
+// GetRunningModules gets the Sink modules that are running
+func (r *SinkRegistry) GetRunningModules() []SinkModule {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	modules := make([]SinkModule, 0, len(r.sinkRegistryMap))
+	for id, m := range r.sinkRegistryMap {
+		if r.running[id] {
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
 // StartModules starts all the registered Sink modules (non-blocking method)
 func (r *SinkRegistry) StartModules(config *MinionConfig, sink Sink) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.config = config
+	r.sink = sink
 	for _, m := range r.sinkRegistryMap {
 		if err := m.Start(config, sink); err != nil {
 			return fmt.Errorf("cannot start Sink API module %s: %v", m.GetID(), err)
 		}
+		r.running[m.GetID()] = true
 	}
 	return nil
 }
 
-// StopModules stops all the registered Sink modules
+// StopModules stops all the running Sink modules
 func (r *SinkRegistry) StopModules() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	for _, m := range r.sinkRegistryMap {
+		if r.running[m.GetID()] {
+			m.Stop()
+			r.running[m.GetID()] = false
+		}
+	}
+}
+
+// ReloadModules restarts the running Sink modules affected by a configuration change, and returns their IDs.
+// The updated configuration is used by the modules started from now on.
+// When a module cannot start with the updated configuration, all the restarted modules are started again with the current one,
+// which is kept for the modules started from now on.
+func (r *SinkRegistry) ReloadModules(updated *MinionConfig) ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.config == nil {
+		return nil, fmt.Errorf("sink modules have not been started")
+	}
+	current := r.config
+	restarted := make([]string, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.sinkRegistryMap)) {
+		m := r.sinkRegistryMap[id]
+		reloadable, ok := m.(ReloadableSinkModule)
+		if !ok || !r.running[id] || !reloadable.RequiresRestart(current, updated) {
+			continue
+		}
+		m.Stop()
+		if err := m.Start(updated, r.sink); err != nil {
+			errs := []error{fmt.Errorf("cannot start Sink API module %s with the updated configuration: %v", id, err)}
+			errs = append(errs, r.rollback(append(restarted, id), current)...)
+			return nil, errors.Join(errs...)
+		}
+		restarted = append(restarted, id)
+	}
+	r.config = updated
+	return restarted, nil
+}
+
+// Starts again a given list of Sink modules with a given configuration
+func (r *SinkRegistry) rollback(ids []string, config *MinionConfig) []error {
+	errs := make([]error, 0)
+	for _, id := range ids {
+		m := r.sinkRegistryMap[id]
 		m.Stop()
+		if err := m.Start(config, r.sink); err != nil {
+			r.running[id] = false
+			errs = append(errs, fmt.Errorf("cannot start Sink API module %s again: %v", id, err))
+		}
+	}
+	return errs
+}
+
+// IsRunning returns true when a given Sink module is running
+func (r *SinkRegistry) IsRunning(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.running[id]
+}
+
+// EnableModule starts a given Sink module that was disabled at runtime
+func (r *SinkRegistry) EnableModule(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m, ok := r.sinkRegistryMap[id]
+	if !ok {
+		return fmt.Errorf("sink module %s not found", id)
+	}
+	if r.config == nil {
+		return fmt.Errorf("sink modules have not been started")
 	}
+	if r.running[id] {
+		return fmt.Errorf("sink module %s is already running", id)
+	}
+	if err := m.Start(r.config, r.sink); err != nil {
+		return fmt.Errorf("cannot start Sink API module %s: %v", id, err)
+	}
+	r.running[id] = true
+	return nil
+}
+
+// DisableModule stops a given running Sink module at runtime
+func (r *SinkRegistry) DisableModule(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m, ok := r.sinkRegistryMap[id]
+	if !ok {
+		return fmt.Errorf("sink module %s not found", id)
+	}
+	if !r.running[id] {
+		return fmt.Errorf("sink module %s is not running", id)
+	}
+	m.Stop()
+	r.running[id] = false
+	return nil
 }
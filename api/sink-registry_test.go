@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+type mockReloadableModule struct {
+	id      string
+	port    int
+	fail    bool
+	started int
+	stopped int
+}
+
+func (m *mockReloadableModule) GetID() string {
+	return m.id
+}
+
+func (m *mockReloadableModule) Start(config *MinionConfig, sink Sink) error {
+	if m.fail && config.SyslogPort == 0 {
+		return fmt.Errorf("invalid port")
+	}
+	m.port = config.SyslogPort
+	m.started++
+	return nil
+}
+
+func (m *mockReloadableModule) Stop() {
+	m.stopped++
+}
+
+func (m *mockReloadableModule) RequiresRestart(current *MinionConfig, updated *MinionConfig) bool {
+	return current.SyslogPort != updated.SyslogPort
+}
+
+type mockStaticModule struct {
+	started int
+}
+
+func (m *mockStaticModule) GetID() string {
+	return "Static"
+}
+
+func (m *mockStaticModule) Start(config *MinionConfig, sink Sink) error {
+	m.started++
+	return nil
+}
+
+func (m *mockStaticModule) Stop() {}
+
+func TestReloadModules(t *testing.T) {
+	registry := new(SinkRegistry)
+	registry.Init()
+	netflow := &mockReloadableModule{id: "Netflow-5"}
+	syslog := &mockReloadableModule{id: "Syslog"}
+	static := &mockStaticModule{}
+	registry.RegisterModule(netflow)
+	registry.RegisterModule(syslog)
+	registry.RegisterModule(static)
+
+	_, err := registry.ReloadModules(&MinionConfig{SyslogPort: 1514})
+	assert.ErrorContains(t, err, "sink modules have not been started")
+
+	assert.NilError(t, registry.StartModules(&MinionConfig{SyslogPort: 1514}, nil))
+	assert.Equal(t, 1, syslog.started)
+	assert.Equal(t, 1, static.started)
+
+	// Nothing changed
+	restarted, err := registry.ReloadModules(&MinionConfig{SyslogPort: 1514})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(restarted))
+	assert.Equal(t, 1, syslog.started)
+
+	// Only the affected module is restarted
+	restarted, err = registry.ReloadModules(&MinionConfig{SyslogPort: 1515})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"Netflow-5", "Syslog"}, restarted)
+	assert.Equal(t, 1, syslog.stopped)
+	assert.Equal(t, 2, syslog.started)
+	assert.Equal(t, 1515, syslog.port)
+	assert.Equal(t, 1, static.started)
+
+	// All the restarted modules are started again with the current configuration when the updated one fails
+	syslog.fail = true
+	restarted, err = registry.ReloadModules(&MinionConfig{SyslogPort: 0})
+	assert.ErrorContains(t, err, "cannot start Sink API module Syslog with the updated configuration")
+	assert.Equal(t, 0, len(restarted))
+	assert.Equal(t, 1515, syslog.port)
+	assert.Equal(t, 1515, netflow.port)
+	assert.Equal(t, 1515, registry.config.SyslogPort)
+	assert.Assert(t, registry.IsRunning("Syslog"))
+	assert.Assert(t, registry.IsRunning("Netflow-5"))
+}
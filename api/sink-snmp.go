@@ -1,6 +1,9 @@
 package api
 
-import "encoding/xml"
+import (
+	"encoding/base64"
+	"encoding/xml"
+)
 
 // SNMPResults represents a collection of SNMP result instances
 type SNMPResults struct {
@@ -14,15 +17,38 @@ type TrapIdentityDTO struct {
 	Specific     int    `xml:"specific,attr"`
 }
 
+// Base64Binary represents binary content, encoded in base64 like JAXB does for byte arrays
+type Base64Binary []byte
+
+// MarshalXML converts the binary content into base64
+func (b Base64Binary) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(base64.StdEncoding.EncodeToString(b), start)
+}
+
+// UnmarshalXML converts base64 content into binary
+func (b *Base64Binary) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = data
+	return nil
+}
+
 // TrapDTO represents an SNMP Trap
 type TrapDTO struct {
 	AgentAddress string           `xml:"agent-address"`
 	Community    string           `xml:"community"`
 	Version      string           `xml:"version"`
+	Inform       bool             `xml:"inform,omitempty"` // True for SNMP informs, acknowledged by the Minion
 	Timestamp    int64            `xml:"timestamp"`
 	CreationTime int64            `xml:"creation-time"`
 	PDULength    int              `xml:"pdu-length"`
-	RawMessage   []byte           `xml:"raw-message,omitempty"`
+	RawMessage   Base64Binary     `xml:"raw-message,omitempty"`
 	TrapIdentity *TrapIdentityDTO `xml:"trap-identity"`
 	Results      *SNMPResults     `xml:"results"`
 }
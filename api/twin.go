@@ -0,0 +1,21 @@
+package api
+
+import (
+	"encoding/json"
+)
+
+// TwinObject represents a versioned object received via the Twin API
+type TwinObject struct {
+	Key       string // The consumer key of the object
+	SessionID string // The session of the publisher; versions are only comparable within a session
+	Version   int32  // The version of the object within the session
+	Content   []byte // The object serialized as JSON
+}
+
+// Unmarshal parses the JSON content of the Twin object into a given value
+func (obj *TwinObject) Unmarshal(value interface{}) error {
+	return json.Unmarshal(obj.Content, value)
+}
+
+// TwinHandler represents a function that processes a new version of a Twin object
+type TwinHandler func(obj *TwinObject)
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// LogLevels represents the supported logging levels
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// tlsFileProperties represents the broker properties that point to files
+var tlsFileProperties = []string{"ca-cert-path", "client-cert-path", "client-key-path", "sasl-token-path"}
+
+// Validate performs a comprehensive validation of the configuration, and returns all the problems found.
+// The listener parsers, the port collisions and the broker properties are validated by the sink and broker packages.
+func (cfg *MinionConfig) Validate() []error {
+	errs := cfg.getSettingsErrors()
+	if cfg.LogLevel != "" && !slices.Contains(LogLevels, strings.ToLower(cfg.LogLevel)) {
+		errs = append(errs, fmt.Errorf("invalid logLevel %s%s", cfg.LogLevel, Suggestion(cfg.LogLevel, LogLevels)))
+	}
+	ports := []struct {
+		name  string
+		value int
+	}{{"trapPort", cfg.TrapPort}, {"syslogPort", cfg.SyslogPort}, {"statsPort", cfg.StatsPort}, {"adminPort", cfg.AdminPort}}
+	for _, port := range ports {
+		if port.value < 0 || port.value > 65535 {
+			errs = append(errs, fmt.Errorf("invalid %s %d", port.name, port.value))
+		}
+	}
+	names := make(map[string]bool)
+	for _, listener := range cfg.Listeners {
+		if listener.Name == "" {
+			errs = append(errs, fmt.Errorf("listener name required for parser %s", listener.Parser))
+		} else if names[listener.Name] {
+			errs = append(errs, fmt.Errorf("duplicate listener %s", listener.Name))
+		}
+		names[listener.Name] = true
+		if listener.Port <= 0 || listener.Port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid port %d on listener %s", listener.Port, listener.Name))
+		}
+	}
+	for module, aggregation := range cfg.Aggregation {
+		if aggregation.BatchSize < 0 || aggregation.BatchInterval < 0 {
+			errs = append(errs, fmt.Errorf("invalid aggregation for %s: batch size and interval cannot be negative", module))
+		}
+	}
+	if cfg.Syslog != nil {
+		for _, path := range []string{cfg.Syslog.CertFile, cfg.Syslog.KeyFile, cfg.Syslog.ClientCAFile} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				errs = append(errs, fmt.Errorf("invalid Syslog TLS file: %v", err))
+			}
+		}
+	}
+	for _, property := range tlsFileProperties {
+		if path := cfg.GetBrokerProperty(property); path != "" {
+			if _, err := os.Stat(path); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %v", property, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Suggestion returns a hint with the candidate most similar to a given value (ignoring case), or an empty string when none is close enough
+func Suggestion(value string, candidates []string) string {
+	best := ""
+	bestDistance := len(value)/3 + 1
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(value), strings.ToLower(candidate)); d <= bestDistance && (best == "" || d < bestDistance) {
+			best = candidate
+			bestDistance = d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+// Gets the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateConfiguration(t *testing.T) {
+	caCert := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, os.WriteFile(caCert, []byte("cert"), 0600))
+	config := &MinionConfig{
+		ID:               "minion1",
+		Location:         "Test",
+		BrokerURL:        "10.0.0.100:8990",
+		BrokerProperties: map[string]string{"ca-cert-path": caCert},
+		TrapPort:         1162,
+		SyslogPort:       1514,
+		LogLevel:         "info",
+		Listeners:        []MinionListener{{Name: "Netflow-5", Port: 8877, Parser: "Netflow5UdpParser"}},
+	}
+	assert.Equal(t, 0, len(config.Validate()))
+
+	config.Location = ""
+	config.LogLevel = "inf"
+	config.TrapPort = 70000
+	config.BrokerProperties["client-cert-path"] = "/missing/client.crt"
+	config.Listeners = append(config.Listeners, MinionListener{Name: "Netflow-5", Port: 0, Parser: "Netflow5UdpParser"})
+	config.Aggregation = map[string]AggregationConfig{"syslog": {BatchSize: -1}}
+	errs := config.Validate()
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	assert.DeepEqual(t, []string{
+		"location required",
+		"invalid logLevel inf (did you mean info?)",
+		"invalid trapPort 70000",
+		"duplicate listener Netflow-5",
+		"invalid port 0 on listener Netflow-5",
+		"invalid aggregation for syslog: batch size and interval cannot be negative",
+		"invalid client-cert-path: stat /missing/client.crt: no such file or directory",
+	}, messages)
+	assert.ErrorContains(t, config.IsValid(), "location required")
+}
+
+func TestSuggestion(t *testing.T) {
+	candidates := []string{"tls-enabled", "tls-server-name", "twin-url"}
+	assert.Equal(t, " (did you mean tls-enabled?)", Suggestion("tls-enable", candidates))
+	assert.Equal(t, " (did you mean twin-url?)", Suggestion("Twin-URL", candidates))
+	assert.Equal(t, "", Suggestion("compression", candidates))
+}
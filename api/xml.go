@@ -0,0 +1,23 @@
+package api
+
+import (
+	"encoding/xml"
+	"sync/atomic"
+)
+
+// compactXML indicates whether the XML payloads sent to OpenNMS are generated without indentation
+var compactXML atomic.Bool
+
+// SetCompactXML enables or disables compact XML payloads; indented XML is generated by default.
+func SetCompactXML(compact bool) {
+	compactXML.Store(compact)
+}
+
+// MarshalXMLPayload generates the XML payload of an RPC response or a Sink message.
+// Compact XML reduces the size of large payloads like SNMP walks and collection sets.
+func MarshalXMLPayload(object interface{}) ([]byte, error) {
+	if compactXML.Load() {
+		return xml.Marshal(object)
+	}
+	return xml.MarshalIndent(object, "", "   ")
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMarshalXMLPayload(t *testing.T) {
+	defer SetCompactXML(false)
+	response := &EchoResponse{ID: 1, Body: "hello"}
+
+	bytes, err := MarshalXMLPayload(response)
+	assert.NilError(t, err)
+	assert.Equal(t, "<echo-response id=\"1\">\n   <body>hello</body>\n</echo-response>", string(bytes))
+
+	SetCompactXML(true)
+	bytes, err = MarshalXMLPayload(response)
+	assert.NilError(t, err)
+	assert.Equal(t, "<echo-response id=\"1\"><body>hello</body></echo-response>", string(bytes))
+}
+
+func TestMarshalTrapRawMessage(t *testing.T) {
+	defer SetCompactXML(false)
+	SetCompactXML(true)
+	trap := TrapDTO{AgentAddress: "10.0.0.1", Version: "v2c", RawMessage: []byte{0x30, 0x82, 0x00}}
+	bytes, err := MarshalXMLPayload(trap)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(bytes), "<raw-message>MIIA</raw-message>"))
+
+	decoded := TrapDTO{}
+	assert.NilError(t, xml.Unmarshal(bytes, &decoded))
+	assert.DeepEqual(t, trap.RawMessage, decoded.RawMessage)
+
+	trap.RawMessage = nil
+	bytes, err = MarshalXMLPayload(trap)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(bytes), "raw-message"))
+}
@@ -0,0 +1,305 @@
+package broker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/ipc"
+)
+
+// ActiveMQClient represents the ActiveMQ client implementation for the OpenNMS IPC API, using STOMP.
+// It follows the same queue names used by the JMS implementation of the Java Minion:
+// RPC requests are consumed from <instance-id>.<location>.RPC.<module> and the responses are sent to the reply-to destination,
+// and Sink messages are sent to <instance-id>.Sink.<module>.
+type ActiveMQClient struct {
+	config      *api.MinionConfig
+	registry    *api.SinkRegistry
+	conn        *stompConn
+	queue       *sinkQueue
+	pool        *rpcWorkerPool
+	traceCloser io.Closer
+	metrics     *api.Metrics
+	instanceID  string
+	tlsConfig   *tls.Config
+	reconnect   backoffConfig
+	shutdown    time.Duration
+	heartbeat   time.Duration
+	watchdog    *loopWatchdog
+	connMutex   *sync.RWMutex
+	stopping    atomic.Bool
+}
+
+// Start initializes the ActiveMQ client.
+// Returns an error when the configuration is incorrect or cannot connect to the server.
+func (cli *ActiveMQClient) Start() error {
+	var err error
+	if cli.config == nil {
+		return fmt.Errorf("minion configuration required")
+	}
+	if cli.registry == nil {
+		return fmt.Errorf("sink registry required")
+	}
+	if cli.metrics == nil {
+		return fmt.Errorf("prometheus Metrics required")
+	}
+
+	cli.connMutex = new(sync.RWMutex)
+	cli.stopping.Store(false)
+
+	// The OpenNMS Instance ID (org.opennms.instance.id), for the queue names
+	cli.instanceID = cli.config.GetBrokerProperty("instance-id")
+	if cli.instanceID == "" {
+		cli.instanceID = "OpenNMS"
+	}
+
+	if cli.reconnect, err = getBackoffConfig(cli.config); err != nil {
+		return err
+	}
+	if cli.pool, err = newRPCWorkerPool(cli.config, cli.metrics); err != nil {
+		return err
+	}
+	if cli.shutdown, err = getShutdownTimeout(cli.config); err != nil {
+		return err
+	}
+	if cli.watchdog, err = newLoopWatchdog(cli.config, "RPC consumer loop"); err != nil {
+		return err
+	}
+	if cli.heartbeat, err = getDurationProperty(cli.config, "heartbeat-interval", stompDefaultHeartbeat); err != nil {
+		return err
+	}
+	if cli.config.GetBrokerProperty("tls-enabled") == "true" {
+		log.Infof("Enabling TLS")
+		if cli.tlsConfig, err = getTLSConfig(cli.config); err != nil {
+			return err
+		}
+	}
+
+	if cli.traceCloser, err = initTracing(cli.config); err != nil {
+		return err
+	}
+
+	if err = cli.connect(); err != nil {
+		return err
+	}
+
+	if cli.queue, err = newSinkQueue(cli.config, cli.metrics, cli.sendMessage); err != nil {
+		return err
+	}
+	if cli.queue != nil {
+		cli.queue.start()
+	}
+
+	if err := cli.registry.StartModules(cli.config, cli); err != nil {
+		return err
+	}
+
+	go cli.consume()
+	return nil
+}
+
+// Stop finalizes the ActiveMQ client and all its dependencies.
+// In-flight RPC requests and pending Sink messages are drained before closing the connection, up to the shutdown timeout.
+func (cli *ActiveMQClient) Stop() {
+	cli.registry.StopModules()
+	log.Warnf("Stopping ActiveMQ client")
+	summary := drainClient(cli.shutdown, cli.pool, cli.getSinkDrainers()...)
+	if cli.queue != nil {
+		cli.queue.stop()
+	}
+	cli.stopping.Store(true)
+	cli.connMutex.Lock()
+	if cli.conn != nil {
+		cli.conn.close()
+	}
+	cli.connMutex.Unlock()
+	if cli.traceCloser != nil {
+		cli.traceCloser.Close()
+	}
+	summary.log()
+	log.Infof("Good bye")
+}
+
+// Gets the pending Sink messages to drain when stopping.
+func (cli *ActiveMQClient) getSinkDrainers() []sinkDrainer {
+	if cli.queue == nil {
+		return nil
+	}
+	return []sinkDrainer{{pending: cli.queue.count, flush: cli.queue.flush}}
+}
+
+// GetState gets the state of the connection with the ActiveMQ broker.
+func (cli *ActiveMQClient) GetState() api.BrokerState {
+	cli.connMutex.RLock()
+	defer cli.connMutex.RUnlock()
+	return api.BrokerState{Type: "activemq", Endpoint: cli.config.BrokerURL, Connected: cli.conn != nil}
+}
+
+// CheckLiveness returns an error when the connection with the broker has been down for longer than the liveness timeout.
+func (cli *ActiveMQClient) CheckLiveness() error {
+	return cli.watchdog.check()
+}
+
+// GetMetrics gets the metrics of the ActiveMQ client, shared with the Sink modules.
+func (cli *ActiveMQClient) GetMetrics() *api.Metrics {
+	return cli.metrics
+}
+
+// Send forwards a Sink API message to ActiveMQ.
+// When the persistent queue is enabled, messages that cannot be delivered are stored until the broker is available.
+func (cli *ActiveMQClient) Send(msg *ipc.SinkMessage) error {
+	if cli.queue != nil {
+		return cli.queue.Send(msg)
+	}
+	return cli.sendMessage(msg)
+}
+
+// Forwards the content of a Sink API message to the queue of its module.
+// Messages are discarded when the broker is unavailable.
+func (cli *ActiveMQClient) sendMessage(msg *ipc.SinkMessage) error {
+	trace := startSpanForSinkMessage(msg)
+	defer trace.Finish()
+	destination := cli.getSinkQueue(msg.ModuleId)
+	err := cli.send(destination, nil, msg.Content)
+	if err != nil {
+		cli.metrics.SinkMsgDeliveryFailed.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+		trace.SetTag("failed", "true")
+		trace.LogKV("event", err.Error())
+		return fmt.Errorf("cannot send message to %s: %v", destination, err)
+	}
+	cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+	return nil
+}
+
+// Sends a message to a given destination using the current connection.
+func (cli *ActiveMQClient) send(destination string, headers map[string]string, body []byte) error {
+	cli.connMutex.RLock()
+	defer cli.connMutex.RUnlock()
+	if cli.conn == nil {
+		return fmt.Errorf("broker unreachable")
+	}
+	return cli.conn.send(destination, headers, body)
+}
+
+// Connects to the broker, and subscribes to the RPC queues of all the registered modules.
+func (cli *ActiveMQClient) connect() error {
+	log.Infof("Connecting to ActiveMQ at %s", cli.config.BrokerURL)
+	conn, err := dialStomp(cli.config.BrokerURL, cli.tlsConfig, cli.config.GetBrokerProperty("username"), cli.config.GetBrokerProperty("password"), cli.heartbeat)
+	if err != nil {
+		return fmt.Errorf("cannot connect to ActiveMQ: %v", err)
+	}
+	for _, module := range api.GetAllRPCModules() {
+		destination := cli.getRPCQueue(module.GetID())
+		if err := conn.subscribe(module.GetID(), destination); err != nil {
+			conn.close()
+			return fmt.Errorf("cannot subscribe to %s: %v", destination, err)
+		}
+		log.Debugf("Subscribed to %s", destination)
+	}
+	cli.connMutex.Lock()
+	cli.conn = conn
+	cli.connMutex.Unlock()
+	return nil
+}
+
+// Processes the frames sent by the broker, and reconnects when the connection is lost, until the client stops.
+// The watchdog considers the loop down while reconnecting.
+func (cli *ActiveMQClient) consume() {
+	log.Infof("starting RPC consumer for location %s", cli.config.Location)
+	cli.watchdog.started()
+	for {
+		cli.connMutex.RLock()
+		conn := cli.conn
+		cli.connMutex.RUnlock()
+		frame, err := conn.readFrame()
+		if err == nil {
+			switch frame.command {
+			case "MESSAGE":
+				cli.processRequest(frame)
+			case "ERROR":
+				log.Errorf("ActiveMQ error: %s %s", frame.header("message"), string(frame.body))
+			}
+			continue
+		}
+		cli.watchdog.stopped()
+		if cli.stopping.Load() {
+			return
+		}
+		log.Errorf("Lost connection with ActiveMQ: %v", err)
+		cli.connMutex.Lock()
+		conn.close()
+		cli.conn = nil
+		cli.connMutex.Unlock()
+		retry := newBackoff(cli.reconnect)
+		for {
+			time.Sleep(retry.next())
+			if cli.stopping.Load() {
+				return
+			}
+			err := cli.connect()
+			if err == nil {
+				log.Warnf("ActiveMQ connection restarted")
+				break
+			}
+			log.Warnf("%v", err)
+		}
+		cli.watchdog.started()
+	}
+}
+
+// Processes an RPC API request sent by OpenNMS asynchronously, and sends back the response from the module to the reply-to destination.
+func (cli *ActiveMQClient) processRequest(frame *stompFrame) {
+	moduleID := frame.header("subscription") // The subscription ID is the module ID
+	request := &ipc.RpcRequestProto{
+		RpcId:      frame.header("correlation-id"),
+		SystemId:   cli.config.ID,
+		ModuleId:   moduleID,
+		Location:   cli.config.Location,
+		RpcContent: frame.body,
+	}
+	if expires, err := strconv.ParseUint(frame.header("expires"), 10, 64); err == nil {
+		request.ExpirationTime = expires
+	}
+	replyTo := frame.header("reply-to")
+	if replyTo == "" {
+		cli.metrics.RPCReqReceivedFailed.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		log.Errorf("Ignoring RPC request for module %s without reply-to destination", moduleID)
+		return
+	}
+	cli.metrics.RPCReqReceivedSucceeded.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+	log.Debugf("Received RPC request with ID %s for module %s", request.RpcId, request.ModuleId)
+	if module, ok := api.GetRPCModule(moduleID); ok {
+		submitRPCRequest(cli.pool, cli.metrics, module, request, func(response *ipc.RpcResponseProto) error {
+			return cli.sendResponse(replyTo, response)
+		})
+	} else {
+		log.Errorf("Cannot find implementation for module %s, ignoring request with ID %s", request.ModuleId, request.RpcId)
+	}
+}
+
+// Sends an RPC API response to OpenNMS
+func (cli *ActiveMQClient) sendResponse(replyTo string, response *ipc.RpcResponseProto) error {
+	headers := map[string]string{"correlation-id": response.RpcId}
+	if err := cli.send(replyTo, headers, response.RpcContent); err != nil {
+		cli.metrics.RPCResSentFailed.WithLabelValues(response.SystemId, response.ModuleId).Inc()
+		return fmt.Errorf("cannot send RPC response for module %s with ID %s: %v", response.ModuleId, response.RpcId, err)
+	}
+	cli.metrics.RPCResSentSucceeded.WithLabelValues(response.SystemId, response.ModuleId).Inc()
+	return nil
+}
+
+// Gets the destination of the RPC requests for a given module at the Minion's location.
+func (cli *ActiveMQClient) getRPCQueue(module string) string {
+	return fmt.Sprintf("/queue/%s.%s.RPC.%s", cli.instanceID, cli.config.Location, module)
+}
+
+// Gets the destination of the Sink API messages for a given module.
+func (cli *ActiveMQClient) getSinkQueue(module string) string {
+	return fmt.Sprintf("/queue/%s.Sink.%s", cli.instanceID, module)
+}
@@ -0,0 +1,199 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/xml"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+// mockStompServer represents an in-process STOMP stand-in for ActiveMQ
+type mockStompServer struct {
+	listener      net.Listener
+	conn          *stompConn
+	connected     chan *stompFrame
+	subscriptions chan *stompFrame
+	messages      chan *stompFrame
+}
+
+// Starts a mock STOMP server, which accepts a single connection and responds with a given heart-beat header when it is not empty
+func startMockStompServer(t *testing.T, heartBeat string) *mockStompServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	server := &mockStompServer{
+		listener:      listener,
+		connected:     make(chan *stompFrame, 1),
+		subscriptions: make(chan *stompFrame, 100),
+		messages:      make(chan *stompFrame, 100),
+	}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.conn = &stompConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn), writeMutex: new(sync.Mutex)}
+		for {
+			frame, err := server.conn.readFrame()
+			if err != nil {
+				return
+			}
+			switch frame.command {
+			case "CONNECT":
+				headers := map[string]string{"version": "1.2"}
+				if heartBeat != "" {
+					headers["heart-beat"] = heartBeat
+				}
+				server.conn.writeFrame(&stompFrame{command: "CONNECTED", headers: headers})
+				server.connected <- frame
+			case "SUBSCRIBE":
+				server.subscriptions <- frame
+			case "SEND":
+				server.messages <- frame
+			}
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (server *mockStompServer) waitFor(t *testing.T, frames chan *stompFrame) *stompFrame {
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for STOMP frame")
+	}
+	return nil
+}
+
+func TestActiveMQClient(t *testing.T) {
+	server := startMockStompServer(t, "")
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		Location:   "Apex",
+		BrokerURL:  server.listener.Addr().String(),
+		BrokerType: "activemq",
+		BrokerProperties: map[string]string{
+			"username": "admin",
+			"password": "admin:secret",
+		},
+	}
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	cli := &ActiveMQClient{config: config, registry: registry, metrics: api.NewMetrics()}
+	assert.NilError(t, cli.Start())
+	defer cli.Stop()
+
+	connect := server.waitFor(t, server.connected)
+	assert.Equal(t, "10000,10000", connect.header("heart-beat"))
+	assert.Equal(t, "admin", connect.header("login"))
+	assert.Equal(t, "admin:secret", connect.header("passcode"))
+
+	destinations := make(map[string]string)
+	for range api.GetAllRPCModules() {
+		frame := server.waitFor(t, server.subscriptions)
+		destinations[frame.header("id")] = frame.header("destination")
+	}
+	assert.Equal(t, "/queue/OpenNMS.Apex.RPC.Echo", destinations["Echo"])
+
+	// Sink API
+	assert.NilError(t, cli.Send(&ipc.SinkMessage{MessageId: "001", SystemId: config.ID, ModuleId: "Heartbeat", Content: []byte("<minion/>")}))
+	frame := server.waitFor(t, server.messages)
+	assert.Equal(t, "/queue/OpenNMS.Sink.Heartbeat", frame.header("destination"))
+	assert.Equal(t, "<minion/>", string(frame.body))
+
+	// RPC API
+	server.conn.writeFrame(&stompFrame{
+		command: "MESSAGE",
+		headers: map[string]string{
+			"subscription":   "Echo",
+			"destination":    destinations["Echo"],
+			"message-id":     "ID:1",
+			"correlation-id": "rpc-001",
+			"reply-to":       "/temp-queue/ID:reply",
+		},
+		body: []byte(`<echo-request id="1" message="hello"/>`),
+	})
+	frame = server.waitFor(t, server.messages)
+	assert.Equal(t, "/temp-queue/ID:reply", frame.header("destination"))
+	assert.Equal(t, "rpc-001", frame.header("correlation-id"))
+	response := &api.EchoResponse{}
+	assert.NilError(t, xml.Unmarshal(frame.body, response))
+	assert.Equal(t, "hello", response.Message)
+}
+
+func TestActiveMQLiveness(t *testing.T) {
+	server := startMockStompServer(t, "")
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		Location:   "Apex",
+		BrokerURL:  server.listener.Addr().String(),
+		BrokerType: "activemq",
+		BrokerProperties: map[string]string{
+			"liveness-timeout":        "200ms",
+			"reconnect-initial-delay": "10ms",
+			"reconnect-max-delay":     "50ms",
+		},
+	}
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	cli := &ActiveMQClient{config: config, registry: registry, metrics: api.NewMetrics()}
+	assert.NilError(t, cli.Start())
+	defer cli.Stop()
+	server.waitFor(t, server.connected)
+	time.Sleep(300 * time.Millisecond)
+	assert.NilError(t, cli.CheckLiveness())
+
+	// The consumer loop cannot reconnect after losing the connection
+	server.listener.Close()
+	server.conn.conn.Close()
+	time.Sleep(300 * time.Millisecond)
+	assert.ErrorContains(t, cli.CheckLiveness(), "RPC consumer loop has not been running")
+}
+
+func TestActiveMQHeartbeats(t *testing.T) {
+	server := startMockStompServer(t, "50,50")
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		Location:   "Apex",
+		BrokerURL:  server.listener.Addr().String(),
+		BrokerType: "activemq",
+		BrokerProperties: map[string]string{
+			"heartbeat-interval":      "20ms",
+			"reconnect-initial-delay": "10ms",
+		},
+	}
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	cli := &ActiveMQClient{config: config, registry: registry, metrics: api.NewMetrics()}
+	assert.NilError(t, cli.Start())
+	defer cli.Stop()
+	connect := server.waitFor(t, server.connected)
+	assert.Equal(t, "20,20", connect.header("heart-beat"))
+	assert.Assert(t, cli.GetState().Connected)
+
+	// The server doesn't send the heart-beats it offered, as if the connection was half-open
+	time.Sleep(300 * time.Millisecond)
+	assert.Assert(t, !cli.GetState().Connected)
+}
+
+func TestNegotiateStompHeartbeats(t *testing.T) {
+	send, receive := negotiateStompHeartbeats(10*time.Second, "0,0")
+	assert.Equal(t, time.Duration(0), send)
+	assert.Equal(t, time.Duration(0), receive)
+
+	send, receive = negotiateStompHeartbeats(10*time.Second, "5000,30000")
+	assert.Equal(t, 30*time.Second, send)
+	assert.Equal(t, 10*time.Second, receive)
+
+	send, receive = negotiateStompHeartbeats(10*time.Second, "")
+	assert.Equal(t, time.Duration(0), send)
+	assert.Equal(t, time.Duration(0), receive)
+}
@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+)
+
+// backoffDefaultInitialDelay is the default time to wait before the first reconnection attempt
+const backoffDefaultInitialDelay = 1 * time.Second
+
+// backoffDefaultMaxDelay is the default maximum time to wait between reconnection attempts
+const backoffDefaultMaxDelay = 2 * time.Minute
+
+// backoffConfig represents the settings for reconnecting to the server
+type backoffConfig struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+}
+
+// Gets the reconnection settings from the broker properties.
+func getBackoffConfig(config *api.MinionConfig) (backoffConfig, error) {
+	cfg := backoffConfig{
+		initialDelay: backoffDefaultInitialDelay,
+		maxDelay:     backoffDefaultMaxDelay,
+	}
+	var err error
+	if cfg.initialDelay, err = getDurationProperty(config, "reconnect-initial-delay", cfg.initialDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.maxDelay, err = getDurationProperty(config, "reconnect-max-delay", cfg.maxDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.maxDelay < cfg.initialDelay {
+		return cfg, fmt.Errorf("reconnect-max-delay cannot be less than reconnect-initial-delay")
+	}
+	return cfg, nil
+}
+
+// backoff computes the delay between reconnection attempts, growing exponentially up to a maximum.
+// A random jitter is added, so a group of Minions don't reconnect in lockstep when the server restarts.
+// It is safe for concurrent use.
+type backoff struct {
+	config   backoffConfig
+	attempts int
+	retryAt  time.Time
+	mutex    *sync.Mutex
+}
+
+// Creates a new backoff instance with the given settings.
+func newBackoff(config backoffConfig) *backoff {
+	return &backoff{
+		config: config,
+		mutex:  new(sync.Mutex),
+	}
+}
+
+// Registers a failed attempt, and returns the time to wait before the next one.
+func (b *backoff) next() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delay := b.config.maxDelay
+	if b.attempts < 32 {
+		if d := b.config.initialDelay << b.attempts; d > 0 && d < delay {
+			delay = d
+		}
+	}
+	b.attempts++
+	// Equal jitter: half of the delay is fixed, the other half is random
+	delay = delay/2 + rand.N(delay/2+1)
+	b.retryAt = time.Now().Add(delay)
+	return delay
+}
+
+// Returns true when the time to wait after the last failed attempt has passed.
+func (b *backoff) ready() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return !time.Now().Before(b.retryAt)
+}
+
+// Registers a successful attempt.
+func (b *backoff) reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.attempts = 0
+	b.retryAt = time.Time{}
+}
+
+// Gets a positive duration from the broker properties, or the default value when the property is not set.
+func getDurationProperty(config *api.MinionConfig, name string, defaultValue time.Duration) (time.Duration, error) {
+	value := config.GetBrokerProperty(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,53 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	config := &api.MinionConfig{
+		ID: "minion1",
+		BrokerProperties: map[string]string{
+			"reconnect-initial-delay": "100ms",
+			"reconnect-max-delay":     "1s",
+		},
+	}
+	cfg, err := getBackoffConfig(config)
+	assert.NilError(t, err)
+	b := newBackoff(cfg)
+	assert.Assert(t, b.ready())
+
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for _, max := range expected {
+		max = max * time.Millisecond
+		delay := b.next()
+		assert.Assert(t, delay >= max/2 && delay <= max, "delay %s expected between %s and %s", delay, max/2, max)
+	}
+	assert.Assert(t, !b.ready())
+
+	b.reset()
+	assert.Assert(t, b.ready())
+	delay := b.next()
+	assert.Assert(t, delay <= 100*time.Millisecond)
+}
+
+func TestBackoffInvalidConfig(t *testing.T) {
+	config := &api.MinionConfig{
+		ID: "minion1",
+		BrokerProperties: map[string]string{
+			"reconnect-initial-delay": "10s",
+			"reconnect-max-delay":     "1s",
+		},
+	}
+	_, err := getBackoffConfig(config)
+	assert.ErrorContains(t, err, "cannot be less than")
+
+	config.BrokerProperties["reconnect-max-delay"] = "forever"
+	_, err = getBackoffConfig(config)
+	assert.ErrorContains(t, err, "invalid reconnect-max-delay")
+}
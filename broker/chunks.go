@@ -0,0 +1,187 @@
+package broker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/rpc"
+)
+
+// chunkBufferDefaultTTL is the default time to wait for the missing chunks of a request without expiration time
+const chunkBufferDefaultTTL = 2 * time.Minute
+
+// chunkBufferDefaultMaxSize is the default maximum size in bytes of the partial messages kept in memory
+const chunkBufferDefaultMaxSize = 50 * 1024 * 1024
+
+// chunkBufferEvictionInterval is the time between checks for expired partial messages
+const chunkBufferEvictionInterval = 5 * time.Second
+
+// chunkBufferEntry represents a partial RPC message
+type chunkBufferEntry struct {
+	module     string
+	chunks     map[int32][]byte
+	size       int64
+	expiration time.Time
+}
+
+// chunkBuffer reassembles RPC messages split in chunks by OpenNMS when using Kafka.
+// Partial messages are evicted when they expire, or when the buffer is full, starting with the ones that expire first.
+// It is safe for concurrent use.
+type chunkBuffer struct {
+	config  *api.MinionConfig
+	metrics *api.Metrics
+	ttl     time.Duration
+	maxSize int64
+	size    int64
+	entries map[string]*chunkBufferEntry
+	mutex   *sync.Mutex
+	ticker  *time.Ticker
+}
+
+// Creates a new chunk buffer based on the broker properties.
+func newChunkBuffer(config *api.MinionConfig, metrics *api.Metrics) (*chunkBuffer, error) {
+	buffer := &chunkBuffer{
+		config:  config,
+		metrics: metrics,
+		ttl:     chunkBufferDefaultTTL,
+		maxSize: chunkBufferDefaultMaxSize,
+		entries: make(map[string]*chunkBufferEntry),
+		mutex:   new(sync.Mutex),
+	}
+	var err error
+	if buffer.ttl, err = getDurationProperty(config, "rpc-chunk-ttl", chunkBufferDefaultTTL); err != nil {
+		return nil, err
+	}
+	maxSize, err := getPositiveIntProperty(config, "rpc-chunk-buffer-max-size", chunkBufferDefaultMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	buffer.maxSize = int64(maxSize)
+	return buffer, nil
+}
+
+// Starts a goroutine that evicts the expired partial messages.
+func (b *chunkBuffer) start() {
+	b.ticker = time.NewTicker(chunkBufferEvictionInterval)
+	go func(ticker *time.Ticker) {
+		for now := range ticker.C {
+			b.evictExpired(now)
+		}
+	}(b.ticker)
+}
+
+// Stops the eviction goroutine.
+func (b *chunkBuffer) stop() {
+	if b.ticker != nil {
+		b.ticker.Stop()
+	}
+}
+
+// Adds a chunk to the buffer, and returns the content of the message when all its chunks have been received.
+func (b *chunkBuffer) add(msg *rpc.RpcMessageProto) ([]byte, bool) {
+	if msg.TotalChunks <= 1 { // Handle special case chunk == total == 1
+		return msg.RpcContent, true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	entry, ok := b.entries[msg.RpcId]
+	if !ok {
+		entry = &chunkBufferEntry{
+			module:     msg.ModuleId,
+			chunks:     make(map[int32][]byte),
+			expiration: b.getExpiration(msg),
+		}
+		b.entries[msg.RpcId] = entry
+	}
+	if _, ok := entry.chunks[msg.CurrentChunkNumber]; ok {
+		log.Warnf("Chunk %d from %s was already processed, ignoring...", msg.CurrentChunkNumber+1, msg.RpcId)
+		return nil, false
+	}
+	size := int64(len(msg.RpcContent))
+	if !b.makeRoom(msg.RpcId, size) {
+		log.Warnf("RPC message %s doesn't fit on the chunk buffer, discarding it", msg.RpcId)
+		b.remove(msg.RpcId, entry)
+		b.metrics.RPCChunksEvicted.WithLabelValues(b.config.ID, msg.ModuleId).Inc()
+		b.updateMetrics()
+		return nil, false
+	}
+	entry.chunks[msg.CurrentChunkNumber] = msg.RpcContent
+	entry.size += size
+	b.size += size
+	if int32(len(entry.chunks)) < msg.TotalChunks {
+		b.updateMetrics()
+		return nil, false
+	}
+	data := make([]byte, 0, entry.size)
+	for chunk := int32(0); chunk < msg.TotalChunks; chunk++ {
+		data = append(data, entry.chunks[chunk]...)
+	}
+	b.remove(msg.RpcId, entry)
+	b.updateMetrics()
+	return data, true
+}
+
+// Evicts the partial messages that expired before a given time.
+func (b *chunkBuffer) evictExpired(now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for id, entry := range b.entries {
+		if now.After(entry.expiration) {
+			log.Warnf("Missing chunks for RPC message %s (%d received), discarding it", id, len(entry.chunks))
+			b.remove(id, entry)
+			b.metrics.RPCChunksEvicted.WithLabelValues(b.config.ID, entry.module).Inc()
+		}
+	}
+	b.updateMetrics()
+}
+
+// Evicts partial messages, other than the current one, until there is room for a given size; must be called while holding the lock.
+// Returns false when the size doesn't fit even after evicting all other messages.
+func (b *chunkBuffer) makeRoom(current string, size int64) bool {
+	if b.size+size <= b.maxSize {
+		return true
+	}
+	if b.entries[current].size+size > b.maxSize {
+		return false
+	}
+	ids := make([]string, 0, len(b.entries))
+	for id := range b.entries {
+		if id != current {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return b.entries[ids[i]].expiration.Before(b.entries[ids[j]].expiration) })
+	for _, id := range ids {
+		if b.size+size <= b.maxSize {
+			break
+		}
+		entry := b.entries[id]
+		log.Warnf("Chunk buffer is full, discarding partial RPC message %s", id)
+		b.remove(id, entry)
+		b.metrics.RPCChunksEvicted.WithLabelValues(b.config.ID, entry.module).Inc()
+	}
+	return b.size+size <= b.maxSize
+}
+
+// Removes a partial message from the buffer; must be called while holding the lock.
+func (b *chunkBuffer) remove(id string, entry *chunkBufferEntry) {
+	b.size -= entry.size
+	delete(b.entries, id)
+}
+
+// Updates the buffer metrics; must be called while holding the lock.
+func (b *chunkBuffer) updateMetrics() {
+	b.metrics.RPCChunksIncomplete.WithLabelValues(b.config.ID).Set(float64(len(b.entries)))
+	b.metrics.RPCChunksBytes.WithLabelValues(b.config.ID).Set(float64(b.size))
+}
+
+// Gets the expiration time of a partial message, based on the expiration time of the request when present.
+func (b *chunkBuffer) getExpiration(msg *rpc.RpcMessageProto) time.Time {
+	if msg.ExpirationTime > 0 {
+		return time.UnixMilli(int64(msg.ExpirationTime))
+	}
+	return time.Now().Add(b.ttl)
+}
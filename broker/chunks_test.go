@@ -0,0 +1,77 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/rpc"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestChunkBuffer(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", Location: "Test"}
+	buffer, err := newChunkBuffer(config, api.NewMetrics())
+	assert.NilError(t, err)
+
+	// Single chunk
+	data, ok := buffer.add(&rpc.RpcMessageProto{RpcId: "1", ModuleId: "Echo", TotalChunks: 1, RpcContent: []byte("abc")})
+	assert.Assert(t, ok)
+	assert.Equal(t, "abc", string(data))
+
+	// Multiple chunks, out of order and duplicated
+	_, ok = buffer.add(&rpc.RpcMessageProto{RpcId: "2", ModuleId: "Echo", TotalChunks: 3, CurrentChunkNumber: 1, RpcContent: []byte("def")})
+	assert.Assert(t, !ok)
+	_, ok = buffer.add(&rpc.RpcMessageProto{RpcId: "2", ModuleId: "Echo", TotalChunks: 3, CurrentChunkNumber: 0, RpcContent: []byte("abc")})
+	assert.Assert(t, !ok)
+	_, ok = buffer.add(&rpc.RpcMessageProto{RpcId: "2", ModuleId: "Echo", TotalChunks: 3, CurrentChunkNumber: 0, RpcContent: []byte("abc")})
+	assert.Assert(t, !ok)
+	assert.Equal(t, int64(6), buffer.size)
+	data, ok = buffer.add(&rpc.RpcMessageProto{RpcId: "2", ModuleId: "Echo", TotalChunks: 3, CurrentChunkNumber: 2, RpcContent: []byte("ghi")})
+	assert.Assert(t, ok)
+	assert.Equal(t, "abcdefghi", string(data))
+	assert.Equal(t, 0, len(buffer.entries))
+	assert.Equal(t, int64(0), buffer.size)
+}
+
+func TestChunkBufferEviction(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:       "minion1",
+		Location: "Test",
+		BrokerProperties: map[string]string{
+			"rpc-chunk-ttl":             "1m",
+			"rpc-chunk-buffer-max-size": "10",
+		},
+	}
+	buffer, err := newChunkBuffer(config, api.NewMetrics())
+	assert.NilError(t, err)
+
+	// Expired based on the request expiration time
+	expiration := time.Now().Add(time.Second)
+	buffer.add(&rpc.RpcMessageProto{RpcId: "1", ModuleId: "SNMP", TotalChunks: 2, ExpirationTime: uint64(expiration.UnixMilli()), RpcContent: []byte("abc")})
+	// Expired based on the TTL
+	buffer.add(&rpc.RpcMessageProto{RpcId: "2", ModuleId: "SNMP", TotalChunks: 2, RpcContent: []byte("abc")})
+	assert.Equal(t, 2, len(buffer.entries))
+	buffer.evictExpired(expiration.Add(time.Second))
+	assert.Equal(t, 1, len(buffer.entries))
+	buffer.evictExpired(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, len(buffer.entries))
+	assert.Equal(t, int64(0), buffer.size)
+
+	// The partial messages that expire first are evicted when the buffer is full
+	buffer.add(&rpc.RpcMessageProto{RpcId: "3", ModuleId: "SNMP", TotalChunks: 2, RpcContent: []byte("abcd")})
+	buffer.add(&rpc.RpcMessageProto{RpcId: "4", ModuleId: "SNMP", TotalChunks: 2, RpcContent: []byte("abcd")})
+	buffer.add(&rpc.RpcMessageProto{RpcId: "5", ModuleId: "SNMP", TotalChunks: 2, RpcContent: []byte("abcd")})
+	assert.Equal(t, 2, len(buffer.entries))
+	_, ok := buffer.entries["3"]
+	assert.Assert(t, !ok)
+	assert.Equal(t, int64(8), buffer.size)
+
+	// Messages larger than the buffer are discarded
+	_, ok = buffer.add(&rpc.RpcMessageProto{RpcId: "6", ModuleId: "SNMP", TotalChunks: 2, RpcContent: []byte("abcdefghijk")})
+	assert.Assert(t, !ok)
+	_, ok = buffer.entries["6"]
+	assert.Assert(t, !ok)
+	assert.Equal(t, 2, len(buffer.entries))
+}
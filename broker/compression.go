@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/agalue/gominion/api"
+	"github.com/klauspost/compress/zstd"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// zstdCompressorName is the name of the zstd codec for gRPC
+const zstdCompressorName = "zstd"
+
+func init() {
+	encoding.RegisterCompressor(newZstdCompressor())
+}
+
+// zstdCompressor represents a gRPC compressor based on zstd, as gRPC only provides gzip.
+// The server must register a compressor with the same name.
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCompressor() *zstdCompressor {
+	// Both are safe for concurrent use when compressing and decompressing whole messages
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	return &zstdCompressor{encoder: encoder, decoder: decoder}
+}
+
+// Compress returns a writer that compresses the whole message when closed.
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &zstdWriter{writer: w, encoder: c.encoder}, nil
+}
+
+// Decompress reads the whole message, and returns a reader for its decompressed content.
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(content), nil
+}
+
+func (c *zstdCompressor) Name() string {
+	return zstdCompressorName
+}
+
+// zstdWriter buffers a message, and writes it compressed when closed
+type zstdWriter struct {
+	writer  io.Writer
+	encoder *zstd.Encoder
+	buffer  bytes.Buffer
+}
+
+func (w *zstdWriter) Write(p []byte) (int, error) {
+	return w.buffer.Write(p)
+}
+
+func (w *zstdWriter) Close() error {
+	_, err := w.writer.Write(w.encoder.EncodeAll(w.buffer.Bytes(), nil))
+	return err
+}
+
+// Gets the call options to compress the messages sent through the gRPC streams, based on the broker properties.
+// Returns nil when compression is disabled.
+func getCompressionCallOptions(config *api.MinionConfig) ([]grpc.CallOption, error) {
+	switch name := config.GetBrokerProperty("compression"); name {
+	case "", "none":
+		return nil, nil
+	case gzip.Name, zstdCompressorName:
+		return []grpc.CallOption{grpc.UseCompressor(name)}, nil
+	default:
+		return nil, fmt.Errorf("invalid compression %s, expected none, gzip or zstd", name)
+	}
+}
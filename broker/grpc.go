@@ -2,42 +2,68 @@ package broker
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 	"github.com/agalue/gominion/protobuf/ipc"
+	"github.com/agalue/gominion/protobuf/twin"
 
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 
 	"google.golang.org/grpc"
+	grpc_backoff "google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 )
 
 // GrpcClient represents the gRPC client implementation for the OpenNMS IPC API.
 // This should be equivalent to MinionGrpcClient.java
+//
+// The broker URL can be an ordered list of endpoints separated by commas, for active/standby servers.
+// The streams use the first endpoint whose connection is ready, switching over when it fails, and back when a preferred one returns.
 type GrpcClient struct {
-	config      *api.MinionConfig
-	registry    *api.SinkRegistry
-	conn        *grpc.ClientConn
-	onms        ipc.OpenNMSIpcClient
-	rpcStream   ipc.OpenNMSIpc_RpcStreamingClient
-	sinkStream  ipc.OpenNMSIpc_SinkStreamingClient
-	traceCloser io.Closer
-	metrics     *api.Metrics
-	sinkMutex   *sync.Mutex
-	rpcMutex    *sync.Mutex
+	config        *api.MinionConfig
+	registry      *api.SinkRegistry
+	endpoints     []string
+	conns         []*grpc.ClientConn
+	active        int
+	conn          *grpc.ClientConn
+	twinConn      *grpc.ClientConn
+	twinShared    bool
+	session       context.Context
+	sessionCancel context.CancelFunc
+	twinSession   context.Context
+	connMutex     *sync.RWMutex
+	connEvents    chan struct{}
+	callOptions   []grpc.CallOption
+	twinClient    twin.OpenNMSTwinIpcClient
+	rpcStream     ipc.OpenNMSIpc_RpcStreamingClient
+	sinkStream    ipc.OpenNMSIpc_SinkStreamingClient
+	twinRPCStream twin.OpenNMSTwinIpc_RpcStreamingClient
+	tracker       *twinTracker
+	queue         *sinkQueue
+	pool          *rpcWorkerPool
+	traceCloser   io.Closer
+	metrics       *api.Metrics
+	sinkMutex     *sync.Mutex
+	rpcMutex      *sync.Mutex
+	twinMutex     *sync.Mutex
+	reconnect     backoffConfig
+	sinkBackoff   *backoff
+	shutdown      time.Duration
+	watchdog      *loopWatchdog
+	ctx           context.Context
+	ctxCancel     context.CancelFunc
 }
 
 // Start initializes the gRPC client.
@@ -56,14 +82,46 @@ func (cli *GrpcClient) Start() error {
 
 	cli.sinkMutex = new(sync.Mutex)
 	cli.rpcMutex = new(sync.Mutex)
+	cli.twinMutex = new(sync.Mutex)
+	cli.connMutex = new(sync.RWMutex)
+	cli.connEvents = make(chan struct{}, 1)
+	cli.ctx, cli.ctxCancel = context.WithCancel(context.Background())
+
+	if cli.reconnect, err = getBackoffConfig(cli.config); err != nil {
+		return err
+	}
+	cli.sinkBackoff = newBackoff(cli.reconnect)
+	if cli.shutdown, err = getShutdownTimeout(cli.config); err != nil {
+		return err
+	}
+	if cli.watchdog, err = newLoopWatchdog(cli.config, "RPC consumer loop"); err != nil {
+		return err
+	}
+	if cli.callOptions, err = getCompressionCallOptions(cli.config); err != nil {
+		return err
+	}
 
 	if cli.traceCloser, err = initTracing(cli.config); err != nil {
 		return err
 	}
 
 	options := []grpc.DialOption{
-		grpc.WithBlock(),
 		grpc.WithStreamInterceptor(grpc_zap.StreamClientInterceptor(log.GetLogger())),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: grpc_backoff.Config{
+				BaseDelay:  cli.reconnect.initialDelay,
+				Multiplier: 2,
+				Jitter:     0.5,
+				MaxDelay:   cli.reconnect.maxDelay,
+			},
+			MinConnectTimeout: 20 * time.Second,
+		}),
+	}
+
+	if keepaliveOpt, err := cli.getKeepaliveOption(); err != nil {
+		return err
+	} else if keepaliveOpt != nil {
+		options = append(options, keepaliveOpt)
 	}
 
 	if cli.config.GetBrokerProperty("tls-enabled") == "true" {
@@ -82,55 +140,162 @@ func (cli *GrpcClient) Start() error {
 		options = append(options, grpc.WithStreamInterceptor(grpc_prometheus.StreamClientInterceptor))
 	}
 
-	cli.conn, err = grpc.Dial(cli.config.BrokerURL, options...)
-	if err != nil {
-		return fmt.Errorf("cannot dial gRPC server: %v", err)
+	// The Twin API might be served by a different gRPC server; otherwise, it follows the active endpoint
+	cli.twinShared = true
+	if twinURL := cli.config.GetBrokerProperty("twin-url"); twinURL != "" && twinURL != cli.config.BrokerURL {
+		if cli.twinConn, err = grpc.Dial(twinURL, options...); err != nil {
+			return fmt.Errorf("cannot dial gRPC Twin server: %v", err)
+		}
+		go cli.watchConnection("twin", cli.twinConn)
+		cli.twinShared = false
+		cli.twinClient = twin.NewOpenNMSTwinIpcClient(cli.twinConn)
+		cli.twinSession = cli.ctx
+	}
+
+	cli.endpoints = strings.Split(cli.config.BrokerURL, ",")
+	for _, endpoint := range cli.endpoints {
+		conn, err := grpc.Dial(endpoint, options...)
+		if err != nil {
+			return fmt.Errorf("cannot dial gRPC server %s: %v", endpoint, err)
+		}
+		cli.conns = append(cli.conns, conn)
+		go cli.watchConnection(endpoint, conn)
 	}
-	cli.onms = ipc.NewOpenNMSIpcClient(cli.conn)
+	log.Infof("Waiting for gRPC server at %s", cli.config.BrokerURL)
+	cli.useEndpoint(cli.waitForEndpoint())
 
 	log.Infof("Starting Sink API Stream")
-	if err = cli.initSinkStream(); err != nil {
+	if err = cli.initSinkStream(cli.session); err != nil {
+		return err
+	}
+	if cli.queue, err = newSinkQueue(cli.config, cli.metrics, cli.sendMessage); err != nil {
 		return err
 	}
+	if cli.queue != nil {
+		cli.queue.start()
+	}
+
+	log.Infof("Starting Twin API Streams")
+	cli.tracker = newTwinTracker(cli.config, cli.metrics, cli.sendTwinRequest)
+	if err = cli.initTwinRPCStream(cli.twinSession); err != nil {
+		return err
+	}
+	if err = cli.initTwinSinkStream(cli.twinSession); err != nil {
+		return err
+	}
+	cli.tracker.start()
 
 	if err := cli.registry.StartModules(cli.config, cli); err != nil {
 		return err
 	}
 
+	if cli.pool, err = newRPCWorkerPool(cli.config, cli.metrics); err != nil {
+		return err
+	}
+
 	log.Infof("Starting RPC API Stream")
-	if err = cli.initRPCStream(); err != nil {
+	if err = cli.initRPCStream(cli.session); err != nil {
 		return err
 	}
 
+	go cli.monitorEndpoints()
 	return nil
 }
 
 // Stop finalizes the gRPC client and all its dependencies.
+// In-flight RPC requests and pending Sink messages are drained before closing the streams, up to the shutdown timeout.
 func (cli *GrpcClient) Stop() {
 	cli.registry.StopModules()
 	log.Warnf("Stopping gRPC client")
+	summary := drainClient(cli.shutdown, cli.pool, cli.getSinkDrainers()...)
+	if cli.queue != nil {
+		cli.queue.stop()
+	}
+	if cli.ctxCancel != nil {
+		cli.ctxCancel()
+	}
+	if cli.tracker != nil {
+		cli.tracker.stop()
+	}
 	if cli.rpcStream != nil {
 		cli.rpcStream.CloseSend()
 	}
 	if cli.sinkStream != nil {
 		cli.sinkStream.CloseSend()
 	}
-	if cli.conn != nil {
-		cli.conn.Close()
+	if cli.twinRPCStream != nil {
+		cli.twinRPCStream.CloseSend()
+	}
+	if cli.twinConn != nil && !cli.twinShared {
+		cli.twinConn.Close()
+	}
+	for _, conn := range cli.conns {
+		conn.Close()
 	}
 	if cli.traceCloser != nil {
 		cli.traceCloser.Close()
 	}
+	summary.log()
 	log.Infof("Good bye")
 }
 
+// Gets the pending Sink messages to drain when stopping.
+func (cli *GrpcClient) getSinkDrainers() []sinkDrainer {
+	if cli.queue == nil {
+		return nil
+	}
+	return []sinkDrainer{{pending: cli.queue.count, flush: cli.queue.flush}}
+}
+
+// GetState gets the state of the connection with every endpoint, and with the Twin API server when it is different.
+func (cli *GrpcClient) GetState() api.BrokerState {
+	cli.connMutex.RLock()
+	defer cli.connMutex.RUnlock()
+	state := api.BrokerState{Type: "grpc", Connections: make(map[string]string)}
+	for index, conn := range cli.conns {
+		state.Connections[cli.endpoints[index]] = conn.GetState().String()
+	}
+	if cli.conn != nil {
+		state.Endpoint = cli.endpoints[cli.active]
+		state.Connected = cli.conn.GetState() == connectivity.Ready
+	}
+	if cli.twinConn != nil && !cli.twinShared {
+		state.Connections["twin"] = cli.twinConn.GetState().String()
+	}
+	return state
+}
+
+// CheckLiveness returns an error when the RPC API stream has been down for longer than the liveness timeout.
+func (cli *GrpcClient) CheckLiveness() error {
+	return cli.watchdog.check()
+}
+
+// GetMetrics gets the metrics of the gRPC client, shared with the Sink modules.
+func (cli *GrpcClient) GetMetrics() *api.Metrics {
+	return cli.metrics
+}
+
 // Send forwards a Sink API message to the OpenNMS gRPC server.
+// When the persistent queue is enabled, messages that cannot be delivered are stored until the server is available.
+func (cli *GrpcClient) Send(msg *ipc.SinkMessage) error {
+	if cli.queue != nil {
+		return cli.queue.Send(msg)
+	}
+	return cli.sendMessage(msg)
+}
+
+// Forwards a Sink API message to the OpenNMS gRPC server.
 // Attempts to restart the client when the stream is unavailable or the connection is not ready.
 // Messages are discarded when the server is unavailable.
-func (cli *GrpcClient) Send(msg *ipc.SinkMessage) error {
-	if cli.sinkStream == nil || cli.conn.GetState() != connectivity.Ready {
-		// Try to restart the Sink stream
-		if err := cli.initSinkStream(); err != nil {
+func (cli *GrpcClient) sendMessage(msg *ipc.SinkMessage) error {
+	conn, session := cli.getConnection()
+	if cli.getSinkStream() == nil || conn.GetState() != connectivity.Ready {
+		// Try to restart the Sink stream, waiting between attempts to avoid overloading the server
+		if !cli.sinkBackoff.ready() {
+			return fmt.Errorf("server unreachable")
+		}
+		if err := cli.initSinkStream(session); err != nil {
+			cli.sinkBackoff.next()
 			return err
 		}
 		log.Warnf("Sink API stream restarted")
@@ -141,9 +306,11 @@ func (cli *GrpcClient) Send(msg *ipc.SinkMessage) error {
 	err := cli.sinkStream.Send(msg)
 	cli.sinkMutex.Unlock()
 	if err == nil {
+		cli.sinkBackoff.reset()
 		cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
 		return nil
 	}
+	cli.sinkBackoff.next()
 	if err == io.EOF {
 		err = fmt.Errorf("server unreachable")
 	}
@@ -153,18 +320,29 @@ func (cli *GrpcClient) Send(msg *ipc.SinkMessage) error {
 	return err
 }
 
-// Initializes the Sink API stream
-func (cli *GrpcClient) initSinkStream() error {
+// Gets the Sink API stream.
+func (cli *GrpcClient) getSinkStream() ipc.OpenNMSIpc_SinkStreamingClient {
+	cli.sinkMutex.Lock()
+	defer cli.sinkMutex.Unlock()
+	return cli.sinkStream
+}
+
+// Initializes the Sink API stream for a given session of the active endpoint.
+func (cli *GrpcClient) initSinkStream(session context.Context) error {
 	var err error
 
 	cli.sinkMutex.Lock()
 	defer cli.sinkMutex.Unlock()
 
+	if session.Err() != nil {
+		return fmt.Errorf("cannot initialize Sink API Stream: the gRPC endpoint has changed")
+	}
 	if cli.sinkStream != nil {
 		cli.sinkStream.CloseSend()
 	}
 
-	cli.sinkStream, err = cli.onms.SinkStreaming(context.Background())
+	conn, _ := cli.getConnection()
+	cli.sinkStream, err = ipc.NewOpenNMSIpcClient(conn).SinkStreaming(session, cli.callOptions...)
 	if err != nil {
 		return fmt.Errorf("cannot initialize Sink API Stream: %v", err)
 	}
@@ -172,98 +350,340 @@ func (cli *GrpcClient) initSinkStream() error {
 	return nil
 }
 
-// Initializes the RPC API stream
-func (cli *GrpcClient) initRPCStream() error {
+// Initializes the RPC API stream for a given session of the active endpoint.
+func (cli *GrpcClient) initRPCStream(session context.Context) error {
 	var err error
 
 	cli.rpcMutex.Lock()
 	defer cli.rpcMutex.Unlock()
 
+	if session.Err() != nil {
+		return fmt.Errorf("cannot initialize RPC API Stream: the gRPC endpoint has changed")
+	}
 	if cli.rpcStream != nil {
 		cli.rpcStream.CloseSend()
 	}
 
-	cli.rpcStream, err = cli.onms.RpcStreaming(context.Background())
+	conn, _ := cli.getConnection()
+	cli.rpcStream, err = ipc.NewOpenNMSIpcClient(conn).RpcStreaming(session, cli.callOptions...)
 	if err != nil {
 		return fmt.Errorf("cannot initialize RPC API Stream: %v", err)
 	}
 
 	// Goroutine to handle RPC API requests from the gRPC server.
-	go func() {
-		cli.sendMinionHeaders()
+	go func(stream ipc.OpenNMSIpc_RpcStreamingClient) {
+		cli.watchdog.started()
+		defer cli.watchdog.stopped()
+		cli.sendMinionHeaders(stream)
 		for {
-			if cli.rpcStream == nil || cli.conn.GetState() != connectivity.Ready {
+			if conn.GetState() != connectivity.Ready {
 				break
 			}
-			if request, err := cli.rpcStream.Recv(); err == nil {
+			if request, err := stream.Recv(); err == nil {
 				cli.processRequest(request)
 				cli.metrics.RPCReqReceivedSucceeded.WithLabelValues(request.SystemId, request.ModuleId).Inc()
 			} else {
-				if err == io.EOF {
+				if err == io.EOF || session.Err() != nil {
 					break
 				}
 				if errStatus, _ := status.FromError(err); errStatus.Code() != codes.Unavailable {
 					log.Errorf("Cannot receive RPC Request: %v", err)
 				}
-				cli.metrics.RPCReqReceivedFailed.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+				cli.metrics.RPCReqReceivedFailed.WithLabelValues(request.GetSystemId(), request.GetModuleId()).Inc()
+				break // The stream is terminated after any error
 			}
 		}
 		log.Warnf("Terminating RPC API handler")
-	}()
+	}(cli.rpcStream)
 
 	// Detects the termination of the stream and try to restart it until success
-	go func() {
-		<-cli.rpcStream.Context().Done()
-		for {
-			if err := cli.initRPCStream(); err == nil {
-				log.Warnf("RPC API stream restarted")
-				return
-			}
-			time.Sleep(1 * time.Second)
+	go func(stream ipc.OpenNMSIpc_RpcStreamingClient) {
+		<-stream.Context().Done()
+		cli.restartStream("RPC API", session, cli.initRPCStream)
+	}(cli.rpcStream)
+
+	return nil
+}
+
+// Tries to restart a stream for a given session until success.
+// Gives up when the session ends, either because the client stops or because the active endpoint changes, as the streams are initialized again after switching.
+func (cli *GrpcClient) restartStream(name string, session context.Context, init func(session context.Context) error) {
+	retry := newBackoff(cli.reconnect)
+	for session.Err() == nil {
+		time.Sleep(retry.next())
+		if err := init(session); err == nil {
+			log.Warnf("%s stream restarted", name)
+			return
 		}
-	}()
+	}
+}
+
+// Subscribes to a Twin object by its key.
+func (cli *GrpcClient) Subscribe(key string, handler api.TwinHandler) error {
+	if cli.tracker == nil {
+		return fmt.Errorf("gRPC client not started")
+	}
+	return cli.tracker.subscribe(key, handler)
+}
+
+// Unsubscribe removes all the handlers for a given Twin object key.
+func (cli *GrpcClient) Unsubscribe(key string) {
+	if cli.tracker != nil {
+		cli.tracker.unsubscribe(key)
+	}
+}
+
+// Initializes the Twin API RPC stream for a given session, used to request objects and receive the responses.
+// All the subscribed objects are requested again every time the stream is created.
+func (cli *GrpcClient) initTwinRPCStream(session context.Context) error {
+	var err error
+
+	cli.twinMutex.Lock()
+	defer cli.twinMutex.Unlock()
 
+	if session.Err() != nil {
+		return fmt.Errorf("cannot initialize Twin RPC API Stream: the gRPC endpoint has changed")
+	}
+	if cli.twinRPCStream != nil {
+		cli.twinRPCStream.CloseSend()
+	}
+
+	cli.twinRPCStream, err = cli.getTwinClient().RpcStreaming(session)
+	if err != nil {
+		return fmt.Errorf("cannot initialize Twin RPC API Stream: %v", err)
+	}
+
+	go cli.tracker.requestAll()
+	go cli.handleTwinStream("RPC", session, cli.twinRPCStream, cli.initTwinRPCStream)
 	return nil
 }
 
-// Gets the TLS transport credentials from a file or a string.
-func (cli *GrpcClient) getTransportCredentials() (credentials.TransportCredentials, error) {
-	cfg := &tls.Config{}
-
-	if srvCertPath := cli.config.GetBrokerProperty("ca-cert-path"); srvCertPath != "" {
-		log.Infof("Loading CA certificate")
-		certPool := x509.NewCertPool()
-		if certificate, err := os.ReadFile(srvCertPath); err == nil {
-			if ok := certPool.AppendCertsFromPEM(certificate); !ok {
-				return nil, fmt.Errorf("failed to append certs")
-			}
+// Initializes the Twin API Sink stream for a given session, used to receive object updates.
+func (cli *GrpcClient) initTwinSinkStream(session context.Context) error {
+	header := &twin.MinionHeader{
+		SystemId: cli.config.ID,
+		Location: cli.config.Location,
+	}
+	stream, err := cli.getTwinClient().SinkStreaming(session, header)
+	if err != nil {
+		return fmt.Errorf("cannot initialize Twin Sink API Stream: %v", err)
+	}
+	go cli.handleTwinStream("Sink", session, stream, cli.initTwinSinkStream)
+	return nil
+}
+
+// Gets the Twin API client, which follows the active endpoint unless the Twin API is served by a different server.
+func (cli *GrpcClient) getTwinClient() twin.OpenNMSTwinIpcClient {
+	cli.connMutex.RLock()
+	defer cli.connMutex.RUnlock()
+	return cli.twinClient
+}
+
+// Handles Twin API responses from a given stream, and tries to restart the stream until success when it terminates.
+// Gives up when the server doesn't implement the Twin API.
+func (cli *GrpcClient) handleTwinStream(name string, session context.Context, stream grpc.ServerStreamingClient[twin.TwinResponseProto], restart func(session context.Context) error) {
+	for {
+		response, err := stream.Recv()
+		if err == nil {
+			cli.tracker.process(response)
+			continue
+		}
+		code := status.Code(err)
+		if code == codes.Unimplemented {
+			log.Warnf("The gRPC server doesn't support the Twin API")
+			cli.tracker.stop()
+			return
+		}
+		if err != io.EOF && code != codes.Unavailable && code != codes.Canceled {
+			log.Errorf("Cannot receive Twin %s API response: %v", name, err)
+		}
+		break
+	}
+	log.Warnf("Terminating Twin %s API handler", name)
+	cli.restartStream("Twin "+name+" API", session, restart)
+}
+
+// Sends a Twin API request to OpenNMS.
+func (cli *GrpcClient) sendTwinRequest(request *twin.TwinRequestProto) error {
+	cli.twinMutex.Lock()
+	defer cli.twinMutex.Unlock()
+	if cli.twinRPCStream == nil {
+		return fmt.Errorf("twin RPC API stream unavailable")
+	}
+	return cli.twinRPCStream.Send(request)
+}
+
+// Gets the keepalive dial option from the broker properties; returns nil when keepalive pings are disabled.
+// The server must allow pings at the configured frequency, or it will close the connection.
+func (cli *GrpcClient) getKeepaliveOption() (grpc.DialOption, error) {
+	if cli.config.GetBrokerProperty("keepalive-time") == "" {
+		return nil, nil
+	}
+	params := keepalive.ClientParameters{
+		PermitWithoutStream: cli.config.GetBrokerProperty("keepalive-permit-without-stream") == "true",
+	}
+	var err error
+	if params.Time, err = getDurationProperty(cli.config, "keepalive-time", 0); err != nil {
+		return nil, err
+	}
+	if params.Timeout, err = getDurationProperty(cli.config, "keepalive-timeout", 20*time.Second); err != nil {
+		return nil, err
+	}
+	log.Infof("Sending keepalive pings every %s with a timeout of %s", params.Time, params.Timeout)
+	return grpc.WithKeepaliveParams(params), nil
+}
+
+// Tracks the state of a given gRPC connection, logging the transitions and exposing it as a metric, until the client stops.
+// Every transition is notified to the goroutine that chooses the active endpoint.
+func (cli *GrpcClient) watchConnection(name string, conn *grpc.ClientConn) {
+	state := conn.GetState()
+	for {
+		cli.metrics.GRPCConnectionState.WithLabelValues(cli.config.ID, name).Set(float64(state))
+		if !conn.WaitForStateChange(cli.ctx, state) {
+			return
+		}
+		select {
+		case cli.connEvents <- struct{}{}:
+		default:
+		}
+		previous := state
+		state = conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			log.Warnf("The %s gRPC connection changed from %s to %s", name, previous, state)
 		} else {
-			return nil, err
+			log.Infof("The %s gRPC connection changed from %s to %s", name, previous, state)
 		}
-		cfg.RootCAs = certPool
 	}
+}
 
-	cliCertPath := cli.config.GetBrokerProperty("client-cert-path")
-	cliKeyPath := cli.config.GetBrokerProperty("client-key-path")
-	if cliCertPath != "" && cliKeyPath != "" {
-		log.Infof("Loading Client certificate for mTLS")
-		certificate, err := tls.LoadX509KeyPair(cliCertPath, cliKeyPath)
-		if err != nil {
-			return nil, err
+// Gets the connection with the active endpoint, and the session shared by its streams.
+func (cli *GrpcClient) getConnection() (*grpc.ClientConn, context.Context) {
+	cli.connMutex.RLock()
+	defer cli.connMutex.RUnlock()
+	return cli.conn, cli.session
+}
+
+// Waits until the connection with any of the endpoints is ready, and returns the index of the preferred one.
+// Endpoints are evaluated in order, so a standby is not used while the connection with a preferred one is in progress.
+func (cli *GrpcClient) waitForEndpoint() int {
+	for {
+		for index, conn := range cli.conns {
+			state := conn.GetState()
+			if state == connectivity.Ready {
+				return index
+			}
+			if state == connectivity.Idle {
+				conn.Connect()
+			}
+			if state != connectivity.TransientFailure {
+				break
+			}
+		}
+		<-cli.connEvents
+	}
+}
+
+// Gets the index of the first endpoint whose connection is ready, or -1 when none is ready.
+// Idle connections are asked to reconnect, so a preferred endpoint is detected when it returns.
+func (cli *GrpcClient) getPreferredEndpoint() int {
+	preferred := -1
+	for index, conn := range cli.conns {
+		state := conn.GetState()
+		if state == connectivity.Idle {
+			conn.Connect()
+		}
+		if state == connectivity.Ready && preferred < 0 {
+			preferred = index
+		}
+	}
+	return preferred
+}
+
+// Switches to the preferred endpoint every time the state of a connection changes, until the client stops.
+func (cli *GrpcClient) monitorEndpoints() {
+	for {
+		select {
+		case <-cli.ctx.Done():
+			return
+		case <-cli.connEvents:
+		}
+		cli.connMutex.RLock()
+		active := cli.active
+		cli.connMutex.RUnlock()
+		if index := cli.getPreferredEndpoint(); index >= 0 && index != active {
+			log.Warnf("Switching gRPC endpoint from %s to %s", cli.endpoints[active], cli.endpoints[index])
+			cli.switchEndpoint(index)
+		}
+	}
+}
+
+// Makes a given endpoint the active one; the streams of the previous endpoint are terminated by cancelling its session.
+func (cli *GrpcClient) useEndpoint(index int) {
+	cli.connMutex.Lock()
+	defer cli.connMutex.Unlock()
+	if cli.sessionCancel != nil {
+		cli.sessionCancel()
+	}
+	cli.active = index
+	cli.conn = cli.conns[index]
+	cli.session, cli.sessionCancel = context.WithCancel(cli.ctx)
+	if cli.twinShared {
+		cli.twinConn = cli.conn
+		cli.twinClient = twin.NewOpenNMSTwinIpcClient(cli.conn)
+		cli.twinSession = cli.session
+	}
+	log.Infof("Using gRPC server at %s", cli.endpoints[index])
+	for i, endpoint := range cli.endpoints {
+		value := 0.0
+		if i == index {
+			value = 1
 		}
-		cfg.Certificates = []tls.Certificate{certificate}
+		cli.metrics.GRPCActiveEndpoint.WithLabelValues(cli.config.ID, endpoint).Set(value)
+	}
+}
+
+// Switches to a given endpoint, and initializes the streams on it.
+// The Minion headers are sent again when the RPC API stream is initialized.
+func (cli *GrpcClient) switchEndpoint(index int) {
+	cli.useEndpoint(index)
+	_, session := cli.getConnection()
+	cli.sinkBackoff.reset()
+	if err := cli.initSinkStream(session); err != nil {
+		log.Warnf("%v", err) // Restarted when sending the next message
 	}
+	if err := cli.initRPCStream(session); err != nil {
+		log.Warnf("%v", err)
+		go cli.restartStream("RPC API", session, cli.initRPCStream)
+	}
+	if !cli.twinShared {
+		return
+	}
+	if err := cli.initTwinRPCStream(session); err != nil {
+		log.Warnf("%v", err)
+		go cli.restartStream("Twin RPC API", session, cli.initTwinRPCStream)
+	}
+	if err := cli.initTwinSinkStream(session); err != nil {
+		log.Warnf("%v", err)
+		go cli.restartStream("Twin Sink API", session, cli.initTwinSinkStream)
+	}
+}
 
+// Gets the TLS transport credentials from a file or a string.
+func (cli *GrpcClient) getTransportCredentials() (credentials.TransportCredentials, error) {
+	cfg, err := getTLSConfig(cli.config)
+	if err != nil {
+		return nil, err
+	}
 	return credentials.NewTLS(cfg), nil
 }
 
-// Sends the Minion headers as an RPC API response, to register the Minion as a client.
-// Executes this every time the RPC API Stream is created.
-func (cli *GrpcClient) sendMinionHeaders() {
+// Sends the Minion headers as an RPC API response through a given stream, to register the Minion as a client.
+// Executes this every time the RPC API Stream is created, including after switching endpoints.
+func (cli *GrpcClient) sendMinionHeaders(stream ipc.OpenNMSIpc_RpcStreamingClient) {
 	headers := cli.config.GetHeaderResponse()
 	log.Infof("Sending Minion Headers from SystemId %s to gRPC server", cli.config.ID)
 	cli.rpcMutex.Lock()
-	if err := cli.rpcStream.Send(headers); err != nil {
+	if err := stream.Send(headers); err != nil {
 		log.Errorf("Cannot send RPC headers: %v", err)
 	}
 	cli.rpcMutex.Unlock()
@@ -273,22 +693,7 @@ func (cli *GrpcClient) sendMinionHeaders() {
 func (cli *GrpcClient) processRequest(request *ipc.RpcRequestProto) {
 	log.Debugf("Received RPC request with ID %s for module %s at location %s", request.RpcId, request.ModuleId, request.Location)
 	if module, ok := api.GetRPCModule(request.ModuleId); ok {
-		go func() {
-			trace := startSpanFromRPCMessage(request)
-			var err error
-			if response := module.Execute(request); response != nil {
-				cli.metrics.RPCReqProcessedSucceeded.WithLabelValues(request.SystemId, request.ModuleId).Inc()
-				err = cli.sendResponse(response)
-			} else {
-				cli.metrics.RPCReqProcessedFailed.WithLabelValues(request.SystemId, request.ModuleId).Inc()
-				err = fmt.Errorf("module %s returned an empty response for request %s, ignoring", request.ModuleId, request.RpcId)
-			}
-			if err != nil {
-				trace.SetTag("failed", "true")
-				trace.LogKV("event", err.Error())
-			}
-			trace.Finish()
-		}()
+		submitRPCRequest(cli.pool, cli.metrics, module, request, cli.sendResponse)
 	} else {
 		log.Errorf("Cannot find implementation for module %s, ignoring request with ID %s", request.ModuleId, request.RpcId)
 	}
@@ -296,9 +701,13 @@ func (cli *GrpcClient) processRequest(request *ipc.RpcRequestProto) {
 
 // Sends an RPC API response to OpenNMS
 func (cli *GrpcClient) sendResponse(response *ipc.RpcResponseProto) error {
-	if cli.rpcStream != nil && cli.conn.GetState() == connectivity.Ready {
+	conn, _ := cli.getConnection()
+	cli.rpcMutex.Lock()
+	stream := cli.rpcStream
+	cli.rpcMutex.Unlock()
+	if stream != nil && conn.GetState() == connectivity.Ready {
 		cli.rpcMutex.Lock()
-		err := cli.rpcStream.Send(response)
+		err := stream.Send(response)
 		cli.rpcMutex.Unlock()
 		if err == nil {
 			cli.metrics.RPCResSentSucceeded.WithLabelValues(response.SystemId, response.ModuleId).Inc()
@@ -0,0 +1,189 @@
+package broker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/ipc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+	"gotest.tools/v3/assert"
+)
+
+// mockIpcServer represents an OpenNMS gRPC server that records the Minion headers and the Sink messages it receives
+type mockIpcServer struct {
+	ipc.UnimplementedOpenNMSIpcServer
+	name     string
+	headers  chan string
+	messages chan string
+}
+
+func (srv *mockIpcServer) RpcStreaming(stream ipc.OpenNMSIpc_RpcStreamingServer) error {
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if response.ModuleId == "MINION_HEADERS" {
+			srv.headers <- srv.name
+		}
+	}
+}
+
+func (srv *mockIpcServer) SinkStreaming(stream ipc.OpenNMSIpc_SinkStreamingServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		srv.messages <- srv.name + ":" + msg.MessageId
+	}
+}
+
+// Starts a mock server on a given address, and returns the server with its actual address.
+func startMockIpcServer(t *testing.T, address string, srv *mockIpcServer) (*grpc.Server, string) {
+	listener, err := net.Listen("tcp", address)
+	assert.NilError(t, err)
+	server := grpc.NewServer()
+	ipc.RegisterOpenNMSIpcServer(server, srv)
+	go server.Serve(listener)
+	return server, listener.Addr().String()
+}
+
+func waitForValue(t *testing.T, values chan string) string {
+	select {
+	case value := <-values:
+		return value
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the gRPC server")
+	}
+	return ""
+}
+
+func TestGrpcClientFailover(t *testing.T) {
+	log.InitLogger("debug")
+	headers := make(chan string, 10)
+	messages := make(chan string, 10)
+	primary := &mockIpcServer{name: "primary", headers: headers, messages: messages}
+	standby := &mockIpcServer{name: "standby", headers: headers, messages: messages}
+	primaryServer, primaryURL := startMockIpcServer(t, "127.0.0.1:0", primary)
+	standbyServer, standbyURL := startMockIpcServer(t, "127.0.0.1:0", standby)
+	defer standbyServer.Stop()
+
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		Location:   "Test",
+		BrokerURL:  primaryURL + "," + standbyURL,
+		BrokerType: "grpc",
+		BrokerProperties: map[string]string{
+			"reconnect-initial-delay": "100ms",
+			"reconnect-max-delay":     "500ms",
+			"shutdown-timeout":        "1s",
+		},
+	}
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	metrics := api.NewMetrics()
+	cli := &GrpcClient{config: config, registry: registry, metrics: metrics}
+	assert.NilError(t, cli.Start())
+	defer cli.Stop()
+
+	// The primary server is preferred
+	assert.Equal(t, "primary", waitForValue(t, headers))
+	assert.NilError(t, cli.Send(&ipc.SinkMessage{MessageId: "1", SystemId: config.ID, ModuleId: "Heartbeat"}))
+	assert.Equal(t, "primary:1", waitForValue(t, messages))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCActiveEndpoint.WithLabelValues(config.ID, primaryURL)))
+
+	// Fail over to the standby server, sending the headers again
+	primaryServer.Stop()
+	assert.Equal(t, "standby", waitForValue(t, headers))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCActiveEndpoint.WithLabelValues(config.ID, standbyURL)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.GRPCActiveEndpoint.WithLabelValues(config.ID, primaryURL)))
+	assert.NilError(t, cli.Send(&ipc.SinkMessage{MessageId: "2", SystemId: config.ID, ModuleId: "Heartbeat"}))
+	assert.Equal(t, "standby:2", waitForValue(t, messages))
+
+	// Fail back to the primary server when it returns
+	primaryServer, _ = startMockIpcServer(t, primaryURL, primary)
+	defer primaryServer.Stop()
+	assert.Equal(t, "primary", waitForValue(t, headers))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCActiveEndpoint.WithLabelValues(config.ID, primaryURL)))
+	assert.NilError(t, cli.Send(&ipc.SinkMessage{MessageId: "3", SystemId: config.ID, ModuleId: "Heartbeat"}))
+	assert.Equal(t, "primary:3", waitForValue(t, messages))
+}
+
+// compressionStatsHandler records the compression used by every stream received by a server
+type compressionStatsHandler struct {
+	encodings chan string
+}
+
+func (h *compressionStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		h.encodings <- header.FullMethod + ":" + header.Compression
+	}
+}
+
+func (h *compressionStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+func TestGrpcClientCompression(t *testing.T) {
+	log.InitLogger("debug")
+	for _, compression := range []string{"gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			encodings := make(chan string, 10)
+			messages := make(chan string, 10)
+			srv := &mockIpcServer{name: "server", headers: make(chan string, 10), messages: messages}
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NilError(t, err)
+			server := grpc.NewServer(grpc.StatsHandler(&compressionStatsHandler{encodings: encodings}))
+			ipc.RegisterOpenNMSIpcServer(server, srv)
+			go server.Serve(listener)
+			defer server.Stop()
+
+			config := &api.MinionConfig{
+				ID:               "minion1",
+				Location:         "Test",
+				BrokerURL:        listener.Addr().String(),
+				BrokerType:       "grpc",
+				BrokerProperties: map[string]string{"compression": compression, "shutdown-timeout": "1s"},
+			}
+			registry := new(api.SinkRegistry)
+			registry.Init()
+			cli := &GrpcClient{config: config, registry: registry, metrics: api.NewMetrics()}
+			assert.NilError(t, cli.Start())
+			defer cli.Stop()
+
+			assert.NilError(t, cli.Send(&ipc.SinkMessage{MessageId: "1", SystemId: config.ID, ModuleId: "Heartbeat", Content: []byte("<minion/>")}))
+			assert.Equal(t, "server:1", waitForValue(t, messages))
+			// The Twin API stream is not compressed
+			received := map[string]string{}
+			for len(received) < 2 {
+				method, encoding, _ := strings.Cut(waitForValue(t, encodings), ":")
+				if strings.HasPrefix(method, "/OpenNMSIpc/") {
+					received[method] = encoding
+				}
+			}
+			assert.Equal(t, compression, received["/OpenNMSIpc/SinkStreaming"])
+			assert.Equal(t, compression, received["/OpenNMSIpc/RpcStreaming"])
+		})
+	}
+}
+
+func TestGrpcClientInvalidCompression(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", BrokerProperties: map[string]string{"compression": "lz4"}}
+	_, err := getCompressionCallOptions(config)
+	assert.ErrorContains(t, err, "invalid compression lz4")
+}
@@ -2,12 +2,14 @@ package broker
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
-	"net"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/agalue/gominion/api"
@@ -15,24 +17,34 @@ import (
 	"github.com/agalue/gominion/protobuf/ipc"
 	"github.com/agalue/gominion/protobuf/rpc"
 	"github.com/agalue/gominion/protobuf/sink"
-	"github.com/google/uuid"
+	"github.com/agalue/gominion/protobuf/twin"
 	"github.com/twmb/franz-go/pkg/kgo"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// kafkaPingTimeout is the maximum time to wait for the Kafka cluster when reporting the state of the client
+const kafkaPingTimeout = 2 * time.Second
+
 // KafkaClient represents the Kafka client implementation for the OpenNMS IPC API.
 type KafkaClient struct {
 	config        *api.MinionConfig
 	registry      *api.SinkRegistry
 	consumer      *kgo.Client
 	producer      *kgo.Client
+	twinConsumer  *kgo.Client
+	tracker       *twinTracker
+	queue         *sinkQueue
+	pool          *rpcWorkerPool
 	traceCloser   io.Closer
 	metrics       *api.Metrics
 	maxBufferSize int
+	asyncSink     bool
+	singleTopic   bool
 	instanceID    string
-	msgBuffer     map[string][]byte
-	chunkTracker  map[string]int32
+	chunks        *chunkBuffer
+	shutdown      time.Duration
+	watchdog      *loopWatchdog
 	ctx           context.Context
 	ctxCancel     context.CancelFunc
 }
@@ -51,8 +63,9 @@ func (cli *KafkaClient) Start() error {
 		return fmt.Errorf("prometheus Metrics required")
 	}
 
-	cli.msgBuffer = make(map[string][]byte)
-	cli.chunkTracker = make(map[string]int32)
+	if cli.chunks, err = newChunkBuffer(cli.config, cli.metrics); err != nil {
+		return err
+	}
 
 	// Maximum size of the buffer to split messages in chunks
 	cli.maxBufferSize, err = strconv.Atoi(cli.config.GetBrokerProperty("max-buffer-size"))
@@ -66,6 +79,16 @@ func (cli *KafkaClient) Start() error {
 		cli.instanceID = "OpenNMS"
 	}
 
+	if cli.pool, err = newRPCWorkerPool(cli.config, cli.metrics); err != nil {
+		return err
+	}
+	if cli.shutdown, err = getShutdownTimeout(cli.config); err != nil {
+		return err
+	}
+	if cli.watchdog, err = newLoopWatchdog(cli.config, "RPC consumer loop"); err != nil {
+		return err
+	}
+
 	if cli.traceCloser, err = initTracing(cli.config); err != nil {
 		return err
 	}
@@ -73,44 +96,87 @@ func (cli *KafkaClient) Start() error {
 	// Creating Context for Kafka Clients
 	cli.ctx, cli.ctxCancel = context.WithCancel(context.Background())
 
+	// Security options (TLS and SASL) shared by all Kafka Clients
+	securityOpts, err := cli.getSecurityOptions()
+	if err != nil {
+		return err
+	}
+
 	// Creating Kafka Producer Client
 	producerCfg := []kgo.Opt{
 		kgo.SeedBrokers(strings.Split(cli.config.BrokerURL, ",")...),
 	}
-	if cli.config.GetBrokerProperty("tls-enabled") == "true" {
-		producerCfg = append(producerCfg, cli.enableTLS())
+	producerCfg = append(producerCfg, securityOpts...)
+	producerOpts, err := cli.getProducerOptions()
+	if err != nil {
+		return err
+	}
+	producerCfg = append(producerCfg, producerOpts...)
+	if cli.asyncSink, err = isAsyncSinkProducer(cli.config); err != nil {
+		return err
 	}
 	if cli.producer, err = kgo.NewClient(producerCfg...); err != nil {
 		return fmt.Errorf("could not create producer: %v", err)
 	}
 
 	// Creating Kafka Consumer Client
-	topic := fmt.Sprintf("%s.%s.rpc-request", cli.instanceID, cli.config.Location)
+	cli.singleTopic = cli.config.GetBrokerProperty("single-topic") != "false"
 	consumerCfg := []kgo.Opt{
 		kgo.SeedBrokers(strings.Split(cli.config.BrokerURL, ",")...),
 		kgo.ConsumerGroup(cli.config.Location),
-		kgo.ConsumeTopics(topic),
-	}
-	if cli.config.GetBrokerProperty("tls-enabled") == "true" {
-		consumerCfg = append(consumerCfg, cli.enableTLS())
+		kgo.ConsumeTopics(cli.getRPCRequestTopics()...),
 	}
+	consumerCfg = append(consumerCfg, securityOpts...)
 	if cli.consumer, err = kgo.NewClient(consumerCfg...); err != nil {
 		return fmt.Errorf("could not create consumer: %v", err)
 	}
 
+	// Creating Kafka Consumer Client for the Twin API
+	// Every Minion must receive all the updates, so no consumer group is used
+	twinCfg := []kgo.Opt{
+		kgo.SeedBrokers(strings.Split(cli.config.BrokerURL, ",")...),
+		kgo.ConsumeTopics(cli.getTwinResponseTopics()...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()),
+	}
+	twinCfg = append(twinCfg, securityOpts...)
+	if cli.twinConsumer, err = kgo.NewClient(twinCfg...); err != nil {
+		return fmt.Errorf("could not create twin consumer: %v", err)
+	}
+	cli.tracker = newTwinTracker(cli.config, cli.metrics, cli.sendTwinRequest)
+	cli.startTwinConsumer()
+
+	// Starting the persistent Sink queue
+	if cli.queue, err = newSinkQueue(cli.config, cli.metrics, cli.sendMessage); err != nil {
+		return err
+	}
+	if cli.queue != nil {
+		cli.queue.start()
+	}
+
 	// Starting Sink Modules
 	if err := cli.registry.StartModules(cli.config, cli); err != nil {
 		return err
 	}
 
+	cli.chunks.start()
 	go func() {
 		log.Infof("starting RPC consumer for location %s", cli.config.Location)
+		cli.watchdog.started()
+		defer cli.watchdog.stopped()
 		for {
-			fetches := cli.consumer.PollFetches(cli.ctx)
-			if fetches.IsClientClosed() {
-				continue
+			// Polls with a timeout, so the watchdog receives a heartbeat even when there are no requests
+			cli.watchdog.beat()
+			ctx, cancel := context.WithTimeout(cli.ctx, cli.watchdog.interval())
+			fetches := cli.consumer.PollFetches(ctx)
+			cancel()
+			if fetches.IsClientClosed() || cli.ctx.Err() != nil {
+				log.Warnf("Terminating RPC consumer")
+				return
 			}
-			if errs := fetches.Errors(); len(errs) > 0 {
+			errs := slices.DeleteFunc(fetches.Errors(), func(fetchErr kgo.FetchError) bool {
+				return errors.Is(fetchErr.Err, context.DeadlineExceeded)
+			})
+			if len(errs) > 0 {
 				log.Errorf("kafka consumer errors: %v", errs)
 				continue
 			}
@@ -130,44 +196,130 @@ func (cli *KafkaClient) Start() error {
 	return nil
 }
 
-func (cli *KafkaClient) enableTLS() kgo.Opt {
-	// Uses SystemCertPool for RootCAs by default.
-	dialer := &tls.Dialer{
-		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
-		Config:    &tls.Config{InsecureSkipVerify: true},
+// Gets the TLS and SASL options from the broker properties.
+// The CA certificate is used to verify the brokers; the system CA pool is used when it is not provided.
+func (cli *KafkaClient) getSecurityOptions() ([]kgo.Opt, error) {
+	opts := make([]kgo.Opt, 0)
+	if cli.config.GetBrokerProperty("tls-enabled") == "true" {
+		log.Infof("Enabling TLS for Kafka")
+		cfg, err := getTLSConfig(cli.config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.DialTLSConfig(cfg))
+	}
+	if mechanism := cli.config.GetBrokerProperty("sasl-mechanism"); mechanism != "" {
+		log.Infof("Enabling SASL %s for Kafka", mechanism)
+		m, err := getSASLMechanism(cli.config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(m))
 	}
-	return kgo.Dialer(dialer.DialContext)
+	return opts, nil
 }
 
 // Stop finalizes the Kafka client and all its dependencies.
+// In-flight RPC requests and pending Sink messages are drained before closing the clients, up to the shutdown timeout.
 func (cli *KafkaClient) Stop() {
 	cli.registry.StopModules()
 	log.Warnf("Stopping Kafka client")
+	summary := drainClient(cli.shutdown, cli.pool, cli.getSinkDrainers()...)
+	if cli.queue != nil {
+		cli.queue.stop()
+	}
 	cli.ctxCancel()
+	cli.tracker.stop()
+	cli.chunks.stop()
 	cli.consumer.Close()
+	cli.twinConsumer.Close()
 	cli.producer.Close()
 	if cli.traceCloser != nil {
 		cli.traceCloser.Close()
 	}
+	summary.log()
 	log.Infof("Good bye")
 }
 
+// Gets the pending Sink messages to drain when stopping.
+// The persistent queue is flushed first, as it delivers its messages through the producer.
+func (cli *KafkaClient) getSinkDrainers() []sinkDrainer {
+	drainers := []sinkDrainer{}
+	if cli.queue != nil {
+		drainers = append(drainers, sinkDrainer{pending: cli.queue.count, flush: cli.queue.flush})
+	}
+	drainers = append(drainers, sinkDrainer{
+		pending: func() int {
+			return int(cli.producer.BufferedProduceRecords())
+		},
+		flush: func(ctx context.Context) int {
+			cli.producer.Flush(ctx)
+			return int(cli.producer.BufferedProduceRecords())
+		},
+	})
+	return drainers
+}
+
+// GetState gets the state of the connections with the Kafka cluster, by pinging it with every client in parallel.
+func (cli *KafkaClient) GetState() api.BrokerState {
+	state := api.BrokerState{Type: "kafka", Endpoint: cli.config.BrokerURL, Connected: true, Connections: make(map[string]string)}
+	clients := map[string]*kgo.Client{"consumer": cli.consumer, "producer": cli.producer, "twin-consumer": cli.twinConsumer}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaPingTimeout)
+	defer cancel()
+	mutex := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+	for name, client := range clients {
+		if client == nil {
+			state.Connected = false
+			continue
+		}
+		wg.Go(func() {
+			err := client.Ping(ctx)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				state.Connections[name] = err.Error()
+				state.Connected = false
+			} else {
+				state.Connections[name] = "READY"
+			}
+		})
+	}
+	wg.Wait()
+	return state
+}
+
+// CheckLiveness returns an error when the RPC consumer loop has exited or hangs for longer than the liveness timeout.
+func (cli *KafkaClient) CheckLiveness() error {
+	return cli.watchdog.check()
+}
+
+// GetMetrics gets the metrics of the Kafka client, shared with the Sink modules.
+func (cli *KafkaClient) GetMetrics() *api.Metrics {
+	return cli.metrics
+}
+
 // Send forwards a Sink API message to Kafka.
-// Messages are discarded when the brokers are unavailable.
+// When the persistent queue is enabled, messages that cannot be delivered are stored until the brokers are available.
+// As the queue requires delivery confirmation, it always uses the synchronous producer.
 func (cli *KafkaClient) Send(msg *ipc.SinkMessage) error {
+	if cli.queue != nil {
+		return cli.queue.Send(msg)
+	}
+	if cli.asyncSink {
+		return cli.sendMessageAsync(msg)
+	}
+	return cli.sendMessage(msg)
+}
+
+// Forwards a Sink API message to Kafka, waiting for the brokers to acknowledge every chunk.
+// Messages are discarded when the brokers are unavailable.
+func (cli *KafkaClient) sendMessage(msg *ipc.SinkMessage) error {
 	trace := startSpanForSinkMessage(msg)
 	defer trace.Finish()
-	totalChunks := cli.getTotalChunks(msg.Content)
-	var chunk int32
 	var err error
 	topic := fmt.Sprintf("%s.Sink.%s", cli.instanceID, msg.ModuleId)
-	for chunk = 0; chunk < totalChunks; chunk++ {
-		bytes := cli.wrapMessageToSink(msg, chunk, totalChunks)
-		record := &kgo.Record{
-			Topic: topic,
-			Key:   []byte(uuid.New().String()),
-			Value: bytes,
-		}
+	for _, record := range cli.buildSinkRecords(topic, msg) {
 		if err = cli.producer.ProduceSync(cli.ctx, record).FirstErr(); err != nil {
 			break
 		}
@@ -182,6 +334,227 @@ func (cli *KafkaClient) Send(msg *ipc.SinkMessage) error {
 	return nil
 }
 
+// SendDirect sends records to a given Kafka topic, without wrapping them as Sink API messages or splitting them in chunks.
+// It honors the producer mode, and the delivery result of each record is reported via metrics; the persistent queue is not used.
+func (cli *KafkaClient) SendDirect(moduleID string, topic string, key []byte, values [][]byte) error {
+	records := make([]*kgo.Record, len(values))
+	for i, value := range values {
+		records[i] = &kgo.Record{Topic: topic, Key: key, Value: value}
+	}
+	if !cli.asyncSink {
+		var firstErr error
+		for _, result := range cli.producer.ProduceSync(cli.ctx, records...) {
+			if result.Err != nil {
+				cli.metrics.SinkMsgDeliveryFailed.WithLabelValues(cli.config.ID, moduleID).Inc()
+				if firstErr == nil {
+					firstErr = result.Err
+				}
+			} else {
+				cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(cli.config.ID, moduleID).Inc()
+			}
+		}
+		if firstErr != nil {
+			return fmt.Errorf("cannot send message to %s: %v", topic, firstErr)
+		}
+		return nil
+	}
+	callback := func(record *kgo.Record, err error) {
+		if err != nil {
+			cli.metrics.SinkMsgDeliveryFailed.WithLabelValues(cli.config.ID, moduleID).Inc()
+			log.Errorf("%s cannot send message to %s: %v", moduleID, topic, err)
+		} else {
+			cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(cli.config.ID, moduleID).Inc()
+		}
+	}
+	for _, record := range records {
+		cli.producer.Produce(cli.ctx, record, callback)
+	}
+	return nil
+}
+
+// Subscribes to a Twin object by its key.
+func (cli *KafkaClient) Subscribe(key string, handler api.TwinHandler) error {
+	if cli.tracker == nil {
+		return fmt.Errorf("kafka client not started")
+	}
+	return cli.tracker.subscribe(key, handler)
+}
+
+// Unsubscribe removes all the handlers for a given Twin object key.
+func (cli *KafkaClient) Unsubscribe(key string) {
+	if cli.tracker != nil {
+		cli.tracker.unsubscribe(key)
+	}
+}
+
+// Gets the topics used by OpenNMS to send Twin API responses and updates; either for all locations or for this one.
+func (cli *KafkaClient) getTwinResponseTopics() []string {
+	return []string{
+		fmt.Sprintf("%s.twin.response", cli.instanceID),
+		fmt.Sprintf("%s.%s.twin.response", cli.instanceID, cli.config.Location),
+	}
+}
+
+// Starts a goroutine that processes the Twin API responses and updates from OpenNMS.
+func (cli *KafkaClient) startTwinConsumer() {
+	go func() {
+		log.Infof("starting Twin consumer for location %s", cli.config.Location)
+		for {
+			fetches := cli.twinConsumer.PollFetches(cli.ctx)
+			if fetches.IsClientClosed() || cli.ctx.Err() != nil {
+				return
+			}
+			if errs := fetches.Errors(); len(errs) > 0 {
+				log.Errorf("kafka twin consumer errors: %v", errs)
+				continue
+			}
+			for _, record := range fetches.Records() {
+				response := new(twin.TwinResponseProto)
+				if err := proto.Unmarshal(record.Value, response); err == nil {
+					cli.tracker.process(response)
+				} else {
+					log.Errorf("Cannot process Twin response: %v", err)
+				}
+			}
+		}
+	}()
+	cli.tracker.start()
+}
+
+// Sends a Twin API request to OpenNMS.
+func (cli *KafkaClient) sendTwinRequest(request *twin.TwinRequestProto) error {
+	bytes, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	record := &kgo.Record{
+		Topic: fmt.Sprintf("%s.twin.request", cli.instanceID),
+		Key:   []byte(request.ConsumerKey),
+		Value: bytes,
+	}
+	return cli.producer.ProduceSync(cli.ctx, record).FirstErr()
+}
+
+// Forwards a Sink API message to Kafka without waiting for the brokers, so the records can be batched by the producer.
+// The delivery result is reported via metrics once all the chunks were acknowledged.
+func (cli *KafkaClient) sendMessageAsync(msg *ipc.SinkMessage) error {
+	trace := startSpanForSinkMessage(msg)
+	topic := fmt.Sprintf("%s.Sink.%s", cli.instanceID, msg.ModuleId)
+	records := cli.buildSinkRecords(topic, msg)
+	if len(records) == 0 {
+		cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+		trace.Finish()
+		return nil
+	}
+	pending := len(records)
+	var firstErr error
+	mutex := new(sync.Mutex)
+	callback := func(record *kgo.Record, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if pending--; pending > 0 {
+			return
+		}
+		if firstErr != nil {
+			cli.metrics.SinkMsgDeliveryFailed.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+			trace.SetTag("failed", "true")
+			trace.LogKV("event", firstErr.Error())
+			log.Errorf("%s cannot send message to %s: %v", msg.ModuleId, topic, firstErr)
+		} else {
+			cli.metrics.SinkMsgDeliverySucceeded.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+		}
+		trace.Finish()
+	}
+	for _, record := range records {
+		cli.producer.Produce(cli.ctx, record, callback)
+	}
+	return nil
+}
+
+// Splits a Sink API message in chunks, and builds the Kafka records to send.
+// All the chunks use the message ID as the key, to keep them in order on the same partition.
+func (cli *KafkaClient) buildSinkRecords(topic string, msg *ipc.SinkMessage) []*kgo.Record {
+	totalChunks := cli.getTotalChunks(msg.Content)
+	records := make([]*kgo.Record, totalChunks)
+	var chunk int32
+	for chunk = 0; chunk < totalChunks; chunk++ {
+		records[chunk] = &kgo.Record{
+			Topic: topic,
+			Key:   []byte(msg.MessageId),
+			Value: cli.wrapMessageToSink(msg, chunk, totalChunks),
+		}
+	}
+	return records
+}
+
+// Returns true when the Sink messages must be sent asynchronously, based on sink-producer-mode (sync by default).
+func isAsyncSinkProducer(config *api.MinionConfig) (bool, error) {
+	switch mode := config.GetBrokerProperty("sink-producer-mode"); strings.ToLower(mode) {
+	case "", "sync":
+		return false, nil
+	case "async":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid sink-producer-mode %s, expected sync or async", mode)
+	}
+}
+
+// Gets the producer options from the broker properties, using the same names as the Java Kafka client.
+func (cli *KafkaClient) getProducerOptions() ([]kgo.Opt, error) {
+	opts := make([]kgo.Opt, 0)
+	if value := cli.config.GetBrokerProperty("linger.ms"); value != "" {
+		linger, err := strconv.Atoi(value)
+		if err != nil || linger < 0 {
+			return nil, fmt.Errorf("invalid linger.ms %s", value)
+		}
+		opts = append(opts, kgo.ProducerLinger(time.Duration(linger)*time.Millisecond))
+	}
+	if value := cli.config.GetBrokerProperty("batch.size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid batch.size %s", value)
+		}
+		opts = append(opts, kgo.ProducerBatchMaxBytes(int32(size)))
+	}
+	if value := cli.config.GetBrokerProperty("compression.type"); value != "" {
+		var codec kgo.CompressionCodec
+		switch strings.ToLower(value) {
+		case "none":
+			codec = kgo.NoCompression()
+		case "gzip":
+			codec = kgo.GzipCompression()
+		case "snappy":
+			codec = kgo.SnappyCompression()
+		case "lz4":
+			codec = kgo.Lz4Compression()
+		case "zstd":
+			codec = kgo.ZstdCompression()
+		default:
+			return nil, fmt.Errorf("invalid compression.type %s", value)
+		}
+		opts = append(opts, kgo.ProducerBatchCompression(codec))
+	}
+	idempotent := cli.config.GetBrokerProperty("enable.idempotence") != "false"
+	if !idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+	if value := cli.config.GetBrokerProperty("max.in.flight.requests.per.connection"); value != "" {
+		inflight, err := strconv.Atoi(value)
+		if err != nil || inflight <= 0 {
+			return nil, fmt.Errorf("invalid max.in.flight.requests.per.connection %s", value)
+		}
+		if idempotent {
+			log.Warnf("Ignoring max.in.flight.requests.per.connection, as it requires enable.idempotence=false")
+		} else {
+			opts = append(opts, kgo.MaxProduceRequestsInflightPerBroker(inflight))
+		}
+	}
+	return opts, nil
+}
+
 func (cli *KafkaClient) getTotalChunks(data []byte) int32 {
 	if cli.maxBufferSize == 0 {
 		return int32(1)
@@ -224,64 +597,55 @@ func (cli *KafkaClient) getRemainingBufferSize(messageSize, chunk int32) int32 {
 // Processes an RPC API request sent by OpenNMS asynchronously within a goroutine and sends back the response from the module.
 func (cli *KafkaClient) processRequest(request *rpc.RpcMessageProto) {
 	// Process chunks
-	chunk := request.CurrentChunkNumber + 1 // Chunks starts at 0
-	log.Debugf("%s RPC chunk %d of %d for %s received", request.ModuleId, chunk, request.TotalChunks, request.RpcId)
-	if chunk != request.TotalChunks {
-		if cli.chunkTracker[request.RpcId] < chunk {
-			// Adds partial message to the buffer
-			cli.msgBuffer[request.RpcId] = append(cli.msgBuffer[request.RpcId], request.RpcContent...)
-			cli.chunkTracker[request.RpcId] = chunk
-		} else {
-			log.Warnf("Chunk %d from %s was already processed, ignoring...", chunk, request.RpcId)
-		}
+	log.Debugf("%s RPC chunk %d of %d for %s received", request.ModuleId, request.CurrentChunkNumber+1, request.TotalChunks, request.RpcId)
+	data, ok := cli.chunks.add(request)
+	if !ok {
 		return
 	}
-	// Retrieve the complete message from the buffer
-	var data []byte
-	if request.TotalChunks == 1 { // Handle special case chunk == total == 1
-		data = request.RpcContent
-	} else {
-		data = append(cli.msgBuffer[request.RpcId], request.RpcContent...)
-	}
-	delete(cli.msgBuffer, request.RpcId)
-	delete(cli.chunkTracker, request.RpcId)
 	// Process RPC request
 	log.Debugf("Received RPC request with ID %s for module %s", request.RpcId, request.ModuleId)
 	if module, ok := api.GetRPCModule(request.ModuleId); ok {
-		go func() {
-			var err error
-			req := &ipc.RpcRequestProto{
-				RpcId:          request.RpcId,
-				SystemId:       request.SystemId,
-				ModuleId:       request.ModuleId,
-				ExpirationTime: request.ExpirationTime,
-				RpcContent:     data,
-				Location:       cli.config.Location,
-				TracingInfo:    request.TracingInfo,
-			}
-			trace := startSpanFromRPCMessage(req)
-			if response := module.Execute(req); response != nil {
-				cli.metrics.RPCReqProcessedSucceeded.WithLabelValues(request.SystemId, request.ModuleId).Inc()
-				err = cli.sendResponse(response)
-			} else {
-				cli.metrics.RPCReqProcessedFailed.WithLabelValues(request.SystemId, request.ModuleId).Inc()
-				err = fmt.Errorf("module %s returned an empty response for request %s, ignoring", request.ModuleId, request.RpcId)
-			}
-			if err != nil {
-				trace.SetTag("failed", "true")
-				trace.LogKV("event", err.Error())
-			}
-			trace.Finish()
-		}()
+		req := &ipc.RpcRequestProto{
+			RpcId:          request.RpcId,
+			SystemId:       request.SystemId,
+			ModuleId:       request.ModuleId,
+			ExpirationTime: request.ExpirationTime,
+			RpcContent:     data,
+			Location:       cli.config.Location,
+			TracingInfo:    request.TracingInfo,
+		}
+		submitRPCRequest(cli.pool, cli.metrics, module, req, cli.sendResponse)
 	} else {
 		log.Errorf("Cannot find implementation for module %s, ignoring request with ID %s", request.ModuleId, request.RpcId)
 	}
 }
 
+// Gets the RPC request topics to consume from.
+// With the single-topic layout, all requests for the location share a topic; otherwise, there is a topic per RPC module.
+func (cli *KafkaClient) getRPCRequestTopics() []string {
+	if cli.singleTopic {
+		return []string{fmt.Sprintf("%s.%s.rpc-request", cli.instanceID, cli.config.Location)}
+	}
+	topics := make([]string, 0)
+	for _, module := range api.GetAllRPCModules() {
+		topics = append(topics, fmt.Sprintf("%s.%s.rpc-request.%s", cli.instanceID, cli.config.Location, module.GetID()))
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Gets the topic to send the response for a given RPC module.
+func (cli *KafkaClient) getRPCResponseTopic(module string) string {
+	if cli.singleTopic {
+		return fmt.Sprintf("%s.rpc-response", cli.instanceID)
+	}
+	return fmt.Sprintf("%s.rpc-response.%s", cli.instanceID, module)
+}
+
 func (cli *KafkaClient) sendResponse(response *ipc.RpcResponseProto) error {
 	totalChunks := cli.getTotalChunks(response.RpcContent)
 	var chunk int32
-	topic := fmt.Sprintf("%s.rpc-response", cli.instanceID)
+	topic := cli.getRPCResponseTopic(response.ModuleId)
 	for chunk = 0; chunk < totalChunks; chunk++ {
 		bytes := cli.wrapMessageToRPC(response, chunk, totalChunks)
 		record := &kgo.Record{
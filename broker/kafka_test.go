@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+	"github.com/agalue/gominion/protobuf/sink"
+
+	"google.golang.org/protobuf/proto"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestBuildSinkRecords(t *testing.T) {
+	cli := &KafkaClient{maxBufferSize: 4}
+	msg := &ipc.SinkMessage{MessageId: "0001", ModuleId: "Syslog", Content: []byte("0123456789")}
+	records := cli.buildSinkRecords("OpenNMS.Sink.Syslog", msg)
+	assert.Equal(t, 3, len(records))
+	content := make([]byte, 0)
+	for i, record := range records {
+		assert.Equal(t, "0001", string(record.Key))
+		chunk := &sink.SinkMessage{}
+		assert.NilError(t, proto.Unmarshal(record.Value, chunk))
+		assert.Equal(t, int32(i), chunk.CurrentChunkNumber)
+		assert.Equal(t, int32(3), chunk.TotalChunks)
+		content = append(content, chunk.Content...)
+	}
+	assert.Equal(t, "0123456789", string(content))
+}
+
+func TestGetProducerOptions(t *testing.T) {
+	cli := &KafkaClient{config: &api.MinionConfig{
+		BrokerProperties: map[string]string{
+			"linger.ms":                             "50",
+			"batch.size":                            "65536",
+			"compression.type":                      "zstd",
+			"enable.idempotence":                    "false",
+			"max.in.flight.requests.per.connection": "5",
+		},
+	}}
+	opts, err := cli.getProducerOptions()
+	assert.NilError(t, err)
+	assert.Equal(t, 5, len(opts))
+
+	cli.config.BrokerProperties["compression.type"] = "brotli"
+	_, err = cli.getProducerOptions()
+	assert.ErrorContains(t, err, "compression.type")
+
+	cli.config.BrokerProperties = map[string]string{"linger.ms": "-1"}
+	_, err = cli.getProducerOptions()
+	assert.ErrorContains(t, err, "linger.ms")
+}
+
+func TestSinkProducerMode(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", BrokerProperties: map[string]string{}}
+	async, err := isAsyncSinkProducer(config)
+	assert.NilError(t, err)
+	assert.Assert(t, !async)
+
+	config.BrokerProperties["sink-producer-mode"] = "Async"
+	async, err = isAsyncSinkProducer(config)
+	assert.NilError(t, err)
+	assert.Assert(t, async)
+
+	config.BrokerProperties["sink-producer-mode"] = "batch"
+	_, err = isAsyncSinkProducer(config)
+	assert.ErrorContains(t, err, "invalid sink-producer-mode batch")
+}
+
+func TestRPCTopics(t *testing.T) {
+	cli := &KafkaClient{
+		config:      &api.MinionConfig{ID: "minion1", Location: "Apex"},
+		instanceID:  "OpenNMS",
+		singleTopic: true,
+	}
+	assert.DeepEqual(t, []string{"OpenNMS.Apex.rpc-request"}, cli.getRPCRequestTopics())
+	assert.Equal(t, "OpenNMS.rpc-response", cli.getRPCResponseTopic("SNMP"))
+
+	cli.singleTopic = false
+	topics := cli.getRPCRequestTopics()
+	assert.Equal(t, len(api.GetAllRPCModules()), len(topics))
+	assert.Assert(t, is.Contains(topics, "OpenNMS.Apex.rpc-request.Detect"))
+	assert.Assert(t, is.Contains(topics, "OpenNMS.Apex.rpc-request.SNMP"))
+	assert.Equal(t, "OpenNMS.rpc-response.SNMP", cli.getRPCResponseTopic("SNMP"))
+}
@@ -0,0 +1,145 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+)
+
+// rpcPoolDefaultWorkers is the default maximum number of RPC requests executed concurrently
+const rpcPoolDefaultWorkers = 100
+
+// rpcPoolDefaultQueueSize is the default maximum number of RPC requests waiting for a worker
+const rpcPoolDefaultQueueSize = 1000
+
+// rpcWorkerPool limits the number of RPC requests executed concurrently, globally and per module.
+// Requests that cannot be executed immediately wait on a bounded queue; when the queue is full, requests are rejected.
+// Once closed, new requests are rejected, while the accepted ones continue until they finish.
+type rpcWorkerPool struct {
+	config        *api.MinionConfig
+	metrics       *api.Metrics
+	workers       chan struct{}
+	moduleWorkers map[string]chan struct{}
+	queue         chan struct{}
+	tasks         *sync.WaitGroup
+	pending       int
+	closed        bool
+	mutex         *sync.Mutex
+}
+
+// Creates a new RPC worker pool based on the broker properties.
+func newRPCWorkerPool(config *api.MinionConfig, metrics *api.Metrics) (*rpcWorkerPool, error) {
+	workers, err := getPositiveIntProperty(config, "rpc-workers", rpcPoolDefaultWorkers)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := getPositiveIntProperty(config, "rpc-queue-size", rpcPoolDefaultQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	pool := &rpcWorkerPool{
+		config:        config,
+		metrics:       metrics,
+		workers:       make(chan struct{}, workers),
+		moduleWorkers: make(map[string]chan struct{}),
+		queue:         make(chan struct{}, queueSize),
+		tasks:         new(sync.WaitGroup),
+		mutex:         new(sync.Mutex),
+	}
+	for key, value := range config.BrokerProperties {
+		if module, ok := strings.CutPrefix(strings.ToLower(key), "rpc-workers-"); ok {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid %s %s", key, value)
+			}
+			pool.moduleWorkers[module] = make(chan struct{}, limit)
+		}
+	}
+	return pool, nil
+}
+
+// Executes a task for a given RPC request within a goroutine once a worker is available (non-blocking method).
+// Returns an error when the wait queue is full or the pool is closed; the task is not executed in that case.
+func (pool *rpcWorkerPool) submit(request *ipc.RpcRequestProto, task func()) error {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if pool.closed {
+		pool.metrics.RPCReqRejected.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		return fmt.Errorf("minion is shutting down, rejecting request %s for module %s", request.RpcId, request.ModuleId)
+	}
+	select {
+	case pool.queue <- struct{}{}:
+	default:
+		pool.metrics.RPCReqRejected.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		return fmt.Errorf("RPC queue is full, rejecting request %s for module %s", request.RpcId, request.ModuleId)
+	}
+	pool.tasks.Add(1)
+	pool.pending++
+	go func() {
+		start := time.Now()
+		// The module limit is acquired first to avoid holding global workers while waiting for busy modules
+		moduleWorkers := pool.moduleWorkers[strings.ToLower(request.ModuleId)]
+		if moduleWorkers != nil {
+			moduleWorkers <- struct{}{}
+		}
+		pool.workers <- struct{}{}
+		<-pool.queue
+		pool.metrics.RPCReqQueueWait.WithLabelValues(request.SystemId, request.ModuleId).Observe(time.Since(start).Seconds())
+		defer func() {
+			<-pool.workers
+			if moduleWorkers != nil {
+				<-moduleWorkers
+			}
+			pool.mutex.Lock()
+			pool.pending--
+			pool.mutex.Unlock()
+			pool.tasks.Done()
+		}()
+		task()
+	}()
+	return nil
+}
+
+// Rejects new requests; returns the number of accepted requests that are waiting or running.
+func (pool *rpcWorkerPool) close() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.closed = true
+	return pool.pending
+}
+
+// Waits for the accepted requests to finish, or until the context is done; returns the number of unfinished requests.
+// The pool must be closed first.
+func (pool *rpcWorkerPool) wait(ctx context.Context) int {
+	done := make(chan struct{})
+	go func() {
+		pool.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.pending
+}
+
+// Gets a positive integer from the broker properties, or the default value when the property is not set.
+func getPositiveIntProperty(config *api.MinionConfig, name string, defaultValue int) (int, error) {
+	value := config.GetBrokerProperty(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return number, nil
+}
@@ -0,0 +1,79 @@
+package broker
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRPCWorkerPool(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:       "minion1",
+		Location: "Test",
+		BrokerProperties: map[string]string{
+			"rpc-workers":        "3",
+			"rpc-workers-detect": "1",
+			"rpc-queue-size":     "4",
+		},
+	}
+	pool, err := newRPCWorkerPool(config, api.NewMetrics())
+	assert.NilError(t, err)
+
+	release := make(chan struct{})
+	wg := new(sync.WaitGroup)
+	var running, maxRunning, detectRunning, maxDetectRunning int32
+	track := func(counter *int32, max *int32) {
+		current := atomic.AddInt32(counter, 1)
+		for {
+			previous := atomic.LoadInt32(max)
+			if current <= previous || atomic.CompareAndSwapInt32(max, previous, current) {
+				break
+			}
+		}
+	}
+	task := func(module string) func() {
+		return func() {
+			defer wg.Done()
+			track(&running, &maxRunning)
+			if module == "Detect" {
+				track(&detectRunning, &maxDetectRunning)
+				defer atomic.AddInt32(&detectRunning, -1)
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+		}
+	}
+
+	// 3 workers plus 4 waiting requests are accepted; the rest are rejected
+	modules := []string{"Detect", "Detect", "Detect", "Poller", "Poller", "SNMP", "Collect", "Echo"}
+	rejected := 0
+	for i, module := range modules {
+		wg.Add(1)
+		request := &ipc.RpcRequestProto{RpcId: string(rune('a' + i)), SystemId: config.ID, ModuleId: module}
+		if err := pool.submit(request, task(module)); err != nil {
+			wg.Done()
+			rejected++
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, rejected)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(3), maxRunning)
+	assert.Equal(t, int32(1), maxDetectRunning)
+}
+
+func TestRPCWorkerPoolInvalidConfig(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:               "minion1",
+		BrokerProperties: map[string]string{"rpc-workers-snmp": "none"},
+	}
+	_, err := newRPCWorkerPool(config, api.NewMetrics())
+	assert.ErrorContains(t, err, "invalid rpc-workers-snmp")
+}
@@ -0,0 +1,290 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// sinkQueueRetryInterval is the time to wait before trying to deliver the queued messages after a failure
+const sinkQueueRetryInterval = 1 * time.Second
+
+// sinkQueueFlushInterval is the time between checks for pending messages while flushing the queue
+const sinkQueueFlushInterval = 100 * time.Millisecond
+
+// sinkQueueDefaultMaxSize is the default maximum size in bytes of the on-disk queue
+const sinkQueueDefaultMaxSize = 100 * 1024 * 1024
+
+// sinkQueueEntry represents a message stored on disk
+type sinkQueueEntry struct {
+	seq    uint64
+	module string
+	size   int64
+}
+
+// sinkQueue represents a persistent and size-bounded queue for Sink API messages.
+// Messages are stored on disk, one file per message, when they cannot be delivered, and they are delivered in order when the broker is available again.
+// While the queue is not empty, new messages are appended to it to preserve the order.
+type sinkQueue struct {
+	config       *api.MinionConfig
+	metrics      *api.Metrics
+	deliver      func(msg *ipc.SinkMessage) error
+	path         string
+	maxSize      int64
+	quotas       map[string]int64
+	entries      []sinkQueueEntry
+	moduleSizes  map[string]int64
+	moduleCounts map[string]int
+	totalSize    int64
+	nextSeq      uint64
+	mutex        *sync.Mutex
+	signal       chan struct{}
+	done         chan struct{}
+}
+
+// Creates a persistent Sink queue when enabled via broker properties; returns nil otherwise.
+// The deliver function is used to send the messages to the broker.
+func newSinkQueue(config *api.MinionConfig, metrics *api.Metrics, deliver func(msg *ipc.SinkMessage) error) (*sinkQueue, error) {
+	path := config.GetBrokerProperty("sink-queue-path")
+	if path == "" {
+		return nil, nil
+	}
+	q := &sinkQueue{
+		config:       config,
+		metrics:      metrics,
+		deliver:      deliver,
+		path:         path,
+		maxSize:      sinkQueueDefaultMaxSize,
+		quotas:       make(map[string]int64),
+		moduleSizes:  make(map[string]int64),
+		moduleCounts: make(map[string]int),
+		nextSeq:      1,
+		mutex:        new(sync.Mutex),
+		signal:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	if value := config.GetBrokerProperty("sink-queue-max-size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid sink-queue-max-size %s", value)
+		}
+		q.maxSize = size
+	}
+	for key, value := range config.BrokerProperties {
+		if module, ok := strings.CutPrefix(strings.ToLower(key), "sink-queue-quota-"); ok {
+			quota, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || quota < 0 {
+				return nil, fmt.Errorf("invalid %s %s", key, value)
+			}
+			q.quotas[module] = quota
+		}
+	}
+	if err := os.MkdirAll(path, 0750); err != nil {
+		return nil, fmt.Errorf("cannot create sink queue directory: %v", err)
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	log.Infof("Using persistent Sink queue at %s with %d pending messages", path, len(q.entries))
+	return q, nil
+}
+
+// Loads the index of the messages stored on disk from a previous execution.
+func (q *sinkQueue) load() error {
+	files, err := os.ReadDir(q.path)
+	if err != nil {
+		return fmt.Errorf("cannot read sink queue directory: %v", err)
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".msg") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, ".msg"), "-", 2)
+		seq, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			log.Warnf("Ignoring unexpected file %s on the sink queue directory", name)
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return err
+		}
+		q.entries = append(q.entries, sinkQueueEntry{seq: seq, module: parts[1], size: info.Size()})
+	}
+	sort.Slice(q.entries, func(i, j int) bool { return q.entries[i].seq < q.entries[j].seq })
+	for _, entry := range q.entries {
+		q.track(entry, 1)
+		if entry.seq >= q.nextSeq {
+			q.nextSeq = entry.seq + 1
+		}
+	}
+	return nil
+}
+
+// Starts the goroutine that delivers the queued messages.
+func (q *sinkQueue) start() {
+	go func() {
+		for {
+			if !q.drain() {
+				select {
+				case <-q.done:
+					return
+				case <-time.After(sinkQueueRetryInterval):
+				}
+				continue
+			}
+			select {
+			case <-q.done:
+				return
+			case <-q.signal:
+			}
+		}
+	}()
+	q.notify()
+}
+
+// Stops the delivery goroutine; pending messages remain on disk.
+func (q *sinkQueue) stop() {
+	close(q.done)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.entries) > 0 {
+		log.Warnf("Leaving %d undelivered Sink messages on the persistent queue", len(q.entries))
+	}
+}
+
+// Send delivers a message directly when the queue is empty; otherwise, or when the delivery fails, the message is queued.
+// Returns an error only when the message cannot be queued.
+func (q *sinkQueue) Send(msg *ipc.SinkMessage) error {
+	if q.isEmpty() {
+		if err := q.deliver(msg); err == nil {
+			return nil
+		}
+	}
+	if err := q.enqueue(msg); err != nil {
+		q.metrics.SinkQueueDropped.WithLabelValues(msg.SystemId, msg.ModuleId).Inc()
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+func (q *sinkQueue) isEmpty() bool {
+	return q.count() == 0
+}
+
+// Returns the number of messages stored on disk.
+func (q *sinkQueue) count() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// Waits for the delivery goroutine to empty the queue, or until the context is done; returns the number of undelivered messages.
+func (q *sinkQueue) flush(ctx context.Context) int {
+	ticker := time.NewTicker(sinkQueueFlushInterval)
+	defer ticker.Stop()
+	q.notify()
+	for !q.isEmpty() {
+		select {
+		case <-ctx.Done():
+			return q.count()
+		case <-ticker.C:
+		}
+	}
+	return 0
+}
+
+func (q *sinkQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Stores a message on disk, enforcing the global size and the module quota.
+func (q *sinkQueue) enqueue(msg *ipc.SinkMessage) error {
+	bytes, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("cannot serialize message: %v", err)
+	}
+	size := int64(len(bytes))
+	module := msg.ModuleId
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.totalSize+size > q.maxSize {
+		return fmt.Errorf("sink queue is full, discarding message")
+	}
+	if quota, ok := q.quotas[strings.ToLower(module)]; ok && q.moduleSizes[module]+size > quota {
+		return fmt.Errorf("sink queue quota for %s exceeded, discarding message", msg.ModuleId)
+	}
+	entry := sinkQueueEntry{seq: q.nextSeq, module: module, size: size}
+	tmpFile := q.getFileName(entry) + ".tmp"
+	if err := os.WriteFile(tmpFile, bytes, 0640); err != nil {
+		return fmt.Errorf("cannot store message: %v", err)
+	}
+	if err := os.Rename(tmpFile, q.getFileName(entry)); err != nil {
+		return fmt.Errorf("cannot store message: %v", err)
+	}
+	q.nextSeq++
+	q.entries = append(q.entries, entry)
+	q.track(entry, 1)
+	return nil
+}
+
+// Delivers the queued messages in order; returns false when a delivery fails.
+func (q *sinkQueue) drain() bool {
+	for {
+		q.mutex.Lock()
+		if len(q.entries) == 0 {
+			q.mutex.Unlock()
+			return true
+		}
+		entry := q.entries[0]
+		q.mutex.Unlock()
+
+		msg := &ipc.SinkMessage{}
+		bytes, err := os.ReadFile(q.getFileName(entry))
+		if err == nil {
+			err = proto.Unmarshal(bytes, msg)
+		}
+		if err != nil {
+			log.Errorf("Discarding corrupted message from the sink queue: %v", err)
+		} else if err := q.deliver(msg); err != nil {
+			return false
+		}
+
+		q.mutex.Lock()
+		os.Remove(q.getFileName(entry))
+		q.entries = q.entries[1:]
+		q.track(entry, -1)
+		q.mutex.Unlock()
+	}
+}
+
+// Updates the size trackers and metrics for a given entry; must be called while holding the lock.
+func (q *sinkQueue) track(entry sinkQueueEntry, delta int) {
+	q.totalSize += int64(delta) * entry.size
+	q.moduleSizes[entry.module] += int64(delta) * entry.size
+	q.moduleCounts[entry.module] += delta
+	q.metrics.SinkQueueMessages.WithLabelValues(q.config.ID, entry.module).Set(float64(q.moduleCounts[entry.module]))
+	q.metrics.SinkQueueBytes.WithLabelValues(q.config.ID, entry.module).Set(float64(q.moduleSizes[entry.module]))
+}
+
+func (q *sinkQueue) getFileName(entry sinkQueueEntry) string {
+	return filepath.Join(q.path, fmt.Sprintf("%020d-%s.msg", entry.seq, entry.module))
+}
@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+type mockDelivery struct {
+	available bool
+	messages  []*ipc.SinkMessage
+}
+
+func (d *mockDelivery) deliver(msg *ipc.SinkMessage) error {
+	if !d.available {
+		return fmt.Errorf("server unreachable")
+	}
+	d.messages = append(d.messages, msg)
+	return nil
+}
+
+func TestSinkQueue(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:       "minion1",
+		Location: "Test",
+		BrokerProperties: map[string]string{
+			"sink-queue-path":       t.TempDir(),
+			"sink-queue-quota-trap": "40",
+		},
+	}
+	delivery := &mockDelivery{available: true}
+	queue, err := newSinkQueue(config, api.NewMetrics(), delivery.deliver)
+	assert.NilError(t, err)
+
+	// Direct delivery when the queue is empty
+	assert.NilError(t, queue.Send(&ipc.SinkMessage{MessageId: "1", ModuleId: "Syslog"}))
+	assert.Equal(t, 1, len(delivery.messages))
+
+	// Messages are stored while the server is unavailable, until reaching the module quota
+	delivery.available = false
+	for i := 2; i <= 4; i++ {
+		assert.NilError(t, queue.Send(&ipc.SinkMessage{MessageId: fmt.Sprintf("%d", i), ModuleId: "Syslog"}))
+	}
+	assert.NilError(t, queue.Send(&ipc.SinkMessage{MessageId: "5", ModuleId: "Trap", Content: []byte("trap")}))
+	assert.ErrorContains(t, queue.Send(&ipc.SinkMessage{MessageId: "6", ModuleId: "Trap", Content: []byte("a much bigger trap")}), "quota")
+	assert.Equal(t, 4, len(queue.entries))
+	assert.Assert(t, !queue.drain())
+
+	// Messages survive a restart and are delivered in order
+	delivery.available = true
+	queue, err = newSinkQueue(config, api.NewMetrics(), delivery.deliver)
+	assert.NilError(t, err)
+	assert.Equal(t, 4, len(queue.entries))
+	assert.NilError(t, queue.Send(&ipc.SinkMessage{MessageId: "7", ModuleId: "Syslog"}))
+	assert.Assert(t, queue.drain())
+	assert.Equal(t, 0, len(queue.entries))
+	assert.Equal(t, int64(0), queue.totalSize)
+	ids := make([]string, 0)
+	for _, msg := range delivery.messages {
+		ids = append(ids, msg.MessageId)
+	}
+	assert.DeepEqual(t, []string{"1", "2", "3", "4", "5", "7"}, ids)
+}
@@ -0,0 +1,101 @@
+package broker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Gets the TLS configuration from the broker properties, shared by all the broker implementations.
+// Uses the system CA pool when the CA certificate is not provided.
+func getTLSConfig(config *api.MinionConfig) (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	if srvCertPath := config.GetBrokerProperty("ca-cert-path"); srvCertPath != "" {
+		log.Infof("Loading CA certificate")
+		certPool := x509.NewCertPool()
+		if certificate, err := os.ReadFile(srvCertPath); err == nil {
+			if ok := certPool.AppendCertsFromPEM(certificate); !ok {
+				return nil, fmt.Errorf("failed to append certs")
+			}
+		} else {
+			return nil, err
+		}
+		cfg.RootCAs = certPool
+	}
+
+	cliCertPath := config.GetBrokerProperty("client-cert-path")
+	cliKeyPath := config.GetBrokerProperty("client-key-path")
+	if cliCertPath != "" && cliKeyPath != "" {
+		log.Infof("Loading Client certificate for mTLS")
+		certificate, err := tls.LoadX509KeyPair(cliCertPath, cliKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{certificate}
+	}
+
+	if serverName := config.GetBrokerProperty("tls-server-name"); serverName != "" {
+		cfg.ServerName = serverName
+	}
+
+	if config.GetBrokerProperty("tls-skip-verify") == "true" {
+		log.Warnf("Skipping TLS server certificate verification")
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg, nil
+}
+
+// Gets the Kafka SASL mechanism from the broker properties.
+// Supports PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 and OAUTHBEARER.
+func getSASLMechanism(config *api.MinionConfig) (sasl.Mechanism, error) {
+	username := config.GetBrokerProperty("sasl-username")
+	password := config.GetBrokerProperty("sasl-password")
+	mechanism := strings.ToUpper(config.GetBrokerProperty("sasl-mechanism"))
+	switch mechanism {
+	case "PLAIN":
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("sasl-username and sasl-password required for SASL %s", mechanism)
+		}
+		return plain.Auth{User: username, Pass: password}.AsMechanism(), nil
+	case "SCRAM-SHA-256", "SCRAM-SHA-512":
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("sasl-username and sasl-password required for SASL %s", mechanism)
+		}
+		auth := scram.Auth{User: username, Pass: password}
+		if mechanism == "SCRAM-SHA-256" {
+			return auth.AsSha256Mechanism(), nil
+		}
+		return auth.AsSha512Mechanism(), nil
+	case "OAUTHBEARER":
+		token := config.GetBrokerProperty("sasl-token")
+		tokenPath := config.GetBrokerProperty("sasl-token-path")
+		if token == "" && tokenPath == "" {
+			return nil, fmt.Errorf("sasl-token or sasl-token-path required for SASL %s", mechanism)
+		}
+		// The token file is read on every authentication, to support token rotation
+		return oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+			if tokenPath == "" {
+				return oauth.Auth{Token: token}, nil
+			}
+			data, err := os.ReadFile(tokenPath)
+			if err != nil {
+				return oauth.Auth{}, fmt.Errorf("cannot read SASL token: %v", err)
+			}
+			return oauth.Auth{Token: strings.TrimSpace(string(data))}, nil
+		}), nil
+	}
+	return nil, fmt.Errorf("invalid SASL mechanism %s", mechanism)
+}
@@ -0,0 +1,53 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGetSASLMechanism(t *testing.T) {
+	config := &api.MinionConfig{BrokerProperties: map[string]string{
+		"sasl-username": "minion",
+		"sasl-password": "secret",
+	}}
+	for _, name := range []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"} {
+		config.BrokerProperties["sasl-mechanism"] = name
+		m, err := getSASLMechanism(config)
+		assert.NilError(t, err)
+		assert.Equal(t, name, m.Name())
+	}
+
+	config.BrokerProperties["sasl-mechanism"] = "oauthbearer"
+	_, err := getSASLMechanism(config)
+	assert.ErrorContains(t, err, "sasl-token")
+	config.BrokerProperties["sasl-token"] = "my-token"
+	m, err := getSASLMechanism(config)
+	assert.NilError(t, err)
+	assert.Equal(t, "OAUTHBEARER", m.Name())
+
+	config.BrokerProperties["sasl-mechanism"] = "GSSAPI"
+	_, err = getSASLMechanism(config)
+	assert.ErrorContains(t, err, "invalid SASL mechanism")
+
+	config.BrokerProperties = map[string]string{"sasl-mechanism": "PLAIN"}
+	_, err = getSASLMechanism(config)
+	assert.ErrorContains(t, err, "sasl-username")
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	config := &api.MinionConfig{BrokerProperties: map[string]string{
+		"tls-server-name": "kafka.example.com",
+	}}
+	cfg, err := getTLSConfig(config)
+	assert.NilError(t, err)
+	assert.Equal(t, "kafka.example.com", cfg.ServerName)
+	assert.Assert(t, !cfg.InsecureSkipVerify)
+	assert.Assert(t, cfg.RootCAs == nil)
+
+	config.BrokerProperties["ca-cert-path"] = "/non-existent/ca.crt"
+	_, err = getTLSConfig(config)
+	assert.ErrorContains(t, err, "no such file")
+}
@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+)
+
+// shutdownDefaultTimeout is the default time to wait for in-flight RPC requests and pending Sink messages when stopping
+const shutdownDefaultTimeout = 30 * time.Second
+
+// sinkDrainer represents the pending Sink messages of a broker client
+type sinkDrainer struct {
+	pending func() int                    // Returns the number of pending messages
+	flush   func(ctx context.Context) int // Delivers the pending messages until the context is done, and returns the number of undelivered ones
+}
+
+// shutdownSummary represents the outcome of draining a broker client
+type shutdownSummary struct {
+	rpcCompleted  int
+	rpcDropped    int
+	sinkDelivered int
+	sinkDropped   int
+}
+
+// Gets the shutdown timeout from the broker properties.
+func getShutdownTimeout(config *api.MinionConfig) (time.Duration, error) {
+	return getDurationProperty(config, "shutdown-timeout", shutdownDefaultTimeout)
+}
+
+// Stops accepting RPC requests, and waits for the in-flight ones and then for the pending Sink messages, up to a given timeout.
+// Must be called before closing the streams, as the responses are sent while draining.
+func drainClient(timeout time.Duration, pool *rpcWorkerPool, sinks ...sinkDrainer) shutdownSummary {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	summary := shutdownSummary{}
+	if pool != nil {
+		pending := pool.close()
+		if pending > 0 {
+			log.Infof("Waiting for %d in-flight RPC requests", pending)
+		}
+		summary.rpcDropped = pool.wait(ctx)
+		summary.rpcCompleted = max(pending-summary.rpcDropped, 0)
+	}
+	for _, sink := range sinks {
+		pending := sink.pending()
+		if pending == 0 {
+			continue
+		}
+		log.Infof("Waiting for %d pending Sink messages", pending)
+		dropped := sink.flush(ctx)
+		summary.sinkDropped += dropped
+		summary.sinkDelivered += max(pending-dropped, 0)
+	}
+	return summary
+}
+
+// Logs the outcome of draining a broker client.
+func (s shutdownSummary) log() {
+	if s.rpcDropped > 0 || s.sinkDropped > 0 {
+		log.Warnf("Shutdown summary: %d RPC requests completed, %d dropped; %d Sink messages delivered, %d undelivered", s.rpcCompleted, s.rpcDropped, s.sinkDelivered, s.sinkDropped)
+		return
+	}
+	log.Infof("Shutdown summary: %d RPC requests completed; %d Sink messages delivered", s.rpcCompleted, s.sinkDelivered)
+}
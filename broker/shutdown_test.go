@@ -0,0 +1,60 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDrainClient(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", Location: "Test"}
+	pool, err := newRPCWorkerPool(config, api.NewMetrics())
+	assert.NilError(t, err)
+
+	// One request finishes while draining, the other one is still running when the timeout expires
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	defer close(blocked)
+	assert.NilError(t, pool.submit(&ipc.RpcRequestProto{RpcId: "1", ModuleId: "Echo"}, func() { <-release }))
+	assert.NilError(t, pool.submit(&ipc.RpcRequestProto{RpcId: "2", ModuleId: "Detect"}, func() { <-blocked }))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	// 3 of 5 pending Sink messages are delivered
+	sink := sinkDrainer{
+		pending: func() int { return 5 },
+		flush:   func(ctx context.Context) int { return 2 },
+	}
+	summary := drainClient(500*time.Millisecond, pool, sink)
+	assert.Equal(t, 1, summary.rpcCompleted)
+	assert.Equal(t, 1, summary.rpcDropped)
+	assert.Equal(t, 3, summary.sinkDelivered)
+	assert.Equal(t, 2, summary.sinkDropped)
+
+	// New requests are rejected once draining started
+	err = pool.submit(&ipc.RpcRequestProto{RpcId: "3", ModuleId: "Echo"}, func() {})
+	assert.ErrorContains(t, err, "shutting down")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", BrokerProperties: map[string]string{}}
+	timeout, err := getShutdownTimeout(config)
+	assert.NilError(t, err)
+	assert.Equal(t, shutdownDefaultTimeout, timeout)
+
+	config.BrokerProperties["shutdown-timeout"] = "5s"
+	timeout, err = getShutdownTimeout(config)
+	assert.NilError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	config.BrokerProperties["shutdown-timeout"] = "-1s"
+	_, err = getShutdownTimeout(config)
+	assert.ErrorContains(t, err, "invalid shutdown-timeout")
+}
@@ -0,0 +1,276 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stompConnectTimeout is the time to wait for the broker to accept the connection
+const stompConnectTimeout = 30 * time.Second
+
+// stompDefaultHeartbeat is the default interval of the heart-beats negotiated with the broker
+const stompDefaultHeartbeat = 10 * time.Second
+
+// stompHeartbeatTolerance is the number of heart-beats of the broker that can be missed before considering the connection lost
+const stompHeartbeatTolerance = 2
+
+// stompFrame represents a STOMP 1.2 frame
+type stompFrame struct {
+	command string
+	headers map[string]string
+	body    []byte
+}
+
+// Gets the value of a given header, or an empty string when not present.
+func (f *stompFrame) header(name string) string {
+	return f.headers[name]
+}
+
+// stompConn represents a minimal STOMP 1.2 client connection, as supported by ActiveMQ.
+// Writes are safe for concurrent use; frames must be read from a single goroutine.
+// Heart-beats are exchanged in both directions, so a connection that stops receiving them is considered lost.
+type stompConn struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	writer      *bufio.Writer
+	writeMutex  *sync.Mutex
+	readTimeout time.Duration // Maximum time without receiving data from the server; zero when the server doesn't send heart-beats
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// Connects to a STOMP server, using TLS when a configuration is provided, and authenticates with the given credentials.
+// The heart-beats are negotiated with a given interval, which the server can increase.
+func dialStomp(address string, tlsConfig *tls.Config, login string, passcode string, heartbeat time.Duration) (*stompConn, error) {
+	dialer := &net.Dialer{Timeout: stompConnectTimeout}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &stompConn{
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		writer:     bufio.NewWriter(conn),
+		writeMutex: new(sync.Mutex),
+		done:       make(chan struct{}),
+	}
+	host, _, _ := net.SplitHostPort(address)
+	headers := map[string]string{
+		"accept-version": "1.2",
+		"host":           host,
+		"heart-beat":     fmt.Sprintf("%d,%d", heartbeat.Milliseconds(), heartbeat.Milliseconds()),
+	}
+	if login != "" {
+		headers["login"] = login
+		headers["passcode"] = passcode
+	}
+	conn.SetDeadline(time.Now().Add(stompConnectTimeout))
+	if err := c.writeFrame(&stompFrame{command: "CONNECT", headers: headers}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	frame, err := c.readFrame()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	if frame.command != "CONNECTED" {
+		conn.Close()
+		return nil, fmt.Errorf("connection rejected: %s %s", frame.header("message"), string(frame.body))
+	}
+	send, receive := negotiateStompHeartbeats(heartbeat, frame.header("heart-beat"))
+	c.readTimeout = receive * stompHeartbeatTolerance
+	if send > 0 {
+		go c.sendHeartbeats(send)
+	}
+	return c, nil
+}
+
+// Gets the intervals to send and receive heart-beats, from a given client interval and the heart-beat header of the server (STOMP 1.2).
+// An interval is zero when the server doesn't send or doesn't want to receive heart-beats.
+func negotiateStompHeartbeats(interval time.Duration, header string) (time.Duration, time.Duration) {
+	serverSend, serverReceive, _ := strings.Cut(header, ",")
+	negotiate := func(value string) time.Duration {
+		ms, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || ms <= 0 {
+			return 0
+		}
+		return max(interval, time.Duration(ms)*time.Millisecond)
+	}
+	return negotiate(serverReceive), negotiate(serverSend)
+}
+
+// Sends a heart-beat to the server on every interval, until the connection is closed.
+func (c *stompConn) sendHeartbeats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.writeMutex.Lock()
+			c.writer.WriteByte('\n')
+			err := c.writer.Flush()
+			c.writeMutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Sends a message to a given destination.
+func (c *stompConn) send(destination string, headers map[string]string, body []byte) error {
+	frame := &stompFrame{
+		command: "SEND",
+		headers: map[string]string{"destination": destination},
+		body:    body,
+	}
+	for key, value := range headers {
+		frame.headers[key] = value
+	}
+	return c.writeFrame(frame)
+}
+
+// Subscribes to a given destination; messages are acknowledged automatically.
+func (c *stompConn) subscribe(id string, destination string) error {
+	return c.writeFrame(&stompFrame{
+		command: "SUBSCRIBE",
+		headers: map[string]string{"id": id, "destination": destination, "ack": "auto"},
+	})
+}
+
+// Disconnects from the server and closes the connection; it can be called more than once.
+// The connection might be lost, so the disconnection is not sent when it cannot be written quickly.
+func (c *stompConn) close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.writeFrame(&stompFrame{command: "DISCONNECT", headers: map[string]string{}})
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Writes a frame to the connection.
+// The content-length header is always sent, so ActiveMQ treats the body as bytes.
+func (c *stompConn) writeFrame(frame *stompFrame) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.writer.WriteString(frame.command)
+	c.writer.WriteByte('\n')
+	for key, value := range frame.headers {
+		if frame.command == "CONNECT" {
+			fmt.Fprintf(c.writer, "%s:%s\n", key, value)
+		} else {
+			fmt.Fprintf(c.writer, "%s:%s\n", encodeStompHeader(key), encodeStompHeader(value))
+		}
+	}
+	if frame.command == "SEND" {
+		fmt.Fprintf(c.writer, "content-length:%d\n", len(frame.body))
+	}
+	c.writer.WriteByte('\n')
+	c.writer.Write(frame.body)
+	c.writer.WriteByte(0)
+	return c.writer.Flush()
+}
+
+// Reads the next frame from the connection, skipping heart-beats.
+// Fails when the server stops sending heart-beats for longer than the negotiated timeout.
+func (c *stompConn) readFrame() (*stompFrame, error) {
+	for {
+		if c.readTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		next, err := c.reader.Peek(1)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && c.readTimeout > 0 {
+				return nil, fmt.Errorf("no heart-beat received from the server in %s", c.readTimeout)
+			}
+			return nil, err
+		}
+		if next[0] != '\n' && next[0] != '\r' {
+			return readStompFrame(c.reader)
+		}
+		c.reader.ReadByte()
+	}
+}
+
+// Reads a STOMP frame from a given reader, skipping heart-beats.
+func readStompFrame(reader *bufio.Reader) (*stompFrame, error) {
+	var command string
+	for command == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		command = strings.TrimRight(line, "\r\n")
+	}
+	frame := &stompFrame{command: command, headers: make(map[string]string)}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid STOMP header %s", line)
+		}
+		key = decodeStompHeader(key)
+		if _, exists := frame.headers[key]; !exists { // The first occurrence wins
+			frame.headers[key] = decodeStompHeader(value)
+		}
+	}
+	if value, ok := frame.headers["content-length"]; ok {
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid STOMP content-length %s", value)
+		}
+		frame.body = make([]byte, length)
+		if _, err := io.ReadFull(reader, frame.body); err != nil {
+			return nil, err
+		}
+		if _, err := reader.ReadByte(); err != nil { // NULL terminator
+			return nil, err
+		}
+	} else {
+		body, err := reader.ReadBytes(0)
+		if err != nil {
+			return nil, err
+		}
+		frame.body = bytes.TrimSuffix(body, []byte{0})
+	}
+	return frame, nil
+}
+
+var stompHeaderEncoder = strings.NewReplacer("\\", "\\\\", "\r", "\\r", "\n", "\\n", ":", "\\c")
+var stompHeaderDecoder = strings.NewReplacer("\\\\", "\\", "\\r", "\r", "\\n", "\n", "\\c", ":")
+
+func encodeStompHeader(value string) string {
+	return stompHeaderEncoder.Replace(value)
+}
+
+func decodeStompHeader(value string) string {
+	return stompHeaderDecoder.Replace(value)
+}
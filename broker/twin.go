@@ -0,0 +1,208 @@
+package broker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/twin"
+	"github.com/agalue/gominion/tools"
+)
+
+// twinRetryInterval is the time to wait before requesting again the Twin objects that haven't been received
+const twinRetryInterval = 10 * time.Second
+
+// twinTracker keeps track of the Twin API subscriptions and the last version of each object.
+// It is shared by all the broker implementations, which only have to transport requests and responses.
+// This should be equivalent to AbstractTwinSubscriber.java
+type twinTracker struct {
+	config   *api.MinionConfig
+	metrics  *api.Metrics
+	sender   func(request *twin.TwinRequestProto) error
+	objects  map[string]*api.TwinObject
+	handlers map[string][]api.TwinHandler
+	mutex    *sync.Mutex
+	ticker   *time.Ticker
+}
+
+// Creates a new Twin tracker that uses a given function to send requests to OpenNMS.
+func newTwinTracker(config *api.MinionConfig, metrics *api.Metrics, sender func(request *twin.TwinRequestProto) error) *twinTracker {
+	return &twinTracker{
+		config:   config,
+		metrics:  metrics,
+		sender:   sender,
+		objects:  make(map[string]*api.TwinObject),
+		handlers: make(map[string][]api.TwinHandler),
+		mutex:    new(sync.Mutex),
+	}
+}
+
+// Starts a goroutine that requests again the objects that haven't been received.
+func (t *twinTracker) start() {
+	t.ticker = time.NewTicker(twinRetryInterval)
+	go func(ticker *time.Ticker) {
+		for range ticker.C {
+			for _, key := range t.getPendingKeys() {
+				t.request(key)
+			}
+		}
+	}(t.ticker)
+}
+
+// Stops the retry goroutine.
+func (t *twinTracker) stop() {
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+}
+
+// Registers a handler for a given key, and requests the object to OpenNMS.
+// If the object is already known, the handler is called immediately with the current version.
+func (t *twinTracker) subscribe(key string, handler api.TwinHandler) error {
+	if key == "" {
+		return fmt.Errorf("twin object key required")
+	}
+	if handler == nil {
+		return fmt.Errorf("twin handler required")
+	}
+	t.mutex.Lock()
+	t.handlers[key] = append(t.handlers[key], handler)
+	obj := t.objects[key]
+	t.mutex.Unlock()
+	log.Infof("Subscribed to Twin object %s", key)
+	if obj != nil {
+		handler(obj)
+		return nil
+	}
+	t.request(key)
+	return nil
+}
+
+// Removes all the handlers and the last known version of an object.
+func (t *twinTracker) unsubscribe(key string) {
+	t.mutex.Lock()
+	delete(t.handlers, key)
+	delete(t.objects, key)
+	t.mutex.Unlock()
+	log.Infof("Unsubscribed from Twin object %s", key)
+}
+
+// Requests all the subscribed objects to OpenNMS; used when the broker reconnects.
+func (t *twinTracker) requestAll() {
+	t.mutex.Lock()
+	keys := make([]string, 0, len(t.handlers))
+	for key := range t.handlers {
+		keys = append(keys, key)
+	}
+	t.mutex.Unlock()
+	for _, key := range keys {
+		t.request(key)
+	}
+}
+
+// Sends a request for a given object key.
+// Failures are ignored, as pending objects are requested again periodically.
+func (t *twinTracker) request(key string) {
+	request := &twin.TwinRequestProto{
+		ConsumerKey: key,
+		SystemId:    t.config.ID,
+		Location:    t.config.Location,
+	}
+	log.Debugf("Requesting Twin object %s", key)
+	if err := t.sender(request); err != nil {
+		log.Warnf("Cannot request Twin object %s: %v", key, err)
+	}
+}
+
+// Gets the keys of the subscribed objects that haven't been received.
+func (t *twinTracker) getPendingKeys() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	keys := make([]string, 0)
+	for key := range t.handlers {
+		if _, ok := t.objects[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Processes a Twin response or update sent by OpenNMS, applying patches when needed, and notifies the handlers.
+// Patches that cannot be applied against the current version trigger a new request for the full object.
+func (t *twinTracker) process(response *twin.TwinResponseProto) {
+	key := response.ConsumerKey
+	if response.Location != "" && response.Location != t.config.Location {
+		return
+	}
+	t.mutex.Lock()
+	handlers, ok := t.handlers[key]
+	if !ok {
+		t.mutex.Unlock()
+		log.Debugf("Ignoring Twin object %s without subscribers", key)
+		return
+	}
+	obj, err := t.apply(t.objects[key], response)
+	if err != nil {
+		t.mutex.Unlock()
+		t.metrics.TwinUpdatesFailed.WithLabelValues(t.config.ID, key).Inc()
+		log.Warnf("Cannot apply update for Twin object %s, requesting it again: %v", key, err)
+		t.request(key)
+		return
+	}
+	if obj == nil {
+		t.mutex.Unlock()
+		return
+	}
+	t.objects[key] = obj
+	handlers = append([]api.TwinHandler{}, handlers...)
+	t.mutex.Unlock()
+
+	t.metrics.TwinUpdatesSucceeded.WithLabelValues(t.config.ID, key).Inc()
+	log.Infof("Received version %d of Twin object %s (session %s)", obj.Version, key, obj.SessionID)
+	for _, handler := range handlers {
+		handler(obj)
+	}
+}
+
+// Builds the new version of an object from its current version and the response.
+// Returns nil without error when the response has to be ignored.
+func (t *twinTracker) apply(current *api.TwinObject, response *twin.TwinResponseProto) (*api.TwinObject, error) {
+	obj := &api.TwinObject{
+		Key:       response.ConsumerKey,
+		SessionID: response.SessionId,
+		Version:   response.Version,
+	}
+	if response.IsPatchObject {
+		if current == nil {
+			return nil, fmt.Errorf("patch received before the full object")
+		}
+		if current.SessionID != response.SessionId {
+			return nil, fmt.Errorf("patch received for session %s, expected %s", response.SessionId, current.SessionID)
+		}
+		if response.Version <= current.Version {
+			log.Debugf("Ignoring outdated patch version %d for Twin object %s", response.Version, response.ConsumerKey)
+			return nil, nil
+		}
+		if response.Version != current.Version+1 {
+			return nil, fmt.Errorf("patch version %d received, expected %d", response.Version, current.Version+1)
+		}
+		content, err := tools.ApplyJSONPatch(current.Content, response.TwinObject)
+		if err != nil {
+			return nil, err
+		}
+		obj.Content = content
+		return obj, nil
+	}
+	if len(response.TwinObject) == 0 {
+		log.Debugf("Twin object %s not available on OpenNMS yet", response.ConsumerKey)
+		return nil, nil
+	}
+	if current != nil && current.SessionID == response.SessionId && response.Version <= current.Version {
+		log.Debugf("Ignoring outdated version %d for Twin object %s", response.Version, response.ConsumerKey)
+		return nil, nil
+	}
+	obj.Content = response.TwinObject
+	return obj, nil
+}
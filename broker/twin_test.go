@@ -0,0 +1,102 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/twin"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTwinTracker(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", Location: "Test"}
+	requests := make([]*twin.TwinRequestProto, 0)
+	tracker := newTwinTracker(config, api.NewMetrics(), func(request *twin.TwinRequestProto) error {
+		requests = append(requests, request)
+		return nil
+	})
+
+	var received *api.TwinObject
+	err := tracker.subscribe("trapd.listener.config", func(obj *api.TwinObject) {
+		received = obj
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "trapd.listener.config", requests[0].ConsumerKey)
+	assert.Equal(t, "Test", requests[0].Location)
+	assert.DeepEqual(t, []string{"trapd.listener.config"}, tracker.getPendingKeys())
+
+	// Full object
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey: "trapd.listener.config",
+		SessionId:   "s1",
+		Version:     1,
+		TwinObject:  []byte(`{"port":1162}`),
+	})
+	assert.Assert(t, received != nil)
+	assert.Equal(t, int32(1), received.Version)
+	assert.Equal(t, 0, len(tracker.getPendingKeys()))
+
+	// Patch with the next version
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey:   "trapd.listener.config",
+		SessionId:     "s1",
+		Version:       2,
+		IsPatchObject: true,
+		TwinObject:    []byte(`[{"op":"replace","path":"/port","value":10162}]`),
+	})
+	data := make(map[string]int)
+	assert.NilError(t, received.Unmarshal(&data))
+	assert.Equal(t, int32(2), received.Version)
+	assert.Equal(t, 10162, data["port"])
+
+	// Patch with a missing version must trigger a new request
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey:   "trapd.listener.config",
+		SessionId:     "s1",
+		Version:       4,
+		IsPatchObject: true,
+		TwinObject:    []byte(`[{"op":"replace","path":"/port","value":162}]`),
+	})
+	assert.Equal(t, int32(2), received.Version)
+	assert.Equal(t, 2, len(requests))
+
+	// Updates for other locations or keys without subscribers are ignored
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey: "trapd.listener.config",
+		Location:    "Other",
+		SessionId:   "s2",
+		Version:     1,
+		TwinObject:  []byte(`{"port":162}`),
+	})
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey: "telemetry.listeners",
+		SessionId:   "s2",
+		Version:     1,
+		TwinObject:  []byte(`{}`),
+	})
+	assert.Equal(t, "s1", received.SessionID)
+
+	// A full object from a new session replaces the current one
+	tracker.process(&twin.TwinResponseProto{
+		ConsumerKey: "trapd.listener.config",
+		SessionId:   "s2",
+		Version:     1,
+		TwinObject:  []byte(`{"port":162}`),
+	})
+	assert.Equal(t, "s2", received.SessionID)
+
+	// New subscribers get the current version immediately
+	var other *api.TwinObject
+	err = tracker.subscribe("trapd.listener.config", func(obj *api.TwinObject) {
+		other = obj
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, received, other)
+	assert.Equal(t, 2, len(requests))
+
+	tracker.unsubscribe("trapd.listener.config")
+	tracker.requestAll()
+	assert.Equal(t, 2, len(requests))
+}
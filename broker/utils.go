@@ -1,13 +1,17 @@
 package broker
 
 import (
+	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/collectors"
 	"github.com/agalue/gominion/detectors"
 	"github.com/agalue/gominion/log"
 	"github.com/agalue/gominion/monitors"
+	"github.com/agalue/gominion/protobuf/ipc"
 
 	_ "github.com/agalue/gominion/rpc" // Load all RPC modules
 )
@@ -28,9 +32,66 @@ func GetBroker(config *api.MinionConfig, registry *api.SinkRegistry, metrics *ap
 			metrics:  metrics,
 		}
 	}
+	if strings.ToLower(config.BrokerType) == "activemq" {
+		return &ActiveMQClient{
+			config:   config,
+			registry: registry,
+			metrics:  metrics,
+		}
+	}
 	return nil
 }
 
+// Builds the context for executing an RPC request, with a deadline based on its expiration time when present.
+// The expiration time is expressed in milliseconds since epoch.
+func getRPCContext(request *ipc.RpcRequestProto) (context.Context, context.CancelFunc) {
+	if request.ExpirationTime == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), time.UnixMilli(int64(request.ExpirationTime)))
+}
+
+// Executes an RPC request with a given module within a goroutine managed by a worker pool, and sends back the response using a given function.
+// Requests that expire before or during the execution are ignored.
+// When the pool rejects the request, an error response is sent immediately.
+func submitRPCRequest(pool *rpcWorkerPool, metrics *api.Metrics, module api.RPCModule, request *ipc.RpcRequestProto, send func(response *ipc.RpcResponseProto) error) {
+	err := pool.submit(request, func() {
+		trace := startSpanFromRPCMessage(request)
+		if err := executeRPCRequest(metrics, module, request, send); err != nil {
+			trace.SetTag("failed", "true")
+			trace.LogKV("event", err.Error())
+		}
+		trace.Finish()
+	})
+	if err != nil {
+		log.Warnf("%v", err)
+		if response := module.ErrorResponse(request, err); response != nil {
+			send(response)
+		}
+	}
+}
+
+// Executes an RPC request with a given module, honoring its expiration time, and sends the response using a given function.
+func executeRPCRequest(metrics *api.Metrics, module api.RPCModule, request *ipc.RpcRequestProto, send func(response *ipc.RpcResponseProto) error) error {
+	ctx, cancel := getRPCContext(request)
+	defer cancel()
+	if ctx.Err() != nil {
+		metrics.RPCReqExpired.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		return fmt.Errorf("request %s for module %s expired before execution, ignoring", request.RpcId, request.ModuleId)
+	}
+	response := module.Execute(ctx, request)
+	if ctx.Err() == context.DeadlineExceeded {
+		metrics.RPCReqExpired.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		return fmt.Errorf("request %s for module %s expired during execution, ignoring", request.RpcId, request.ModuleId)
+	}
+	if response == nil {
+		metrics.RPCReqProcessedFailed.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+		return fmt.Errorf("module %s returned an empty response for request %s, ignoring", request.ModuleId, request.RpcId)
+	}
+	metrics.RPCReqProcessedSucceeded.WithLabelValues(request.SystemId, request.ModuleId).Inc()
+	return send(response)
+}
+
 // DisplayRegisteredModules displays all registered modules
 func DisplayRegisteredModules(sinkRegistry *api.SinkRegistry) {
 	for _, m := range api.GetAllRPCModules() {
@@ -0,0 +1,31 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGetRPCContext(t *testing.T) {
+	ctx, cancel := getRPCContext(&ipc.RpcRequestProto{RpcId: "001"})
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.Assert(t, !ok)
+	assert.NilError(t, ctx.Err())
+
+	expiration := time.Now().Add(time.Minute)
+	ctx, cancel = getRPCContext(&ipc.RpcRequestProto{RpcId: "002", ExpirationTime: uint64(expiration.UnixMilli())})
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.Assert(t, ok)
+	assert.Equal(t, expiration.UnixMilli(), deadline.UnixMilli())
+	assert.NilError(t, ctx.Err())
+
+	ctx, cancel = getRPCContext(&ipc.RpcRequestProto{RpcId: "003", ExpirationTime: uint64(time.Now().Add(-time.Second).UnixMilli())})
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/agalue/gominion/api"
+)
+
+// BrokerTypes represents the supported broker implementations
+var BrokerTypes = []string{"grpc", "kafka", "activemq"}
+
+// commonProperties represents the broker properties supported by all the broker implementations
+var commonProperties = []string{
+	"tls-enabled", "ca-cert-path", "client-cert-path", "client-key-path", "tls-server-name", "tls-skip-verify",
+	"shutdown-timeout", "liveness-timeout", "rpc-workers", "rpc-queue-size",
+	"sink-queue-path", "sink-queue-max-size",
+}
+
+// prefixedProperties represents the broker properties that end with the ID of a module
+var prefixedProperties = []string{"rpc-workers-", "sink-queue-quota-"}
+
+// brokerProperties represents the broker properties supported by each broker implementation
+var brokerProperties = map[string][]string{
+	"grpc": {
+		"twin-url", "compression", "keepalive-time", "keepalive-timeout", "keepalive-permit-without-stream",
+		"reconnect-initial-delay", "reconnect-max-delay",
+	},
+	"kafka": {
+		"instance-id", "max-buffer-size", "single-topic", "sink-producer-mode", "rpc-chunk-ttl", "rpc-chunk-buffer-max-size",
+		"sasl-mechanism", "sasl-username", "sasl-password", "sasl-token", "sasl-token-path",
+		"linger.ms", "batch.size", "compression.type", "enable.idempotence", "max.in.flight.requests.per.connection",
+	},
+	"activemq": {
+		"instance-id", "username", "password", "reconnect-initial-delay", "reconnect-max-delay", "heartbeat-interval",
+	},
+}
+
+// ValidateProperties returns all the problems found with the broker type and its properties.
+// The values are parsed the same way as when the broker starts.
+func ValidateProperties(config *api.MinionConfig) []error {
+	brokerType := strings.ToLower(config.BrokerType)
+	known, ok := brokerProperties[brokerType]
+	if !ok {
+		return []error{fmt.Errorf("unknown brokerType %s%s", config.BrokerType, api.Suggestion(config.BrokerType, BrokerTypes))}
+	}
+	known = append(slices.Clone(commonProperties), known...)
+	errs := make([]error, 0)
+	keys := make([]string, 0, len(config.BrokerProperties))
+	for key := range config.BrokerProperties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		name := strings.ToLower(key)
+		prefixed := slices.ContainsFunc(prefixedProperties, func(prefix string) bool {
+			return strings.HasPrefix(name, prefix)
+		})
+		if !prefixed && !slices.Contains(known, name) {
+			errs = append(errs, fmt.Errorf("unknown property %s for broker %s%s", key, brokerType, api.Suggestion(key, known)))
+		}
+	}
+	check := func(_ any, err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	check(getShutdownTimeout(config))
+	check(newLoopWatchdog(config, "RPC consumer loop"))
+	check(getPositiveIntProperty(config, "rpc-workers", rpcPoolDefaultWorkers))
+	check(getPositiveIntProperty(config, "rpc-queue-size", rpcPoolDefaultQueueSize))
+	switch brokerType {
+	case "grpc":
+		check(getBackoffConfig(config))
+		check(getCompressionCallOptions(config))
+		check(getDurationProperty(config, "keepalive-time", 0))
+		check(getDurationProperty(config, "keepalive-timeout", 0))
+	case "kafka":
+		check(getDurationProperty(config, "rpc-chunk-ttl", chunkBufferDefaultTTL))
+		check(getPositiveIntProperty(config, "rpc-chunk-buffer-max-size", chunkBufferDefaultMaxSize))
+		check((&KafkaClient{config: config}).getProducerOptions())
+		check(isAsyncSinkProducer(config))
+		if config.GetBrokerProperty("sasl-mechanism") != "" {
+			check(getSASLMechanism(config))
+		}
+	case "activemq":
+		check(getBackoffConfig(config))
+		check(getDurationProperty(config, "heartbeat-interval", stompDefaultHeartbeat))
+	}
+	return errs
+}
@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateProperties(t *testing.T) {
+	config := &api.MinionConfig{
+		BrokerType: "kafka",
+		BrokerProperties: map[string]string{
+			"tls-enabled":           "true",
+			"sasl-mechanism":        "PLAIN",
+			"sasl-username":         "minion",
+			"sasl-password":         "secret",
+			"linger.ms":             "10",
+			"rpc-workers-snmp":      "4",
+			"sink-queue-quota-trap": "1000",
+		},
+	}
+	assert.Equal(t, 0, len(ValidateProperties(config)))
+
+	config.BrokerProperties["compression"] = "gzip"
+	config.BrokerProperties["sasl-pasword"] = "secret"
+	config.BrokerProperties["linger.ms"] = "-1"
+	config.BrokerProperties["shutdown-timeout"] = "10"
+	errs := ValidateProperties(config)
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	assert.DeepEqual(t, []string{
+		"unknown property compression for broker kafka",
+		"unknown property sasl-pasword for broker kafka (did you mean sasl-password?)",
+		"invalid shutdown-timeout 10",
+		"invalid linger.ms -1",
+	}, messages)
+
+	config.BrokerType = "Kafak"
+	errs = ValidateProperties(config)
+	assert.Equal(t, 1, len(errs))
+	assert.Error(t, errs[0], "unknown brokerType Kafak (did you mean kafka?)")
+}
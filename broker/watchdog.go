@@ -0,0 +1,89 @@
+package broker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+)
+
+// watchdogDefaultTimeout is the default maximum time a consumer loop can be down or without progress
+const watchdogDefaultTimeout = time.Minute
+
+// loopWatchdog detects a consumer loop that is not running, either because it exited and could not be restarted
+// (for instance, when the broker is unreachable), or because it hangs.
+// Hangs are only detected for the loops that report a heartbeat on every iteration, which must wait with a bounded timeout.
+// More than one instance of the loop can run at the same time, for instance, while switching endpoints.
+type loopWatchdog struct {
+	name      string
+	timeout   time.Duration
+	running   int       // Number of instances of the loop running
+	downSince time.Time // When the last instance of the loop exited, or when the watchdog was created
+	heartbeat time.Time // Last iteration of the loop; zero when the loop doesn't report heartbeats
+	mutex     sync.Mutex
+}
+
+// Creates a watchdog for a given consumer loop, using the liveness timeout from the broker properties.
+// The loop is considered down until it starts.
+func newLoopWatchdog(config *api.MinionConfig, name string) (*loopWatchdog, error) {
+	timeout, err := getDurationProperty(config, "liveness-timeout", watchdogDefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &loopWatchdog{name: name, timeout: timeout, downSince: time.Now()}, nil
+}
+
+// Marks an instance of the loop as running, for instance, after connecting to the broker.
+func (w *loopWatchdog) started() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.running++
+	if !w.heartbeat.IsZero() {
+		w.heartbeat = time.Now()
+	}
+}
+
+// Marks an instance of the loop as exited, for instance, after losing the connection with the broker.
+func (w *loopWatchdog) stopped() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.running--; w.running <= 0 {
+		w.running = 0
+		w.downSince = time.Now()
+	}
+}
+
+// Records an iteration of the loop.
+func (w *loopWatchdog) beat() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.heartbeat = time.Now()
+}
+
+// Gets the maximum time a loop that reports heartbeats should wait on every iteration.
+func (w *loopWatchdog) interval() time.Duration {
+	return w.timeout / 2
+}
+
+// Returns an error when the loop has been down or without heartbeats for longer than the timeout.
+func (w *loopWatchdog) check() error {
+	if w == nil {
+		return nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.running == 0 {
+		if elapsed := time.Since(w.downSince); elapsed > w.timeout {
+			return fmt.Errorf("the %s has not been running for %s", w.name, elapsed.Round(time.Second))
+		}
+		return nil
+	}
+	if w.heartbeat.IsZero() {
+		return nil
+	}
+	if elapsed := time.Since(w.heartbeat); elapsed > w.timeout {
+		return fmt.Errorf("the %s has been stuck for %s", w.name, elapsed.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLoopWatchdog(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", BrokerProperties: map[string]string{"liveness-timeout": "50ms"}}
+	watchdog, err := newLoopWatchdog(config, "RPC consumer loop")
+	assert.NilError(t, err)
+	assert.Equal(t, 25*time.Millisecond, watchdog.interval())
+
+	// The loop never started
+	assert.NilError(t, watchdog.check())
+	time.Sleep(100 * time.Millisecond)
+	assert.ErrorContains(t, watchdog.check(), "RPC consumer loop has not been running")
+
+	// Waiting for requests without heartbeats is never considered stuck
+	watchdog.started()
+	time.Sleep(100 * time.Millisecond)
+	assert.NilError(t, watchdog.check())
+
+	// The loop is restarted while switching endpoints
+	watchdog.started()
+	watchdog.stopped()
+	time.Sleep(100 * time.Millisecond)
+	assert.NilError(t, watchdog.check())
+
+	// The loop exited
+	watchdog.stopped()
+	assert.NilError(t, watchdog.check())
+	time.Sleep(100 * time.Millisecond)
+	assert.ErrorContains(t, watchdog.check(), "RPC consumer loop has not been running")
+
+	config.BrokerProperties["liveness-timeout"] = "never"
+	_, err = newLoopWatchdog(config, "RPC consumer loop")
+	assert.ErrorContains(t, err, "invalid liveness-timeout")
+}
+
+func TestLoopWatchdogHeartbeat(t *testing.T) {
+	config := &api.MinionConfig{ID: "minion1", BrokerProperties: map[string]string{"liveness-timeout": "100ms"}}
+	watchdog, err := newLoopWatchdog(config, "RPC consumer loop")
+	assert.NilError(t, err)
+
+	// A loop that reports heartbeats until it hangs
+	hang := make(chan struct{})
+	go func() {
+		watchdog.started()
+		defer watchdog.stopped()
+		for {
+			watchdog.beat()
+			select {
+			case <-hang:
+				time.Sleep(time.Hour)
+			case <-time.After(watchdog.interval()):
+			}
+		}
+	}()
+	time.Sleep(200 * time.Millisecond)
+	assert.NilError(t, watchdog.check())
+
+	close(hang)
+	time.Sleep(200 * time.Millisecond)
+	assert.ErrorContains(t, watchdog.check(), "RPC consumer loop has been stuck")
+}
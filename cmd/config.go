@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/broker"
+	"github.com/agalue/gominion/sink"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// configCmd groups the configuration commands
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the Minion configuration",
+	}
+
+	// validateCmd validates the configuration without starting the Minion
+	validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration, printing all the problems found",
+		Run:   validateHandler,
+		Args:  cobra.NoArgs,
+	}
+)
+
+func init() {
+	configCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func validateHandler(cmd *cobra.Command, args []string) {
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+	errs := append(getUnknownSettings(), validateConfig(config)...)
+	if len(errs) == 0 {
+		fmt.Println("Configuration is valid")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Found %d problems on the configuration:\n", len(errs))
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "- %v\n", err)
+	}
+	os.Exit(1)
+}
+
+// Performs a comprehensive validation of a given configuration, and returns all the problems found
+func validateConfig(config *api.MinionConfig) []error {
+	errs := config.Validate()
+	errs = append(errs, sink.ValidateListeners(config)...)
+	errs = append(errs, broker.ValidateProperties(config)...)
+	return errs
+}
+
+// Gets the settings of the configuration file that are not part of the Minion configuration, as they are silently ignored
+func getUnknownSettings() []error {
+	errs := make([]error, 0)
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return errs
+	}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return append(errs, fmt.Errorf("cannot read configuration file: %v", err))
+	}
+	known := make([]string, 0)
+	configType := reflect.TypeFor[api.MinionConfig]()
+	for i := range configType.NumField() {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("yaml"), ",")
+		known = append(known, name)
+	}
+	settings := make([]string, 0)
+	for key := range v.AllSettings() {
+		settings = append(settings, key)
+	}
+	slices.Sort(settings)
+	for _, key := range settings {
+		if !slices.ContainsFunc(known, func(name string) bool { return strings.EqualFold(name, key) }) {
+			errs = append(errs, fmt.Errorf("unknown setting %s%s", key, api.Suggestion(key, known)))
+		}
+	}
+	return errs
+}
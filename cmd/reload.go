@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/agalue/gominion/admin"
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// configReloader applies the changes of the configuration at runtime, when the configuration file changes or on SIGHUP
+type configReloader struct {
+	current  *api.MinionConfig
+	registry *api.SinkRegistry
+	admin    *admin.Server
+}
+
+// Starts watching for configuration changes in the background
+func (r *configReloader) start() {
+	reload := make(chan string, 1)
+	trigger := func(reason string) {
+		select {
+		case reload <- reason:
+		default: // A reload is already pending
+		}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for sig := range signals {
+			trigger(sig.String())
+		}
+	}()
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		if err := watchConfigFile(configFile, trigger); err != nil {
+			log.Warnf("Cannot watch configuration file %s: %v", configFile, err)
+		}
+	}
+	go func() {
+		for reason := range reload {
+			log.Infof("Reloading configuration (%s)", reason)
+			if err := r.reload(); err != nil {
+				log.Errorf("Configuration rejected, keeping the running configuration: %v", err)
+			}
+		}
+	}()
+}
+
+// Reads the configuration again and applies it
+func (r *configReloader) reload() error {
+	updated, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	return r.apply(updated)
+}
+
+// Applies a given configuration, restarting only the Sink modules affected by the changes.
+// The settings that require a restart of the process are ignored.
+// When the configuration is invalid or a module cannot start with it, the running configuration is kept.
+func (r *configReloader) apply(updated *api.MinionConfig) error {
+	r.keepStaticSettings(updated)
+	if errs := validateConfig(updated); len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %v", errors.Join(errs...))
+	}
+	restarted, err := r.registry.ReloadModules(updated)
+	if err != nil {
+		return fmt.Errorf("cannot reload Sink modules: %v", err)
+	}
+	if len(restarted) > 0 {
+		log.Infof("Restarted Sink modules after the configuration change: %v", restarted)
+	}
+	if updated.LogLevel != r.current.LogLevel {
+		log.Warnf("Changing logging level from %s to %s", r.current.LogLevel, updated.LogLevel)
+		log.SetLevel(updated.LogLevel)
+	}
+	if updated.CompactXML != r.current.CompactXML {
+		api.SetCompactXML(updated.CompactXML)
+	}
+	r.current = updated
+	if r.admin != nil {
+		r.admin.SetConfig(updated)
+	}
+	return nil
+}
+
+// Keeps the current broker, identity and HTTP settings, as they cannot change without restarting the process
+func (r *configReloader) keepStaticSettings(updated *api.MinionConfig) {
+	keep := func(name string, changed bool) {
+		if changed {
+			log.Warnf("Ignoring change of %s, a restart is required", name)
+		}
+	}
+	keep("id", updated.ID != r.current.ID)
+	keep("location", updated.Location != r.current.Location)
+	keep("brokerType", updated.BrokerType != r.current.BrokerType)
+	keep("brokerUrl", updated.BrokerURL != r.current.BrokerURL)
+	keep("brokerProperties", !maps.Equal(updated.BrokerProperties, r.current.BrokerProperties))
+	keep("statsPort", updated.StatsPort != r.current.StatsPort)
+	keep("adminPort", updated.AdminPort != r.current.AdminPort)
+	updated.ID = r.current.ID
+	updated.Location = r.current.Location
+	updated.BrokerType = r.current.BrokerType
+	updated.BrokerURL = r.current.BrokerURL
+	updated.BrokerProperties = r.current.BrokerProperties
+	updated.StatsPort = r.current.StatsPort
+	updated.AdminPort = r.current.AdminPort
+}
+
+// Reads the configuration file and the command line flags again
+func loadConfig() (*api.MinionConfig, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("cannot read configuration file: %v", err)
+		}
+	}
+	config := &api.MinionConfig{}
+	if err := viper.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("cannot parse configuration file: %v", err)
+	}
+	if err := config.ParseListeners(listeners); err != nil {
+		return nil, fmt.Errorf("invalid listener configuration: %v", err)
+	}
+	return config, nil
+}
+
+// Watches the directory of the configuration file, to detect when it is written, replaced or when the symlink it points to changes (like Kubernetes ConfigMaps)
+func watchConfigFile(configFile string, trigger func(reason string)) error {
+	configFile = filepath.Clean(configFile)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		return err
+	}
+	realFile, _ := filepath.EvalSymlinks(configFile)
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				currentFile, _ := filepath.EvalSymlinks(configFile)
+				written := filepath.Clean(event.Name) == configFile && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				if written || (currentFile != "" && currentFile != realFile) {
+					realFile = currentFile
+					trigger("configuration file changed")
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("Error watching configuration file: %v", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+type mockSyslogModule struct {
+	port    int
+	started int
+	fail    bool
+}
+
+func (m *mockSyslogModule) GetID() string {
+	return "Syslog"
+}
+
+func (m *mockSyslogModule) Start(config *api.MinionConfig, sink api.Sink) error {
+	if m.fail && config.SyslogPort != 1514 {
+		return fmt.Errorf("cannot listen on port %d", config.SyslogPort)
+	}
+	m.port = config.SyslogPort
+	m.started++
+	return nil
+}
+
+func (m *mockSyslogModule) Stop() {}
+
+func (m *mockSyslogModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return current.SyslogPort != updated.SyslogPort
+}
+
+func buildTestReloader(t *testing.T) (*configReloader, *mockSyslogModule) {
+	current := &api.MinionConfig{
+		ID:               "minion1",
+		Location:         "Test",
+		BrokerType:       "grpc",
+		BrokerURL:        "grpc-server:8990",
+		BrokerProperties: map[string]string{"tls-enabled": "false"},
+		SyslogPort:       1514,
+		TrapPort:         1162,
+		LogLevel:         "info",
+	}
+	module := &mockSyslogModule{}
+	registry := new(api.SinkRegistry)
+	registry.Init()
+	registry.RegisterModule(module)
+	assert.NilError(t, registry.StartModules(current, nil))
+	return &configReloader{current: current, registry: registry}, module
+}
+
+func TestReloadConfiguration(t *testing.T) {
+	reloader, module := buildTestReloader(t)
+	updated := &api.MinionConfig{
+		ID:               "minion2",
+		Location:         "Remote",
+		BrokerType:       "kafka",
+		BrokerURL:        "kafka:9092",
+		BrokerProperties: map[string]string{"tls-enabled": "true"},
+		SyslogPort:       1515,
+		TrapPort:         1162,
+		LogLevel:         "debug",
+	}
+	assert.NilError(t, reloader.apply(updated))
+
+	// The settings that require a restart are kept
+	assert.Equal(t, "minion1", reloader.current.ID)
+	assert.Equal(t, "Test", reloader.current.Location)
+	assert.Equal(t, "grpc", reloader.current.BrokerType)
+	assert.Equal(t, "grpc-server:8990", reloader.current.BrokerURL)
+	assert.Equal(t, "false", reloader.current.BrokerProperties["tls-enabled"])
+
+	// The rest is applied
+	assert.Equal(t, "debug", reloader.current.LogLevel)
+	assert.Equal(t, 2, module.started)
+	assert.Equal(t, 1515, module.port)
+}
+
+func TestReloadInvalidConfiguration(t *testing.T) {
+	reloader, module := buildTestReloader(t)
+	current := reloader.current
+	updated := &api.MinionConfig{
+		SyslogPort: 1515,
+		TrapPort:   1162,
+		LogLevel:   "info",
+		DNS:        &api.DNSConfig{NameServer: "dns-server"},
+	}
+	assert.ErrorContains(t, reloader.apply(updated), "invalid configuration")
+	assert.Equal(t, current, reloader.current)
+	assert.Equal(t, 1, module.started)
+	assert.Equal(t, 1514, module.port)
+}
+
+func TestReloadConflictingPorts(t *testing.T) {
+	reloader, module := buildTestReloader(t)
+	current := reloader.current
+	updated := *current
+	updated.SyslogPort = 1515
+	updated.TrapPort = 1515
+	assert.ErrorContains(t, reloader.apply(&updated), "port 1515/udp used by syslogPort and trapPort")
+	assert.Equal(t, current, reloader.current)
+	assert.Equal(t, 1, module.started)
+}
+
+func TestReloadFailedRestart(t *testing.T) {
+	reloader, module := buildTestReloader(t)
+	current := reloader.current
+	module.fail = true
+	updated := *current
+	updated.SyslogPort = 1515
+	updated.LogLevel = "debug"
+	assert.ErrorContains(t, reloader.apply(&updated), "cannot reload Sink modules")
+	assert.Equal(t, current, reloader.current)
+	assert.Equal(t, "info", reloader.current.LogLevel)
+	assert.Equal(t, 1514, module.port)
+	assert.Assert(t, reloader.registry.IsRunning("Syslog"))
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 
+	"github.com/agalue/gominion/admin"
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/broker"
 	"github.com/agalue/gominion/log"
@@ -64,13 +66,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is ~/.gominion.yaml)")
 	rootCmd.Flags().StringVarP(&minionConfig.ID, "id", "i", hostname, "Minion ID")
 	rootCmd.Flags().StringVarP(&minionConfig.Location, "location", "l", minionConfig.Location, "Minion Location")
-	rootCmd.Flags().StringVarP(&minionConfig.BrokerType, "brokerType", "b", minionConfig.BrokerType, "Broker Type, either grpc or kafka")
+	rootCmd.Flags().StringVarP(&minionConfig.BrokerType, "brokerType", "b", minionConfig.BrokerType, "Broker Type, either grpc, kafka or activemq")
 	rootCmd.Flags().StringVarP(&minionConfig.BrokerURL, "brokerUrl", "u", minionConfig.BrokerURL, "Broker URL")
 	rootCmd.Flags().IntVarP(&minionConfig.TrapPort, "trapPort", "t", minionConfig.TrapPort, "SNMP Trap port")
 	rootCmd.Flags().IntVarP(&minionConfig.SyslogPort, "syslogPort", "s", minionConfig.SyslogPort, "Syslog port")
 	rootCmd.Flags().IntVarP(&minionConfig.StatsPort, "statsPort", "S", minionConfig.StatsPort, "HTTP Prometheus exporter statistics port")
+	rootCmd.Flags().IntVarP(&minionConfig.AdminPort, "adminPort", "A", minionConfig.AdminPort, "HTTP admin API port (can be the same as the statistics port)")
+	rootCmd.Flags().StringVar(&minionConfig.AdminToken, "adminToken", minionConfig.AdminToken, "Bearer token required to change the state of the Minion through the admin API")
 	rootCmd.Flags().StringArrayVarP(&listeners, "listener", "L", nil, "Flow/Telemetry listeners\ne.x. -L Graphite,2003,ForwardParser -L NXOS,5000,NxosGrpcParser")
 	rootCmd.Flags().StringVarP(&minionConfig.LogLevel, "logLevel", "x", minionConfig.LogLevel, "Logging level")
+	rootCmd.Flags().BoolVarP(&minionConfig.CompactXML, "compactXml", "X", minionConfig.CompactXML, "Send XML payloads without indentation")
 
 	// Initialize Flag Binding
 	viper.BindPFlags(rootCmd.Flags())
@@ -116,6 +121,10 @@ func rootHandler(cmd *cobra.Command, args []string) {
 	if err := minionConfig.ParseListeners(listeners); err != nil {
 		log.Fatalf("Invalid listener configuration: %v", err)
 	}
+	for _, err := range validateConfig(minionConfig) {
+		log.Warnf("Configuration problem: %v", err)
+	}
+	api.SetCompactXML(minionConfig.CompactXML)
 	// Initialize metrics object
 	metrics := api.NewMetrics()
 	if minionConfig.StatsPort > 0 {
@@ -128,25 +137,58 @@ func rootHandler(cmd *cobra.Command, args []string) {
 	if client == nil {
 		log.Fatalf("Cannot find broker implementation for %s", minionConfig.BrokerType)
 	}
-	// Start statistics server
+	// Start statistics server, including the health checks
+	adminServer := admin.NewServer(minionConfig, sinkRegistry, client)
 	if minionConfig.StatsPort > 0 {
+		http.Handle("/", promhttp.Handler())
+		adminServer.RegisterHealth(http.DefaultServeMux)
 		go func() {
 			log.Infof("Starting Prometheus Metrics server on port %d", minionConfig.StatsPort)
-			http.Handle("/", promhttp.Handler())
 			err := http.ListenAndServe(fmt.Sprintf(":%d", minionConfig.StatsPort), nil)
 			if err != nil {
 				log.Fatalf("Cannot start prometheus HTTP server: %v", err)
 			}
 		}()
 	}
+	// Start admin API server
+	if minionConfig.AdminPort > 0 {
+		startAdminServer(adminServer)
+	}
 	// Start client broker
 	log.Infof("Starting OpenNMS Minion...\n%s", minionConfig.String())
 	if err := client.Start(); err != nil {
 		log.Fatalf("Cannot connect via %s: %v", minionConfig.BrokerType, err)
 	}
-	// Wait for termination signal
+	adminServer.BrokerStarted()
+	// Apply configuration changes at runtime
+	reloader := &configReloader{current: minionConfig, registry: sinkRegistry, admin: adminServer}
+	reloader.start()
+	// Wait for termination signal (SIGTERM is sent by Docker and Kubernetes)
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	<-stop
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	sig := <-stop
+	log.Warnf("Received %s, shutting down", sig)
 	client.Stop()
 }
+
+// Starts the admin API, sharing the statistics server when both use the same port; otherwise, the health checks are also available
+func startAdminServer(server *admin.Server) {
+	if minionConfig.AdminToken == "" {
+		log.Warnf("The admin API can stop Sink modules without authentication; set adminToken to protect it")
+	}
+	if minionConfig.AdminPort == minionConfig.StatsPort {
+		log.Infof("Adding admin API to the Prometheus Metrics server on port %d", minionConfig.AdminPort)
+		server.Register(http.DefaultServeMux)
+		return
+	}
+	mux := http.NewServeMux()
+	server.Register(mux)
+	server.RegisterHealth(mux)
+	go func() {
+		log.Infof("Starting admin API server on port %d", minionConfig.AdminPort)
+		err := http.ListenAndServe(fmt.Sprintf(":%d", minionConfig.AdminPort), mux)
+		if err != nil {
+			log.Fatalf("Cannot start admin API HTTP server: %v", err)
+		}
+	}()
+}
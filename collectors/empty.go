@@ -3,6 +3,7 @@ package collectors
 // Placeholder for implementing new collectors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/agalue/gominion/api"
@@ -18,7 +19,7 @@ func (collector *EmptyCollector) GetID() string {
 }
 
 // Collect execute the XXX collector request and return the collection response
-func (collector *EmptyCollector) Collect(request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
+func (collector *EmptyCollector) Collect(ctx context.Context, request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
 	response := new(api.CollectorResponseDTO)
 	response.MarkAsFailed(request.CollectionAgent, fmt.Errorf("not implemented"))
 	return response
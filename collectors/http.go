@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
@@ -25,7 +26,7 @@ func (collector *HTTPCollector) GetID() string {
 }
 
 // Collect execute the collector request and return the collection response
-func (collector *HTTPCollector) Collect(request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
+func (collector *HTTPCollector) Collect(ctx context.Context, request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
 	response := &api.CollectorResponseDTO{}
 	httpCollection := &api.HTTPCollection{}
 	err := xml.Unmarshal([]byte(request.GetAttributeValue(httpCollectionAttr, "")), httpCollection)
@@ -46,7 +47,7 @@ func (collector *HTTPCollector) Collect(request *api.CollectorRequestDTO) *api.C
 			Path:   uri.URL.Path,
 		}
 		log.Debugf("Executing an HTTP GET against %s", u.String())
-		httpreq, err := http.NewRequest("GET", u.String(), nil)
+		httpreq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 		if err != nil {
 			response.MarkAsFailed(request.CollectionAgent, err)
 			return response
@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"net/http"
@@ -79,7 +80,7 @@ func TestHttpCollector(t *testing.T) {
 		},
 	}
 	module := new(HTTPCollector)
-	response := module.Collect(request)
+	response := module.Collect(context.Background(), request)
 	bytes, err := xml.MarshalIndent(response, "", "  ")
 	assert.NilError(t, err)
 	fmt.Println(string(bytes))
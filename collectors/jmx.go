@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 
@@ -18,7 +19,7 @@ func (collector *JMXCollector) GetID() string {
 
 // Collect execute the JMX collector request and return the collection response.
 // Currently returns mock data for the JMX-Minion service.
-func (collector *JMXCollector) Collect(request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
+func (collector *JMXCollector) Collect(ctx context.Context, request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
 	response := new(api.CollectorResponseDTO)
 	agent := request.CollectionAgent
 	if agent.IPAddress == "127.0.0.1" && agent.ForeignID == request.SystemID {
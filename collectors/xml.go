@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -24,7 +25,7 @@ func (collector *XMLCollector) GetID() string {
 }
 
 // Collect execute the collector request and return the collection response
-func (collector *XMLCollector) Collect(request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
+func (collector *XMLCollector) Collect(ctx context.Context, request *api.CollectorRequestDTO) *api.CollectorResponseDTO {
 	response := &api.CollectorResponseDTO{}
 	xmlCollection := &api.XMLCollection{}
 	err := xml.Unmarshal([]byte(request.GetAttributeValue(xmlCollectionAttr, "")), xmlCollection)
@@ -41,7 +42,7 @@ func (collector *XMLCollector) Collect(request *api.CollectorRequestDTO) *api.Co
 			return response
 		}
 		log.Debugf("Executing an HTTP GET against %s", src.URL)
-		if doc, err := collector.getDocument(querier, src, request.GetTimeout()); err != nil {
+		if doc, err := collector.getDocument(ctx, querier, src, request.GetTimeout()); err != nil {
 			response.MarkAsFailed(request.CollectionAgent, err)
 			return response
 		} else if err := collector.fillCollectionSet(querier, src, builder, doc); err != nil {
@@ -129,7 +130,7 @@ func (collector *XMLCollector) getCollectionResource(agent *api.CollectionAgentD
 	}
 }
 
-func (collector *XMLCollector) getDocument(querier XPathQuerier, src api.XMLSource, timeout time.Duration) (*XPathNode, error) {
+func (collector *XMLCollector) getDocument(ctx context.Context, querier XPathQuerier, src api.XMLSource, timeout time.Duration) (*XPathNode, error) {
 	httpreq, err := src.GetHTTPRequest()
 	if err != nil {
 		return nil, err
@@ -138,7 +139,7 @@ func (collector *XMLCollector) getDocument(querier XPathQuerier, src api.XMLSour
 		timeout = time.Duration(t) * time.Microsecond
 	}
 	client := tools.GetHTTPClient(src.SkipSSL(), timeout)
-	httpres, err := client.Do(httpreq)
+	httpres, err := client.Do(httpreq.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"net/http"
@@ -76,7 +77,7 @@ func TestXmlCollector(t *testing.T) {
 		},
 	}
 	module := new(XMLCollector)
-	response := module.Collect(request)
+	response := module.Collect(context.Background(), request)
 	bytes, err := xml.MarshalIndent(response, "", "  ")
 	assert.NilError(t, err)
 	fmt.Println(string(bytes))
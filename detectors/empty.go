@@ -3,6 +3,7 @@ package detectors
 // Placeholder for implementing new detectors
 
 import (
+	"context"
 	"github.com/agalue/gominion/api"
 )
 
@@ -16,7 +17,7 @@ func (detector *EmptyDetector) GetID() string {
 }
 
 // Detect execute the XXX detector request and return the detection response
-func (detector *EmptyDetector) Detect(request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
+func (detector *EmptyDetector) Detect(ctx context.Context, request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
 	response := &api.DetectorResponseDTO{Detected: false, Error: "not implemented"}
 	return response
 }
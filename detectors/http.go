@@ -1,6 +1,7 @@
 package detectors
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -25,9 +26,9 @@ func (detector *HTTPDetector) GetID() string {
 }
 
 // Detect execute the HTTP detector request and return the detection response
-func (detector *HTTPDetector) Detect(request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
+func (detector *HTTPDetector) Detect(ctx context.Context, request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
 	results := &api.DetectorResponseDTO{
-		Detected: detector.isDetected(request),
+		Detected: detector.isDetected(ctx, request),
 	}
 	return results
 }
@@ -37,9 +38,9 @@ func (detector *HTTPDetector) getClient(request *api.DetectorRequestDTO) *http.C
 	return tools.GetHTTPClient(useSSLFilter, request.GetTimeout())
 }
 
-func (detector *HTTPDetector) isDetected(request *api.DetectorRequestDTO) bool {
+func (detector *HTTPDetector) isDetected(ctx context.Context, request *api.DetectorRequestDTO) bool {
 	if detector.ID != "WebDetector" {
-		return detector.isDetectedSimple(request)
+		return detector.isDetectedSimple(ctx, request)
 	}
 
 	client := detector.getClient(request)
@@ -52,7 +53,7 @@ func (detector *HTTPDetector) isDetected(request *api.DetectorRequestDTO) bool {
 	if queryString != "" {
 		u.RawQuery = queryString
 	}
-	httpreq, err := http.NewRequest("GET", u.String(), nil)
+	httpreq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return false
 	}
@@ -101,7 +102,7 @@ func (detector *HTTPDetector) getHost(request *api.DetectorRequestDTO) string {
 	return request.IPAddress + ":" + port
 }
 
-func (detector *HTTPDetector) isDetectedSimple(request *api.DetectorRequestDTO) bool {
+func (detector *HTTPDetector) isDetectedSimple(ctx context.Context, request *api.DetectorRequestDTO) bool {
 	maxRetCode, _ := strconv.Atoi(request.GetAttributeValue("maxRetCode", "399"))
 	checkRetCode, _ := strconv.ParseBool(request.GetAttributeValue("checkRetCode", "false"))
 	u := url.URL{
@@ -110,7 +111,11 @@ func (detector *HTTPDetector) isDetectedSimple(request *api.DetectorRequestDTO)
 		Path:   request.GetAttributeValue("url", "/"),
 	}
 	client := detector.getClient(request)
-	response, err := client.Get(u.String())
+	httpreq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return false
+	}
+	response, err := client.Do(httpreq)
 	if err != nil {
 		return false
 	}
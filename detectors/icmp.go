@@ -1,6 +1,7 @@
 package detectors
 
 import (
+	"context"
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/tools"
 )
@@ -15,9 +16,9 @@ func (detector *ICMPDetector) GetID() string {
 }
 
 // Detect execute the ICMP detector request and return the detection response
-func (detector *ICMPDetector) Detect(request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
+func (detector *ICMPDetector) Detect(ctx context.Context, request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
 	results := &api.DetectorResponseDTO{}
-	if _, err := tools.Ping(request.IPAddress, request.GetTimeout()); err == nil {
+	if _, err := tools.Ping(ctx, request.IPAddress, request.GetTimeout()); err == nil {
 		results.Detected = true
 	} else {
 		results.Error = err.Error()
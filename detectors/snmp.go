@@ -1,6 +1,7 @@
 package detectors
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -21,14 +22,14 @@ func (detector *SNMPDetector) GetID() string {
 }
 
 // Detect execute the SNMP detector request and return the detection response
-func (detector *SNMPDetector) Detect(request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
+func (detector *SNMPDetector) Detect(ctx context.Context, request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
 	oid := request.GetAttributeValue("oid", defaultOID)
 	isTable := request.GetAttributeValue("isTable", "false")
 	matchType := request.GetAttributeValue("matchType", "") // Any, All, None, Exist
 	expectedValue := request.GetAttributeValue("vbvalue", "")
 
 	agent := detector.getAgent(request)
-	client := agent.GetSNMPClient()
+	client := agent.GetSNMPClient(ctx)
 	if err := client.Connect(); err != nil {
 		return &api.DetectorResponseDTO{Error: err.Error()}
 	}
@@ -1,6 +1,7 @@
 package detectors
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -18,7 +19,7 @@ func (detector *TCPDetector) GetID() string {
 }
 
 // Detect execute the TCP detector request and return the detection response
-func (detector *TCPDetector) Detect(request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
+func (detector *TCPDetector) Detect(ctx context.Context, request *api.DetectorRequestDTO) *api.DetectorResponseDTO {
 	results := &api.DetectorResponseDTO{Detected: false}
 
 	servAddr := fmt.Sprintf("%s:%s", request.IPAddress, request.GetAttributeValue("port", "23"))
@@ -30,7 +31,7 @@ func (detector *TCPDetector) Detect(request *api.DetectorRequestDTO) *api.Detect
 
 	timeout := request.GetTimeout()
 	dialer := net.Dialer{Timeout: timeout}
-	conn, err := dialer.Dial("tcp", tcpAddr.String())
+	conn, err := dialer.DialContext(ctx, "tcp", tcpAddr.String())
 	if err != nil {
 		results.Error = err.Error()
 		return results
@@ -10,6 +10,7 @@ import (
 
 var logger *zap.Logger
 var log *zap.SugaredLogger
+var level zap.AtomicLevel
 
 // Fatalf logs a formatted fatal message
 func Fatalf(format string, params ...interface{}) {
@@ -58,7 +59,7 @@ func GetSugaredLogger() *zap.SugaredLogger {
 
 // InitLogger initializes colorized logger
 func InitLogger(logLevel string) {
-	level := getLogLevel(logLevel)
+	level = getLogLevel(logLevel)
 	config := zap.Config{
 		Level:             level,
 		Development:       false,
@@ -90,7 +91,8 @@ func InitLogger(logLevel string) {
 // InitProdLogger initializes production logger
 func InitProdLogger(logLevel string) {
 	config := zap.NewProductionConfig()
-	config.Level = getLogLevel(logLevel)
+	level = getLogLevel(logLevel)
+	config.Level = level
 	config.DisableStacktrace = true
 	config.DisableCaller = true
 	var err error
@@ -101,6 +103,13 @@ func InitProdLogger(logLevel string) {
 	log = logger.Sugar()
 }
 
+// SetLevel changes the logging level of an initialized logger at runtime
+func SetLevel(logLevel string) {
+	if logger != nil {
+		level.SetLevel(getLogLevel(logLevel).Level())
+	}
+}
+
 func getLogLevel(logLevel string) zap.AtomicLevel {
 	level := zap.NewAtomicLevel()
 	switch strings.ToLower(logLevel) {
@@ -3,6 +3,7 @@ package monitors
 // Placeholder for implementing new monitors
 
 import (
+	"context"
 	"github.com/agalue/gominion/api"
 )
 
@@ -16,7 +17,7 @@ func (monitor *EmptyMonitor) GetID() string {
 }
 
 // Poll execute the XXX monitor request and return the poller response
-func (monitor *EmptyMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *EmptyMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}, Error: "not implemented"}
 	response.Status.Unknown("not implemented")
 	return response
@@ -1,6 +1,7 @@
 package monitors
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -26,11 +27,11 @@ func (monitor *HTTPMonitor) GetID() string {
 }
 
 // Poll execute the HTTP monitor request and return the the poller response
-func (monitor *HTTPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *HTTPMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}}
 	start := time.Now()
 	client := monitor.getClient(request)
-	httpreq, err := monitor.getHTTPRequest(request)
+	httpreq, err := monitor.getHTTPRequest(ctx, request)
 	if err != nil {
 		response.Status.Down(err.Error())
 		return response
@@ -75,9 +76,9 @@ func (monitor *HTTPMonitor) getURL(request *api.PollerRequestDTO) *url.URL {
 	return u
 }
 
-func (monitor *HTTPMonitor) getHTTPRequest(request *api.PollerRequestDTO) (*http.Request, error) {
+func (monitor *HTTPMonitor) getHTTPRequest(ctx context.Context, request *api.PollerRequestDTO) (*http.Request, error) {
 	u := monitor.getURL(request)
-	httpreq, err := http.NewRequest("GET", u.String(), nil)
+	httpreq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package monitors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/agalue/gominion/api"
@@ -17,9 +18,9 @@ func (monitor *ICMPMonitor) GetID() string {
 }
 
 // Poll execute the ICMP monitor request and return the the poller response
-func (monitor *ICMPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *ICMPMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}}
-	if duration, err := tools.Ping(request.IPAddress, request.GetTimeout()); err == nil {
+	if duration, err := tools.Ping(ctx, request.IPAddress, request.GetTimeout()); err == nil {
 		response.Status.Up(duration.Seconds())
 	} else {
 		msg := fmt.Sprintf("Error while executing ICMP against %s: %v", request.IPAddress, err)
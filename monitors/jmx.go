@@ -1,6 +1,7 @@
 package monitors
 
 import (
+	"context"
 	"github.com/agalue/gominion/api"
 )
 
@@ -15,7 +16,7 @@ func (monitor *JMXMonitor) GetID() string {
 
 // Poll execute the JMX monitor request and return the the poller response.
 // It only assumes the service is up for JMX-Minion; returns an error otherwise.
-func (monitor *JMXMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *JMXMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}}
 	// Whitelist JMX-Minion by default to avoid outages.
 	if request.ServiceName == "JMX-Minion" && request.IPAddress == "127.0.0.1" {
@@ -1,6 +1,7 @@
 package monitors
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -24,7 +25,7 @@ func (monitor *SNMPMonitor) GetID() string {
 }
 
 // Poll execute the SNMP monitor request and return the poller response
-func (monitor *SNMPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *SNMPMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	agent := &api.SNMPAgentDTO{}
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}}
 	if err := xml.Unmarshal([]byte(request.GetAttributeContent("agent")), agent); err == nil {
@@ -35,7 +36,7 @@ func (monitor *SNMPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerRespo
 		matchstr := strings.ToLower(request.GetAttributeValue("match-all", "false"))
 		minimum := request.GetAttributeValueAsInt("minimum", 0)
 		maximum := request.GetAttributeValueAsInt("maximum", 0)
-		client := agent.GetSNMPClient()
+		client := agent.GetSNMPClient(ctx)
 		if err := client.Connect(); err == nil {
 			defer client.Disconnect()
 			response = monitor.poll(client, oid, matchstr, walkstr, operator, operand, minimum, maximum)
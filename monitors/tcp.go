@@ -1,6 +1,7 @@
 package monitors
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"time"
@@ -19,7 +20,7 @@ func (monitor *TCPMonitor) GetID() string {
 }
 
 // Poll execute the TCP monitor request and return the the poller response
-func (monitor *TCPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerResponseDTO {
+func (monitor *TCPMonitor) Poll(ctx context.Context, request *api.PollerRequestDTO) *api.PollerResponseDTO {
 	response := &api.PollerResponseDTO{Status: &api.PollStatus{}}
 	start := time.Now()
 	servAddr := fmt.Sprintf("%s:%s", request.IPAddress, request.GetAttributeValue("port", "23"))
@@ -30,7 +31,7 @@ func (monitor *TCPMonitor) Poll(request *api.PollerRequestDTO) *api.PollerRespon
 	}
 	timeout := request.GetTimeout()
 	dialer := net.Dialer{Timeout: timeout}
-	conn, err := dialer.Dial("tcp", tcpAddr.String())
+	conn, err := dialer.DialContext(ctx, "tcp", tcpAddr.String())
 	if err != nil {
 		response.Status.Down(err.Error())
 		return response
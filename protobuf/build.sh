@@ -8,3 +8,5 @@ protoc --proto_path=./proto --go_out=./ telemetry.proto
 protoc --proto_path=./proto --go_out=./ netflow.proto
 protoc --proto_path=./proto --go_out=./ --go-grpc_out=./ ipc.proto
 protoc --proto_path=./proto --go_out=./ --go-grpc_out=./ mdt_dialout.proto
+protoc --proto_path=./proto --go_out=./ twin-message.proto
+protoc --proto_path=./proto --go_out=./ --go-grpc_out=./ twin-grpc.proto
@@ -0,0 +1,143 @@
+// Source: https://github.com/OpenNMS/opennms/blob/develop/core/ipc/twin/grpc/common/src/main/proto/twin-grpc.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v3.17.3
+// source: twin-grpc.proto
+
+package twin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MinionHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SystemId      string                 `protobuf:"bytes,1,opt,name=system_id,json=systemId,proto3" json:"system_id,omitempty"`
+	Location      string                 `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionHeader) Reset() {
+	*x = MinionHeader{}
+	mi := &file_twin_grpc_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionHeader) ProtoMessage() {}
+
+func (x *MinionHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_twin_grpc_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionHeader.ProtoReflect.Descriptor instead.
+func (*MinionHeader) Descriptor() ([]byte, []int) {
+	return file_twin_grpc_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MinionHeader) GetSystemId() string {
+	if x != nil {
+		return x.SystemId
+	}
+	return ""
+}
+
+func (x *MinionHeader) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+var File_twin_grpc_proto protoreflect.FileDescriptor
+
+const file_twin_grpc_proto_rawDesc = "" +
+	"\n" +
+	"\x0ftwin-grpc.proto\x1a\x12twin-message.proto\"G\n" +
+	"\fMinionHeader\x12\x1b\n" +
+	"\tsystem_id\x18\x01 \x01(\tR\bsystemId\x12\x1a\n" +
+	"\blocation\x18\x02 \x01(\tR\blocation2\x81\x01\n" +
+	"\x0eOpenNMSTwinIpc\x129\n" +
+	"\fRpcStreaming\x12\x11.TwinRequestProto\x1a\x12.TwinResponseProto(\x010\x01\x124\n" +
+	"\rSinkStreaming\x12\r.MinionHeader\x1a\x12.TwinResponseProto0\x01B\bZ\x06./twinb\x06proto3"
+
+var (
+	file_twin_grpc_proto_rawDescOnce sync.Once
+	file_twin_grpc_proto_rawDescData []byte
+)
+
+func file_twin_grpc_proto_rawDescGZIP() []byte {
+	file_twin_grpc_proto_rawDescOnce.Do(func() {
+		file_twin_grpc_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_twin_grpc_proto_rawDesc), len(file_twin_grpc_proto_rawDesc)))
+	})
+	return file_twin_grpc_proto_rawDescData
+}
+
+var file_twin_grpc_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_twin_grpc_proto_goTypes = []any{
+	(*MinionHeader)(nil),      // 0: MinionHeader
+	(*TwinRequestProto)(nil),  // 1: TwinRequestProto
+	(*TwinResponseProto)(nil), // 2: TwinResponseProto
+}
+var file_twin_grpc_proto_depIdxs = []int32{
+	1, // 0: OpenNMSTwinIpc.RpcStreaming:input_type -> TwinRequestProto
+	0, // 1: OpenNMSTwinIpc.SinkStreaming:input_type -> MinionHeader
+	2, // 2: OpenNMSTwinIpc.RpcStreaming:output_type -> TwinResponseProto
+	2, // 3: OpenNMSTwinIpc.SinkStreaming:output_type -> TwinResponseProto
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_twin_grpc_proto_init() }
+func file_twin_grpc_proto_init() {
+	if File_twin_grpc_proto != nil {
+		return
+	}
+	file_twin_message_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_twin_grpc_proto_rawDesc), len(file_twin_grpc_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_twin_grpc_proto_goTypes,
+		DependencyIndexes: file_twin_grpc_proto_depIdxs,
+		MessageInfos:      file_twin_grpc_proto_msgTypes,
+	}.Build()
+	File_twin_grpc_proto = out.File
+	file_twin_grpc_proto_goTypes = nil
+	file_twin_grpc_proto_depIdxs = nil
+}
@@ -0,0 +1,164 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.17.3
+// source: twin-grpc.proto
+
+package twin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OpenNMSTwinIpc_RpcStreaming_FullMethodName  = "/OpenNMSTwinIpc/RpcStreaming"
+	OpenNMSTwinIpc_SinkStreaming_FullMethodName = "/OpenNMSTwinIpc/SinkStreaming"
+)
+
+// OpenNMSTwinIpcClient is the client API for OpenNMSTwinIpc service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// service definitions of Twin API between Minion and OpenNMS
+type OpenNMSTwinIpcClient interface {
+	// Streams Twin requests from Minion to OpenNMS, and the responses back.
+	RpcStreaming(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TwinRequestProto, TwinResponseProto], error)
+	// Streams Twin updates from OpenNMS to Minion.
+	SinkStreaming(ctx context.Context, in *MinionHeader, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TwinResponseProto], error)
+}
+
+type openNMSTwinIpcClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOpenNMSTwinIpcClient(cc grpc.ClientConnInterface) OpenNMSTwinIpcClient {
+	return &openNMSTwinIpcClient{cc}
+}
+
+func (c *openNMSTwinIpcClient) RpcStreaming(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TwinRequestProto, TwinResponseProto], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OpenNMSTwinIpc_ServiceDesc.Streams[0], OpenNMSTwinIpc_RpcStreaming_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TwinRequestProto, TwinResponseProto]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OpenNMSTwinIpc_RpcStreamingClient = grpc.BidiStreamingClient[TwinRequestProto, TwinResponseProto]
+
+func (c *openNMSTwinIpcClient) SinkStreaming(ctx context.Context, in *MinionHeader, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TwinResponseProto], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OpenNMSTwinIpc_ServiceDesc.Streams[1], OpenNMSTwinIpc_SinkStreaming_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MinionHeader, TwinResponseProto]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OpenNMSTwinIpc_SinkStreamingClient = grpc.ServerStreamingClient[TwinResponseProto]
+
+// OpenNMSTwinIpcServer is the server API for OpenNMSTwinIpc service.
+// All implementations must embed UnimplementedOpenNMSTwinIpcServer
+// for forward compatibility.
+//
+// service definitions of Twin API between Minion and OpenNMS
+type OpenNMSTwinIpcServer interface {
+	// Streams Twin requests from Minion to OpenNMS, and the responses back.
+	RpcStreaming(grpc.BidiStreamingServer[TwinRequestProto, TwinResponseProto]) error
+	// Streams Twin updates from OpenNMS to Minion.
+	SinkStreaming(*MinionHeader, grpc.ServerStreamingServer[TwinResponseProto]) error
+	mustEmbedUnimplementedOpenNMSTwinIpcServer()
+}
+
+// UnimplementedOpenNMSTwinIpcServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOpenNMSTwinIpcServer struct{}
+
+func (UnimplementedOpenNMSTwinIpcServer) RpcStreaming(grpc.BidiStreamingServer[TwinRequestProto, TwinResponseProto]) error {
+	return status.Errorf(codes.Unimplemented, "method RpcStreaming not implemented")
+}
+func (UnimplementedOpenNMSTwinIpcServer) SinkStreaming(*MinionHeader, grpc.ServerStreamingServer[TwinResponseProto]) error {
+	return status.Errorf(codes.Unimplemented, "method SinkStreaming not implemented")
+}
+func (UnimplementedOpenNMSTwinIpcServer) mustEmbedUnimplementedOpenNMSTwinIpcServer() {}
+func (UnimplementedOpenNMSTwinIpcServer) testEmbeddedByValue()                        {}
+
+// UnsafeOpenNMSTwinIpcServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OpenNMSTwinIpcServer will
+// result in compilation errors.
+type UnsafeOpenNMSTwinIpcServer interface {
+	mustEmbedUnimplementedOpenNMSTwinIpcServer()
+}
+
+func RegisterOpenNMSTwinIpcServer(s grpc.ServiceRegistrar, srv OpenNMSTwinIpcServer) {
+	// If the following call pancis, it indicates UnimplementedOpenNMSTwinIpcServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OpenNMSTwinIpc_ServiceDesc, srv)
+}
+
+func _OpenNMSTwinIpc_RpcStreaming_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OpenNMSTwinIpcServer).RpcStreaming(&grpc.GenericServerStream[TwinRequestProto, TwinResponseProto]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OpenNMSTwinIpc_RpcStreamingServer = grpc.BidiStreamingServer[TwinRequestProto, TwinResponseProto]
+
+func _OpenNMSTwinIpc_SinkStreaming_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MinionHeader)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OpenNMSTwinIpcServer).SinkStreaming(m, &grpc.GenericServerStream[MinionHeader, TwinResponseProto]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OpenNMSTwinIpc_SinkStreamingServer = grpc.ServerStreamingServer[TwinResponseProto]
+
+// OpenNMSTwinIpc_ServiceDesc is the grpc.ServiceDesc for OpenNMSTwinIpc service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OpenNMSTwinIpc_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "OpenNMSTwinIpc",
+	HandlerType: (*OpenNMSTwinIpcServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RpcStreaming",
+			Handler:       _OpenNMSTwinIpc_RpcStreaming_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SinkStreaming",
+			Handler:       _OpenNMSTwinIpc_SinkStreaming_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "twin-grpc.proto",
+}
@@ -0,0 +1,275 @@
+// Source: https://github.com/OpenNMS/opennms/blob/develop/core/ipc/twin/model/src/main/proto/twin-message.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v3.17.3
+// source: twin-message.proto
+
+package twin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Twin Request object used by Minion to make RPC Request.
+type TwinRequestProto struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConsumerKey   string                 `protobuf:"bytes,1,opt,name=consumer_key,json=consumerKey,proto3" json:"consumer_key,omitempty"`
+	SystemId      string                 `protobuf:"bytes,2,opt,name=system_id,json=systemId,proto3" json:"system_id,omitempty"`
+	Location      string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	TracingInfo   map[string]string      `protobuf:"bytes,4,rep,name=tracing_info,json=tracingInfo,proto3" json:"tracing_info,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TwinRequestProto) Reset() {
+	*x = TwinRequestProto{}
+	mi := &file_twin_message_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TwinRequestProto) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TwinRequestProto) ProtoMessage() {}
+
+func (x *TwinRequestProto) ProtoReflect() protoreflect.Message {
+	mi := &file_twin_message_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TwinRequestProto.ProtoReflect.Descriptor instead.
+func (*TwinRequestProto) Descriptor() ([]byte, []int) {
+	return file_twin_message_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TwinRequestProto) GetConsumerKey() string {
+	if x != nil {
+		return x.ConsumerKey
+	}
+	return ""
+}
+
+func (x *TwinRequestProto) GetSystemId() string {
+	if x != nil {
+		return x.SystemId
+	}
+	return ""
+}
+
+func (x *TwinRequestProto) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *TwinRequestProto) GetTracingInfo() map[string]string {
+	if x != nil {
+		return x.TracingInfo
+	}
+	return nil
+}
+
+// Twin Response object sent by OpenNMS for RPC response as well as for Sink update.
+type TwinResponseProto struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConsumerKey   string                 `protobuf:"bytes,1,opt,name=consumer_key,json=consumerKey,proto3" json:"consumer_key,omitempty"`
+	TwinObject    []byte                 `protobuf:"bytes,2,opt,name=twin_object,json=twinObject,proto3" json:"twin_object,omitempty"`
+	SystemId      string                 `protobuf:"bytes,3,opt,name=system_id,json=systemId,proto3" json:"system_id,omitempty"`
+	Location      string                 `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	IsPatchObject bool                   `protobuf:"varint,5,opt,name=is_patch_object,json=isPatchObject,proto3" json:"is_patch_object,omitempty"`
+	SessionId     string                 `protobuf:"bytes,6,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Version       int32                  `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	TracingInfo   map[string]string      `protobuf:"bytes,8,rep,name=tracing_info,json=tracingInfo,proto3" json:"tracing_info,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TwinResponseProto) Reset() {
+	*x = TwinResponseProto{}
+	mi := &file_twin_message_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TwinResponseProto) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TwinResponseProto) ProtoMessage() {}
+
+func (x *TwinResponseProto) ProtoReflect() protoreflect.Message {
+	mi := &file_twin_message_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TwinResponseProto.ProtoReflect.Descriptor instead.
+func (*TwinResponseProto) Descriptor() ([]byte, []int) {
+	return file_twin_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TwinResponseProto) GetConsumerKey() string {
+	if x != nil {
+		return x.ConsumerKey
+	}
+	return ""
+}
+
+func (x *TwinResponseProto) GetTwinObject() []byte {
+	if x != nil {
+		return x.TwinObject
+	}
+	return nil
+}
+
+func (x *TwinResponseProto) GetSystemId() string {
+	if x != nil {
+		return x.SystemId
+	}
+	return ""
+}
+
+func (x *TwinResponseProto) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *TwinResponseProto) GetIsPatchObject() bool {
+	if x != nil {
+		return x.IsPatchObject
+	}
+	return false
+}
+
+func (x *TwinResponseProto) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *TwinResponseProto) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *TwinResponseProto) GetTracingInfo() map[string]string {
+	if x != nil {
+		return x.TracingInfo
+	}
+	return nil
+}
+
+var File_twin_message_proto protoreflect.FileDescriptor
+
+const file_twin_message_proto_rawDesc = "" +
+	"\n" +
+	"\x12twin-message.proto\"\xf5\x01\n" +
+	"\x10TwinRequestProto\x12!\n" +
+	"\fconsumer_key\x18\x01 \x01(\tR\vconsumerKey\x12\x1b\n" +
+	"\tsystem_id\x18\x02 \x01(\tR\bsystemId\x12\x1a\n" +
+	"\blocation\x18\x03 \x01(\tR\blocation\x12E\n" +
+	"\ftracing_info\x18\x04 \x03(\v2\".TwinRequestProto.TracingInfoEntryR\vtracingInfo\x1a>\n" +
+	"\x10TracingInfoEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xf9\x02\n" +
+	"\x11TwinResponseProto\x12!\n" +
+	"\fconsumer_key\x18\x01 \x01(\tR\vconsumerKey\x12\x1f\n" +
+	"\vtwin_object\x18\x02 \x01(\fR\n" +
+	"twinObject\x12\x1b\n" +
+	"\tsystem_id\x18\x03 \x01(\tR\bsystemId\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\x12&\n" +
+	"\x0fis_patch_object\x18\x05 \x01(\bR\risPatchObject\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x06 \x01(\tR\tsessionId\x12\x18\n" +
+	"\aversion\x18\a \x01(\x05R\aversion\x12F\n" +
+	"\ftracing_info\x18\b \x03(\v2#.TwinResponseProto.TracingInfoEntryR\vtracingInfo\x1a>\n" +
+	"\x10TracingInfoEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\bZ\x06./twinb\x06proto3"
+
+var (
+	file_twin_message_proto_rawDescOnce sync.Once
+	file_twin_message_proto_rawDescData []byte
+)
+
+func file_twin_message_proto_rawDescGZIP() []byte {
+	file_twin_message_proto_rawDescOnce.Do(func() {
+		file_twin_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_twin_message_proto_rawDesc), len(file_twin_message_proto_rawDesc)))
+	})
+	return file_twin_message_proto_rawDescData
+}
+
+var file_twin_message_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_twin_message_proto_goTypes = []any{
+	(*TwinRequestProto)(nil),  // 0: TwinRequestProto
+	(*TwinResponseProto)(nil), // 1: TwinResponseProto
+	nil,                       // 2: TwinRequestProto.TracingInfoEntry
+	nil,                       // 3: TwinResponseProto.TracingInfoEntry
+}
+var file_twin_message_proto_depIdxs = []int32{
+	2, // 0: TwinRequestProto.tracing_info:type_name -> TwinRequestProto.TracingInfoEntry
+	3, // 1: TwinResponseProto.tracing_info:type_name -> TwinResponseProto.TracingInfoEntry
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_twin_message_proto_init() }
+func file_twin_message_proto_init() {
+	if File_twin_message_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_twin_message_proto_rawDesc), len(file_twin_message_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_twin_message_proto_goTypes,
+		DependencyIndexes: file_twin_message_proto_depIdxs,
+		MessageInfos:      file_twin_message_proto_msgTypes,
+	}.Build()
+	File_twin_message_proto = out.File
+	file_twin_message_proto_goTypes = nil
+	file_twin_message_proto_depIdxs = nil
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 
@@ -20,17 +21,16 @@ func (module *CollectorClientRPCModule) GetID() string {
 }
 
 // Execute executes the collection request synchronously and return the response
-func (module *CollectorClientRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *CollectorClientRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.CollectorRequestDTO{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.CollectorResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	collectorID := req.GetCollector()
 	response := &api.CollectorResponseDTO{}
 	log.Infof("Executing %s collector against %s", collectorID, req.CollectionAgent.IPAddress)
 	if collector, ok := collectors.GetCollector(collectorID); ok {
-		response = collector.Collect(req)
+		response = collector.Collect(ctx, req)
 	} else {
 		response.Error = getError(request, fmt.Errorf("cannot find implementation for collector %s", collectorID))
 	}
@@ -38,6 +38,12 @@ func (module *CollectorClientRPCModule) Execute(request *ipc.RpcRequestProto) *i
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a collection response for a request that cannot be executed
+func (module *CollectorClientRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.CollectorResponseDTO{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&CollectorClientRPCModule{})
 }
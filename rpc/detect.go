@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 
@@ -20,17 +21,16 @@ func (module *DetectorClientRPCModule) GetID() string {
 }
 
 // Execute executes the detection request synchronously and return the response
-func (module *DetectorClientRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *DetectorClientRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.DetectorRequestDTO{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.DetectorResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	detectorID := req.GetDetector()
 	response := &api.DetectorResponseDTO{}
 	log.Infof("Executing detector %s against %s", detectorID, req.IPAddress)
 	if monitor, ok := detectors.GetDetector(detectorID); ok {
-		response = monitor.Detect(req)
+		response = monitor.Detect(ctx, req)
 	} else {
 		response.Error = getError(request, fmt.Errorf("cannot find implementation for detector %s", detectorID))
 	}
@@ -38,6 +38,12 @@ func (module *DetectorClientRPCModule) Execute(request *ipc.RpcRequestProto) *ip
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a detection response for a request that cannot be executed
+func (module *DetectorClientRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.DetectorResponseDTO{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&DetectorClientRPCModule{})
 }
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"net"
@@ -20,22 +21,21 @@ func (module *DNSLookupClientRPCModule) GetID() string {
 }
 
 // Execute executes the DNS request synchronously and return the response
-func (module *DNSLookupClientRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *DNSLookupClientRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.DNSLookupRequestDTO{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.DNSLookupResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	response := &api.DNSLookupResponseDTO{}
 	if req.QueryType == "LOOKUP" {
-		addresses, err := net.LookupIP(req.HostRequest)
+		addresses, err := net.DefaultResolver.LookupIP(ctx, "ip", req.HostRequest)
 		if err != nil || len(addresses) == 0 {
 			response.Error = getError(request, fmt.Errorf("cannot lookup for address %s: %v", req.HostRequest, err))
 		} else {
 			response.HostResponse = addresses[0].String()
 		}
 	} else if req.QueryType == "REVERSE_LOOKUP" {
-		hostnames, err := net.LookupAddr(req.HostRequest)
+		hostnames, err := net.DefaultResolver.LookupAddr(ctx, req.HostRequest)
 		if err != nil && len(hostnames) == 0 {
 			response.Error = getError(request, fmt.Errorf("cannot reverse lookup for address %s: %v", req.HostRequest, err))
 		} else {
@@ -48,6 +48,12 @@ func (module *DNSLookupClientRPCModule) Execute(request *ipc.RpcRequestProto) *i
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a DNS response for a request that cannot be executed
+func (module *DNSLookupClientRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.DNSLookupResponseDTO{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&DNSLookupClientRPCModule{})
 }
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"time"
 
@@ -19,14 +20,18 @@ func (module *EchoRPCModule) GetID() string {
 }
 
 // Execute executes the echo request synchronously and return the response
-func (module *EchoRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *EchoRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.EchoRequest{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.EchoResponse{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	if req.Delay > 0 {
-		time.Sleep(time.Duration(req.Delay) * time.Microsecond)
+		select {
+		case <-time.After(time.Duration(req.Delay) * time.Microsecond):
+		case <-ctx.Done():
+			response := &api.EchoResponse{ID: req.ID, Error: getError(request, ctx.Err())}
+			return transformResponse(request, response)
+		}
 	}
 	response := &api.EchoResponse{
 		ID:      req.ID,
@@ -37,6 +42,12 @@ func (module *EchoRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcRespo
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a echo response for a request that cannot be executed
+func (module *EchoRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.EchoResponse{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&EchoRPCModule{})
 }
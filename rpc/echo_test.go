@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEchoCancelled(t *testing.T) {
+	request := &ipc.RpcRequestProto{
+		ModuleId:   "Echo",
+		RpcId:      "001",
+		SystemId:   "minion1",
+		Location:   "Test",
+		RpcContent: []byte(`<echo-request id="1" message="hello" delay="60000000"/>`),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	module := &EchoRPCModule{}
+	start := time.Now()
+	response := module.Execute(ctx, request)
+	assert.Assert(t, time.Since(start) < time.Second)
+	echo := &api.EchoResponse{}
+	assert.NilError(t, xml.Unmarshal(response.RpcContent, echo))
+	assert.Assert(t, echo.Error != "")
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 
@@ -20,14 +21,13 @@ func (module *PingProxyRPCModule) GetID() string {
 }
 
 // Execute executes the ping request synchronously and return the response
-func (module *PingProxyRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *PingProxyRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.PingRequest{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.PingResponse{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	response := &api.PingResponse{}
-	if duration, err := tools.Ping(req.Address, req.GetTimeout()); err == nil {
+	if duration, err := tools.Ping(ctx, req.Address, req.GetTimeout()); err == nil {
 		response.RTT = duration.Seconds()
 	} else {
 		response.Error = getError(request, fmt.Errorf("cannot ping address %s: %v", req.Address, err))
@@ -36,6 +36,12 @@ func (module *PingProxyRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.Rpc
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a ping response for a request that cannot be executed
+func (module *PingProxyRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.PingResponse{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&PingProxyRPCModule{})
 }
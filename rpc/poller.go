@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 
@@ -20,17 +21,16 @@ func (module *PollerClientRPCModule) GetID() string {
 }
 
 // Execute executes the polling request synchronously and return the response
-func (module *PollerClientRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *PollerClientRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.PollerRequestDTO{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.PollerResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	response := &api.PollerResponseDTO{}
 	monitorID := req.GetMonitor()
 	log.Debugf("Executing monitor %s for service %s through %s", monitorID, req.ServiceName, req.IPAddress)
 	if monitor, ok := monitors.GetMonitor(monitorID); ok {
-		response = monitor.Poll(req)
+		response = monitor.Poll(ctx, req)
 	} else {
 		response.Error = getError(request, fmt.Errorf("cannot find implementation for monitor %s", monitorID))
 	}
@@ -38,6 +38,12 @@ func (module *PollerClientRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.
 	return transformResponse(request, response)
 }
 
+// ErrorResponse builds a poller response for a request that cannot be executed
+func (module *PollerClientRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.PollerResponseDTO{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&PollerClientRPCModule{})
 }
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 
@@ -21,16 +22,14 @@ func (module *SNMPProxyRPCModule) GetID() string {
 }
 
 // Execute executes the SNMP request synchronously and return the response
-func (module *SNMPProxyRPCModule) Execute(request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
+func (module *SNMPProxyRPCModule) Execute(ctx context.Context, request *ipc.RpcRequestProto) *ipc.RpcResponseProto {
 	req := &api.SNMPRequestDTO{}
 	if err := xml.Unmarshal(request.RpcContent, req); err != nil {
-		response := &api.SNMPMultiResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
-	client := req.Agent.GetSNMPClient()
+	client := req.Agent.GetSNMPClient(ctx)
 	if err := client.Connect(); err != nil {
-		response := &api.SNMPMultiResponseDTO{Error: getError(request, err)}
-		return transformResponse(request, response)
+		return module.ErrorResponse(request, err)
 	}
 	defer client.Disconnect()
 	return transformResponse(request, module.getResponse(client, req))
@@ -67,6 +66,12 @@ func (module *SNMPProxyRPCModule) snmpWalk(client api.SNMPHandler, walk api.SNMP
 	return response, nil
 }
 
+// ErrorResponse builds a SNMP response for a request that cannot be executed
+func (module *SNMPProxyRPCModule) ErrorResponse(request *ipc.RpcRequestProto, err error) *ipc.RpcResponseProto {
+	response := &api.SNMPMultiResponseDTO{Error: getError(request, err)}
+	return transformResponse(request, response)
+}
+
 func init() {
 	api.RegisterRPCModule(&SNMPProxyRPCModule{})
 }
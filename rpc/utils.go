@@ -1,16 +1,16 @@
 package rpc
 
 import (
-	"encoding/xml"
 	"fmt"
 
+	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 	"github.com/agalue/gominion/protobuf/ipc"
 )
 
 // Builds the RPC Response based on the payload and the request
 func transformResponse(request *ipc.RpcRequestProto, payload interface{}) *ipc.RpcResponseProto {
-	bytes, err := xml.MarshalIndent(payload, "", "   ")
+	bytes, err := api.MarshalXMLPayload(payload)
 	if err != nil {
 		log.Errorf("Cannot parse RPC content: %v", err)
 		return nil
@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+)
+
+// aggregatorDefaultInterval is the default maximum time in milliseconds to wait before sending a batch
+const aggregatorDefaultInterval = 500
+
+// aggregatorBatch represents the entries waiting to be sent for a given key
+type aggregatorBatch[T any] struct {
+	entries []T
+	timer   *time.Timer
+}
+
+// aggregator batches the entries produced by a Sink module per key (for instance, the source address).
+// Each batch is sent as a single message when it reaches the maximum size, or when its first entry reaches the maximum age.
+// When aggregation is disabled for the module, every entry is sent immediately.
+// It is safe for concurrent use.
+type aggregator[K comparable, T any] struct {
+	size     int
+	interval time.Duration
+	flush    func(key K, entries []T)
+	batches  map[K]*aggregatorBatch[T]
+	mutex    *sync.Mutex
+}
+
+// Creates an aggregator for a given Sink module based on its configuration.
+// The flush function builds and sends the message for the entries of a given key.
+func newAggregator[K comparable, T any](moduleID string, config *api.MinionConfig, flush func(key K, entries []T)) *aggregator[K, T] {
+	a := &aggregator[K, T]{
+		size:    1,
+		flush:   flush,
+		batches: make(map[K]*aggregatorBatch[T]),
+		mutex:   new(sync.Mutex),
+	}
+	if cfg := config.GetAggregation(moduleID); cfg != nil && cfg.BatchSize > 1 {
+		interval := cfg.BatchInterval
+		if interval <= 0 {
+			interval = aggregatorDefaultInterval
+		}
+		a.size = cfg.BatchSize
+		a.interval = time.Duration(interval) * time.Millisecond
+		log.Infof("Aggregating %s messages in batches of up to %d entries or %s", moduleID, a.size, a.interval)
+	}
+	return a
+}
+
+// Adds entries to the batch of a given key, and sends the batch when it is full.
+func (a *aggregator[K, T]) add(key K, entries ...T) {
+	if a.size <= 1 {
+		a.flush(key, entries)
+		return
+	}
+	a.mutex.Lock()
+	batch, ok := a.batches[key]
+	if !ok {
+		batch = &aggregatorBatch[T]{entries: make([]T, 0, a.size)}
+		batch.timer = time.AfterFunc(a.interval, func() { a.expire(key, batch) })
+		a.batches[key] = batch
+	}
+	batch.entries = append(batch.entries, entries...)
+	if len(batch.entries) < a.size {
+		a.mutex.Unlock()
+		return
+	}
+	batch.timer.Stop()
+	delete(a.batches, key)
+	a.mutex.Unlock()
+	a.flush(key, batch.entries)
+}
+
+// Sends a given batch when it reaches the maximum age, unless it was already sent.
+func (a *aggregator[K, T]) expire(key K, batch *aggregatorBatch[T]) {
+	a.mutex.Lock()
+	if a.batches[key] != batch {
+		a.mutex.Unlock()
+		return
+	}
+	delete(a.batches, key)
+	a.mutex.Unlock()
+	a.flush(key, batch.entries)
+}
+
+// Sends all the pending batches; must be called when the module stops.
+func (a *aggregator[K, T]) stop() {
+	a.mutex.Lock()
+	batches := a.batches
+	a.batches = make(map[K]*aggregatorBatch[T])
+	a.mutex.Unlock()
+	for key, batch := range batches {
+		batch.timer.Stop()
+		a.flush(key, batch.entries)
+	}
+}
@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+type aggregatedBatch struct {
+	Key     string
+	Entries []int
+}
+
+func newTestAggregator(config *api.MinionConfig) (*aggregator[string, int], chan aggregatedBatch) {
+	batches := make(chan aggregatedBatch, 10)
+	a := newAggregator("Syslog", config, func(key string, entries []int) {
+		batches <- aggregatedBatch{key, entries}
+	})
+	return a, batches
+}
+
+func waitForBatch(t *testing.T, batches chan aggregatedBatch) aggregatedBatch {
+	select {
+	case batch := <-batches:
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a batch")
+	}
+	return aggregatedBatch{}
+}
+
+func TestAggregatorDisabled(t *testing.T) {
+	a, batches := newTestAggregator(&api.MinionConfig{})
+	a.add("10.0.0.1", 1)
+	a.add("10.0.0.1", 2)
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.1", []int{1}}, waitForBatch(t, batches))
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.1", []int{2}}, waitForBatch(t, batches))
+}
+
+func TestAggregatorBatchSize(t *testing.T) {
+	config := &api.MinionConfig{
+		Aggregation: map[string]api.AggregationConfig{"syslog": {BatchSize: 3, BatchInterval: 60000}},
+	}
+	a, batches := newTestAggregator(config)
+	a.add("10.0.0.1", 1)
+	a.add("10.0.0.2", 10)
+	a.add("10.0.0.1", 2, 3)
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.1", []int{1, 2, 3}}, waitForBatch(t, batches))
+
+	// Pending batches are sent when stopping
+	a.stop()
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.2", []int{10}}, waitForBatch(t, batches))
+	assert.Equal(t, 0, len(batches))
+}
+
+func TestAggregatorBatchInterval(t *testing.T) {
+	config := &api.MinionConfig{
+		Aggregation: map[string]api.AggregationConfig{"Syslog": {BatchSize: 100, BatchInterval: 50}},
+	}
+	a, batches := newTestAggregator(config)
+	a.add("10.0.0.1", 1)
+	a.add("10.0.0.1", 2)
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.1", []int{1, 2}}, waitForBatch(t, batches))
+
+	// A new batch starts after the previous one was sent
+	a.add("10.0.0.1", 3)
+	assert.DeepEqual(t, aggregatedBatch{"10.0.0.1", []int{3}}, waitForBatch(t, batches))
+	a.stop()
+	assert.Equal(t, 0, len(batches))
+}
@@ -3,8 +3,10 @@ package sink
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"runtime"
 	"strconv"
 	"sync"
@@ -13,6 +15,7 @@ import (
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 	"github.com/agalue/gominion/protobuf/netflow"
+	"github.com/agalue/gominion/protobuf/telemetry"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/dnscache"
 	"github.com/sony/gobreaker"
@@ -74,16 +77,21 @@ func (logger flowLogger) Debug(params ...interface{}) {}
 // NetflowModule represents a generic UDP forward module
 // It starts a UDP Listener, and forwards the received data to OpenNMS without alteration
 type NetflowModule struct {
-	name      string
-	goflowID  string
-	sink      api.Sink
-	config    *api.MinionConfig
-	listener  *api.MinionListener
-	conn      *net.UDPConn
-	processor *decoder.Processor
-	stopping  bool
-	resolver  *dnscache.Resolver
-	breaker   *gobreaker.CircuitBreaker
+	name       string
+	goflowID   string
+	sink       api.Sink
+	config     *api.MinionConfig
+	listener   *api.MinionListener
+	conn       *net.UDPConn
+	processor  *decoder.Processor
+	stopping   bool
+	resolver   *dnscache.Resolver
+	breaker    *gobreaker.CircuitBreaker
+	direct     api.DirectSink
+	topic      string
+	format     string
+	aggregator *aggregator[telemetrySource, *telemetry.TelemetryMessage]
+	limiter    *rateLimiter
 }
 
 // GetID gets the ID of the sink module
@@ -101,6 +109,11 @@ func (module *NetflowModule) Start(config *api.MinionConfig, sink api.Sink) erro
 		log.Warnf("Flow Module %s disabled", module.name)
 		return nil
 	}
+	if err := module.initDirectSink(); err != nil {
+		return err
+	}
+	module.limiter = newRateLimiter(module.name, config, sink)
+	module.aggregator = newTelemetryAggregator(module.name, "Telemetry-"+module.listener.Name, config, sink)
 	var err error
 	if module.conn, err = createUDPListener(module.listener.Port); err != nil {
 		return err
@@ -117,11 +130,14 @@ func (module *NetflowModule) Start(config *api.MinionConfig, sink api.Sink) erro
 
 	localIP := module.conn.LocalAddr().String()
 
-	go func() {
+	go func(conn *net.UDPConn) {
 		payload := make([]byte, 9000)
 		for {
-			size, pktAddr, err := module.conn.ReadFromUDP(payload)
+			size, pktAddr, err := conn.ReadFromUDP(payload)
 			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
 				if !module.stopping {
 					log.Errorf("%s Cannot read from UDP: %s", module.name, err)
 				}
@@ -165,7 +181,7 @@ func (module *NetflowModule) Start(config *api.MinionConfig, sink api.Sink) erro
 					Observe(float64(size))
 			}
 		}
-	}()
+	}(module.conn)
 	return nil
 }
 
@@ -175,12 +191,25 @@ func (module *NetflowModule) Stop() {
 	module.stopping = true
 	if module.processor != nil {
 		module.processor.Stop()
+		module.processor = nil
 	}
 	if module.conn != nil {
 		module.conn.Close()
+		module.conn = nil
+	}
+	if module.aggregator != nil {
+		module.limiter.stop()
+		module.aggregator.stop()
 	}
 }
 
+// RequiresRestart returns true when the listener, the DNS settings or the Sink settings of the module changed
+func (module *NetflowModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return !reflect.DeepEqual(current.GetListener(module.name), updated.GetListener(module.name)) ||
+		!reflect.DeepEqual(current.DNS, updated.DNS) ||
+		sinkSettingsChanged(module.name, current, updated)
+}
+
 // Publish represents the Transport interface implementation used by goflow
 func (module *NetflowModule) Publish(msgs []*goflowMsg.FlowMessage) {
 	messages := make([][]byte, len(msgs))
@@ -201,9 +230,60 @@ func (module *NetflowModule) Publish(msgs []*goflowMsg.FlowMessage) {
 			messages[idx] = buffer
 		}
 	}
-	if bytes := wrapMessageToTelemetry(module.config, sourceAddress, uint32(module.listener.Port), messages); bytes != nil {
-		sendBytes("Telemetry-"+module.listener.Name, module.config, module.sink, bytes)
+	if module.direct == nil {
+		source := telemetrySource{address: sourceAddress, port: uint32(module.listener.Port)}
+		addTelemetryMessages(module.limiter, module.aggregator, source, messages)
+		return
 	}
+	// Flows sent directly are not aggregated, but are still rate limited per packet
+	module.limiter.submit(sourceAddress, func() {
+		if module.format == "flow" {
+			module.sendDirect(sourceAddress, messages)
+		} else if bytes := wrapMessageToTelemetry(module.config, sourceAddress, uint32(module.listener.Port), messages); bytes != nil {
+			module.sendDirect(sourceAddress, [][]byte{bytes})
+		}
+	})
+}
+
+// Sends the flows directly to the configured topic, keyed by exporter address, bypassing the Sink API
+func (module *NetflowModule) sendDirect(sourceAddress string, messages [][]byte) {
+	values := make([][]byte, 0, len(messages))
+	for _, msg := range messages {
+		if msg != nil {
+			values = append(values, msg)
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	moduleID := "Telemetry-" + module.listener.Name
+	if err := module.direct.SendDirect(moduleID, module.topic, []byte(sourceAddress), values); err != nil {
+		log.Errorf("%s cannot send flows to %s: %v", moduleID, module.topic, err)
+	}
+}
+
+// Enables sending flows directly to a topic when configured on the listener and supported by the broker
+func (module *NetflowModule) initDirectSink() error {
+	module.direct = nil
+	module.topic = module.listener.Properties["kafkaTopic"]
+	if module.topic == "" {
+		return nil
+	}
+	module.format = module.listener.Properties["kafkaFormat"]
+	if module.format == "" {
+		module.format = "flow"
+	}
+	if module.format != "flow" && module.format != "telemetry" {
+		return fmt.Errorf("invalid kafkaFormat %s for %s, expected flow or telemetry", module.format, module.name)
+	}
+	direct, ok := module.sink.(api.DirectSink)
+	if !ok {
+		log.Warnf("The broker doesn't support sending flows directly to %s, using the Sink API", module.topic)
+		return nil
+	}
+	log.Infof("Sending %s flows directly to %s using the %s format", module.name, module.topic, module.format)
+	module.direct = direct
+	return nil
 }
 
 func (module *NetflowModule) getDecoderHandler() decoder.DecoderFunc {
@@ -0,0 +1,136 @@
+package sink
+
+import (
+	"net"
+	"testing"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/netflow"
+	"github.com/agalue/gominion/protobuf/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"google.golang.org/protobuf/proto"
+	"gotest.tools/v3/assert"
+
+	goflowMsg "github.com/cloudflare/goflow/v3/pb"
+)
+
+type MockDirectSink struct {
+	MockSink
+	topic  string
+	key    string
+	values [][]byte
+}
+
+func (sink *MockDirectSink) SendDirect(moduleID string, topic string, key []byte, values [][]byte) error {
+	sink.topic = topic
+	sink.key = string(key)
+	sink.values = append(sink.values, values...)
+	return nil
+}
+
+func buildFlowModule(t *testing.T, sink api.Sink, format string) *NetflowModule {
+	module := &NetflowModule{
+		name:   "Netflow-9",
+		sink:   sink,
+		config: &api.MinionConfig{ID: "minion1", Location: "Test"},
+		listener: &api.MinionListener{
+			Name:       "Netflow-9",
+			Parser:     "Netflow9UdpParser",
+			Port:       4729,
+			Properties: map[string]string{"kafkaTopic": "flows", "kafkaFormat": format},
+		},
+	}
+	assert.NilError(t, module.initDirectSink())
+	module.limiter = newRateLimiter(module.name, module.config, sink)
+	module.aggregator = newTelemetryAggregator(module.name, "Telemetry-"+module.listener.Name, module.config, sink)
+	return module
+}
+
+func buildFlowMessages() []*goflowMsg.FlowMessage {
+	exporter := net.ParseIP("10.0.0.1").To4()
+	return []*goflowMsg.FlowMessage{
+		{Type: goflowMsg.FlowMessage_NETFLOW_V9, SamplerAddress: exporter, SrcAddr: net.ParseIP("192.168.0.1").To4(), SrcPort: 1024},
+		{Type: goflowMsg.FlowMessage_NETFLOW_V9, SamplerAddress: exporter, SrcAddr: net.ParseIP("192.168.0.2").To4(), SrcPort: 2048},
+	}
+}
+
+func TestFlowsDirectSink(t *testing.T) {
+	sink := new(MockDirectSink)
+	module := buildFlowModule(t, sink, "")
+	module.Publish(buildFlowMessages())
+
+	assert.Equal(t, 0, len(sink.messages))
+	assert.Equal(t, "flows", sink.topic)
+	assert.Equal(t, "10.0.0.1", sink.key)
+	assert.Equal(t, 2, len(sink.values))
+	flow := &netflow.FlowMessage{}
+	assert.NilError(t, proto.Unmarshal(sink.values[1], flow))
+	assert.Equal(t, "192.168.0.2", flow.SrcAddress)
+	assert.Equal(t, uint32(2048), flow.SrcPort.Value)
+}
+
+func TestFlowsDirectSinkTelemetry(t *testing.T) {
+	sink := new(MockDirectSink)
+	module := buildFlowModule(t, sink, "telemetry")
+	module.Publish(buildFlowMessages())
+
+	assert.Equal(t, 1, len(sink.values))
+	logMsg := &telemetry.TelemetryMessageLog{}
+	assert.NilError(t, proto.Unmarshal(sink.values[0], logMsg))
+	assert.Equal(t, "10.0.0.1", logMsg.GetSourceAddress())
+	assert.Equal(t, 2, len(logMsg.Message))
+}
+
+func TestFlowsDirectSinkRateLimits(t *testing.T) {
+	sink := new(MockDirectSink)
+	module := buildFlowModule(t, sink, "")
+	module.config.RateLimits = map[string]api.RateLimitConfig{"Netflow-9": {SourceRate: 0.1, SourceBurst: 1}}
+	metrics := api.NewMetrics()
+	module.limiter = newRateLimiter(module.name, module.config, &MockMetricsSink{metrics: metrics})
+	defer module.limiter.stop()
+	module.Publish(buildFlowMessages())
+	module.Publish(buildFlowMessages())
+
+	assert.Equal(t, 2, len(sink.values))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.SinkMsgShed.WithLabelValues("minion1", "Netflow-9", "10.0.0.1")))
+}
+
+func TestFlowsDirectSinkNotSupported(t *testing.T) {
+	sink := new(MockSink)
+	module := buildFlowModule(t, sink, "flow")
+	module.Publish(buildFlowMessages())
+	assert.Equal(t, 1, len(sink.messages))
+	assert.Equal(t, "Telemetry-Netflow-9", sink.messages[0].ModuleId)
+
+	module.listener.Properties["kafkaFormat"] = "json"
+	assert.ErrorContains(t, module.initDirectSink(), "invalid kafkaFormat")
+}
+
+func TestFlowsRequiresRestart(t *testing.T) {
+	module := &NetflowModule{name: "Netflow-5"}
+	current := &api.MinionConfig{
+		Listeners: []api.MinionListener{
+			{Name: "Netflow-5", Port: 8877, Parser: "Netflow5UdpParser"},
+			{Name: "Netflow-9", Port: 4729, Parser: "Netflow9UdpParser"},
+		},
+	}
+	updated := &api.MinionConfig{
+		Listeners: []api.MinionListener{
+			{Name: "Netflow-5", Port: 8877, Parser: "Netflow5UdpParser"},
+			{Name: "Netflow-9", Port: 4730, Parser: "Netflow9UdpParser"},
+		},
+	}
+	assert.Assert(t, !module.RequiresRestart(current, updated))
+
+	updated.Listeners[0].Port = 8878
+	assert.Assert(t, module.RequiresRestart(current, updated))
+
+	updated.Listeners[0].Port = 8877
+	updated.DNS = &api.DNSConfig{NameServer: "8.8.8.8"}
+	assert.Assert(t, module.RequiresRestart(current, updated))
+
+	updated.DNS = nil
+	updated.Aggregation = map[string]api.AggregationConfig{"netflow-5": {BatchSize: 10}}
+	assert.Assert(t, module.RequiresRestart(current, updated))
+}
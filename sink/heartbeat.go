@@ -1,36 +1,72 @@
 package sink
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 )
 
+// heartbeatModuleID is the ID of the heartbeat module, which is never rate limited
+const heartbeatModuleID = "Heartbeat"
+
 // HeartbeatModule represents the heartbeat module
-type HeartbeatModule struct{}
+type HeartbeatModule struct {
+	stop  chan struct{}
+	sent  bool
+	err   error // The outcome of the last heartbeat
+	mutex sync.Mutex
+}
 
 // GetID gets the ID of the sink module
 func (module *HeartbeatModule) GetID() string {
-	return "Heartbeat"
+	return heartbeatModuleID
 }
 
 // Start initiates a blocking loop that sends heartbeats to OpenNMS
 func (module *HeartbeatModule) Start(config *api.MinionConfig, sink api.Sink) error {
 	log.Infof("Starting Sink Heartbeat Module")
-	go func() {
+	module.stop = make(chan struct{})
+	go func(stop chan struct{}) {
 		for {
 			log.Infof("Sending heartbeat for Minion with id %s at location %s", config.ID, config.Location)
-			sendXMLResponse(module.GetID(), config, sink, module.getIdentity(config))
-			time.Sleep(30 * time.Second)
+			err := sendXMLResponse(module.GetID(), config, sink, module.getIdentity(config))
+			module.mutex.Lock()
+			module.sent = true
+			module.err = err
+			module.mutex.Unlock()
+			select {
+			case <-stop:
+				return
+			case <-time.After(30 * time.Second):
+			}
 		}
-	}()
+	}(module.stop)
 	return nil
 }
 
 // Stop shutdowns the sink module
 func (module *HeartbeatModule) Stop() {
 	log.Warnf("Stopping Sink Heartbeat Module")
+	if module.stop != nil {
+		close(module.stop)
+		module.stop = nil
+	}
+}
+
+// CheckReadiness returns an error when the last heartbeat was not delivered
+func (module *HeartbeatModule) CheckReadiness() error {
+	module.mutex.Lock()
+	defer module.mutex.Unlock()
+	if !module.sent {
+		return fmt.Errorf("no heartbeat sent yet")
+	}
+	if module.err != nil {
+		return fmt.Errorf("last heartbeat not delivered: %v", module.err)
+	}
+	return nil
 }
 
 func (module *HeartbeatModule) getIdentity(config *api.MinionConfig) *api.MinionIdentityDTO {
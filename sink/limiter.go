@@ -0,0 +1,224 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterDefaultQueueSize is the default number of messages waiting for the module rate
+const rateLimiterDefaultQueueSize = 100
+
+// rateLimiterSourceIdleTime is the time after which the limiter of an inactive source is discarded
+const rateLimiterSourceIdleTime = time.Minute
+
+// rateLimiterMessage represents a message waiting for the module rate
+type rateLimiterMessage struct {
+	source string
+	send   func()
+}
+
+// sourceLimiter represents the token bucket of a given source address
+type sourceLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter sheds the messages of a Sink module using token buckets per module and per source address.
+// Messages exceeding the source rate are discarded, while the ones exceeding the module rate wait on a bounded queue,
+// discarding either the newest or the oldest messages when it is full.
+// It is safe for concurrent use.
+type rateLimiter struct {
+	minionID    string
+	moduleID    string
+	enabled     bool
+	module      *rate.Limiter // nil when the module rate is not limited
+	sourceRate  rate.Limit    // zero when the source rate is not limited
+	sourceBurst int
+	sources     map[string]*sourceLimiter
+	cleanup     time.Time
+	policy      string
+	queue       []rateLimiterMessage
+	queueSize   int
+	metrics     *api.Metrics
+	wakeup      chan struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+	mutex       *sync.Mutex
+}
+
+// Creates a rate limiter for a given Sink module based on its configuration.
+// The Heartbeat module is never limited.
+func newRateLimiter(moduleID string, config *api.MinionConfig, sink api.Sink) *rateLimiter {
+	l := &rateLimiter{
+		minionID: config.ID,
+		moduleID: moduleID,
+		sources:  make(map[string]*sourceLimiter),
+		mutex:    new(sync.Mutex),
+	}
+	cfg := config.GetRateLimit(moduleID)
+	if cfg == nil || (cfg.Rate <= 0 && cfg.SourceRate <= 0) {
+		return l
+	}
+	if moduleID == heartbeatModuleID {
+		log.Warnf("Ignoring rate limit for %s, the module is never limited", moduleID)
+		return l
+	}
+	l.enabled = true
+	l.policy = cfg.Policy
+	if l.policy == "" {
+		l.policy = api.RateLimitDropNewest
+	}
+	if provider, ok := sink.(api.MetricsProvider); ok {
+		l.metrics = provider.GetMetrics()
+	}
+	if cfg.SourceRate > 0 {
+		l.sourceRate = rate.Limit(cfg.SourceRate)
+		l.sourceBurst = max(int(cfg.SourceRate), 1)
+		if cfg.SourceBurst > 0 {
+			l.sourceBurst = cfg.SourceBurst
+		}
+	}
+	if cfg.Rate > 0 {
+		burst := max(int(cfg.Rate), 1)
+		if cfg.Burst > 0 {
+			burst = cfg.Burst
+		}
+		l.module = rate.NewLimiter(rate.Limit(cfg.Rate), burst)
+		l.queueSize = cfg.QueueSize
+		if l.queueSize == 0 {
+			l.queueSize = rateLimiterDefaultQueueSize
+		}
+		l.wakeup = make(chan struct{}, 1)
+		l.done = make(chan struct{})
+		var ctx context.Context
+		ctx, l.cancel = context.WithCancel(context.Background())
+		go l.process(ctx)
+	}
+	log.Infof("Limiting %s messages to %.2f/sec per module and %.2f/sec per source (0 means unlimited), using %s", moduleID, cfg.Rate, cfg.SourceRate, l.policy)
+	return l
+}
+
+// Sends a message from a given source when allowed by the rate limits; otherwise the message is queued or discarded.
+func (l *rateLimiter) submit(source string, send func()) {
+	if !l.enabled {
+		send()
+		return
+	}
+	now := time.Now()
+	l.mutex.Lock()
+	if !l.allowSource(source, now) {
+		l.mutex.Unlock()
+		l.shed(source)
+		return
+	}
+	if l.module == nil || (len(l.queue) == 0 && l.module.AllowN(now, 1)) {
+		l.mutex.Unlock()
+		send()
+		return
+	}
+	msg := rateLimiterMessage{source: source, send: send}
+	if len(l.queue) < l.queueSize {
+		l.queue = append(l.queue, msg)
+		l.mutex.Unlock()
+		l.notify()
+		return
+	}
+	if l.policy == api.RateLimitDropOldest {
+		oldest := l.queue[0]
+		l.queue = append(l.queue[1:], msg)
+		l.mutex.Unlock()
+		l.shed(oldest.source)
+		return
+	}
+	l.mutex.Unlock()
+	l.shed(source)
+}
+
+// Stops processing the queue, and sends the pending messages regardless of the rate; must be called when the module stops.
+func (l *rateLimiter) stop() {
+	if l.cancel == nil {
+		return
+	}
+	l.cancel()
+	<-l.done
+	l.mutex.Lock()
+	queue := l.queue
+	l.queue = nil
+	l.mutex.Unlock()
+	for _, msg := range queue {
+		msg.send()
+	}
+}
+
+// Checks the token bucket of a given source, discarding the ones of inactive sources. Must be called with the lock held.
+func (l *rateLimiter) allowSource(source string, now time.Time) bool {
+	if l.sourceRate == 0 {
+		return true
+	}
+	if now.Sub(l.cleanup) > rateLimiterSourceIdleTime {
+		for address, s := range l.sources {
+			if now.Sub(s.lastSeen) > rateLimiterSourceIdleTime {
+				delete(l.sources, address)
+			}
+		}
+		l.cleanup = now
+	}
+	s, ok := l.sources[source]
+	if !ok {
+		s = &sourceLimiter{limiter: rate.NewLimiter(l.sourceRate, l.sourceBurst)}
+		l.sources[source] = s
+	}
+	s.lastSeen = now
+	return s.limiter.AllowN(now, 1)
+}
+
+// Sends the queued messages as the module rate allows.
+func (l *rateLimiter) process(ctx context.Context) {
+	defer close(l.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.wakeup:
+		}
+		for {
+			l.mutex.Lock()
+			pending := len(l.queue)
+			l.mutex.Unlock()
+			if pending == 0 {
+				break
+			}
+			if err := l.module.Wait(ctx); err != nil {
+				return
+			}
+			l.mutex.Lock()
+			if len(l.queue) == 0 {
+				l.mutex.Unlock()
+				break
+			}
+			msg := l.queue[0]
+			l.queue = l.queue[1:]
+			l.mutex.Unlock()
+			msg.send()
+		}
+	}
+}
+
+func (l *rateLimiter) notify() {
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (l *rateLimiter) shed(source string) {
+	log.Debugf("Discarding %s message from %s, rate limit exceeded", l.moduleID, source)
+	if l.metrics != nil {
+		l.metrics.SinkMsgShed.WithLabelValues(l.minionID, l.moduleID, source).Inc()
+	}
+}
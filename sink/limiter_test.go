@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"gotest.tools/v3/assert"
+)
+
+type MockMetricsSink struct {
+	MockSink
+	metrics *api.Metrics
+}
+
+func (sink *MockMetricsSink) GetMetrics() *api.Metrics {
+	return sink.metrics
+}
+
+// limiterRecorder records the messages sent through a rate limiter
+type limiterRecorder struct {
+	sent  []int
+	mutex sync.Mutex
+}
+
+func (r *limiterRecorder) submit(l *rateLimiter, source string, id int) {
+	l.submit(source, func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.sent = append(r.sent, id)
+	})
+}
+
+func (r *limiterRecorder) get() []int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]int{}, r.sent...)
+}
+
+func newTestRateLimiter(moduleID string, rateLimit api.RateLimitConfig) (*rateLimiter, *api.Metrics) {
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		RateLimits: map[string]api.RateLimitConfig{moduleID: rateLimit},
+	}
+	metrics := api.NewMetrics()
+	return newRateLimiter(moduleID, config, &MockMetricsSink{metrics: metrics}), metrics
+}
+
+func TestRateLimiterPerSource(t *testing.T) {
+	l, metrics := newTestRateLimiter("Trap", api.RateLimitConfig{SourceRate: 0.1, SourceBurst: 2})
+	defer l.stop()
+	r := new(limiterRecorder)
+	for id := 1; id <= 3; id++ {
+		r.submit(l, "10.0.0.1", id)
+	}
+	r.submit(l, "10.0.0.2", 4)
+	assert.DeepEqual(t, []int{1, 2, 4}, r.get())
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.SinkMsgShed.WithLabelValues("minion1", "Trap", "10.0.0.1")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.SinkMsgShed.WithLabelValues("minion1", "Trap", "10.0.0.2")))
+}
+
+func TestRateLimiterPolicies(t *testing.T) {
+	testCases := []struct {
+		policy string
+		sent   []int
+		shed   float64
+	}{
+		{api.RateLimitDropNewest, []int{1, 2, 3}, 2},
+		{api.RateLimitDropOldest, []int{1, 4, 5}, 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			// The first message is sent, and the rest wait for a token that won't be available during the test
+			l, metrics := newTestRateLimiter("Syslog", api.RateLimitConfig{Rate: 0.1, QueueSize: 2, Policy: tc.policy})
+			r := new(limiterRecorder)
+			for id := 1; id <= 5; id++ {
+				r.submit(l, "10.0.0.1", id)
+			}
+			assert.DeepEqual(t, []int{1}, r.get())
+
+			// Pending messages are sent when stopping
+			l.stop()
+			assert.DeepEqual(t, tc.sent, r.get())
+			assert.Equal(t, tc.shed, testutil.ToFloat64(metrics.SinkMsgShed.WithLabelValues("minion1", "Syslog", "10.0.0.1")))
+		})
+	}
+}
+
+func TestRateLimiterQueue(t *testing.T) {
+	l, _ := newTestRateLimiter("Syslog", api.RateLimitConfig{Rate: 50, Burst: 1})
+	defer l.stop()
+	r := new(limiterRecorder)
+	for id := 1; id <= 3; id++ {
+		r.submit(l, "10.0.0.1", id)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.get()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.DeepEqual(t, []int{1, 2, 3}, r.get())
+}
+
+func TestRateLimiterHeartbeat(t *testing.T) {
+	l, metrics := newTestRateLimiter("Heartbeat", api.RateLimitConfig{Rate: 0.1, SourceRate: 0.1})
+	defer l.stop()
+	r := new(limiterRecorder)
+	for id := 1; id <= 3; id++ {
+		r.submit(l, "minion1", id)
+	}
+	assert.DeepEqual(t, []int{1, 2, 3}, r.get())
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.SinkMsgShed))
+}
@@ -4,21 +4,29 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 	"github.com/agalue/gominion/protobuf/mdt_dialout"
+	"github.com/agalue/gominion/protobuf/telemetry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
 )
 
+// NxosGrpcParser represents org.opennms.netmgt.telemetry.protocols.nxos.adapter.NxosGrpcParser
+const NxosGrpcParser = "NxosGrpcParser"
+
 // NxosGrpcModule represents the Cisco Nexus NX-OS Telemetry module via gRPC
 type NxosGrpcModule struct {
 	mdt_dialout.UnimplementedGRPCMdtDialoutServer
-	sink   api.Sink
-	config *api.MinionConfig
-	server *grpc.Server
-	port   int
+	sink       api.Sink
+	config     *api.MinionConfig
+	server     *grpc.Server
+	port       int
+	aggregator *aggregator[telemetrySource, *telemetry.TelemetryMessage]
+	limiter    *rateLimiter
+	state      *listenerState
 }
 
 // GetID gets the ID of the sink module
@@ -28,7 +36,7 @@ func (module *NxosGrpcModule) GetID() string {
 
 // Start initiates a gRPC Server for NX-OS telemetry
 func (module *NxosGrpcModule) Start(config *api.MinionConfig, sink api.Sink) error {
-	listener := config.GetListenerByParser("NxosGrpcParser")
+	listener := config.GetListenerByParser(NxosGrpcParser)
 	if listener == nil || listener.Port == 0 {
 		log.Warnf("NX-OS Telemetry Module disabled")
 		return nil
@@ -37,6 +45,8 @@ func (module *NxosGrpcModule) Start(config *api.MinionConfig, sink api.Sink) err
 	module.config = config
 	module.sink = sink
 	module.port = listener.Port
+	module.limiter = newRateLimiter(module.GetID(), config, sink)
+	module.aggregator = newTelemetryAggregator(module.GetID(), module.GetID(), config, sink)
 
 	module.server = grpc.NewServer()
 	mdt_dialout.RegisterGRPCMdtDialoutServer(module.server, module)
@@ -46,19 +56,38 @@ func (module *NxosGrpcModule) Start(config *api.MinionConfig, sink api.Sink) err
 	if err != nil {
 		return fmt.Errorf("Error cannot start TCP listener: %s", err)
 	}
-	go func() {
+	module.state = newListenerState("NX-OS gRPC server")
+	module.state.setBound()
+	go func(state *listenerState) {
 		if err := module.server.Serve(lis); err != nil {
 			log.Errorf("Cannot serve NX-OS gRPC: %v", err)
+			state.setError(err)
 		}
-	}()
+	}(module.state)
 	return nil
 }
 
+// CheckReadiness returns an error when the gRPC server cannot serve requests
+func (module *NxosGrpcModule) CheckReadiness() error {
+	return module.state.check()
+}
+
+// RequiresRestart returns true when the listener or the Sink settings of the module changed
+func (module *NxosGrpcModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return !reflect.DeepEqual(current.GetListenerByParser(NxosGrpcParser), updated.GetListenerByParser(NxosGrpcParser)) ||
+		sinkSettingsChanged(module.GetID(), current, updated)
+}
+
 // Stop shutdowns the sink module
 func (module *NxosGrpcModule) Stop() {
 	log.Warnf("Stopping NX-OS telemetry gRPC server")
 	if module.server != nil {
 		module.server.Stop()
+		module.server = nil
+	}
+	if module.aggregator != nil {
+		module.limiter.stop()
+		module.aggregator.stop()
 	}
 }
 
@@ -84,11 +113,8 @@ func (module *NxosGrpcModule) MdtDialout(stream mdt_dialout.GRPCMdtDialout_MdtDi
 			break
 		}
 		log.Debugf("Received request with ID %d of %d bytes from %s", dialoutArgs.ReqId, len(dialoutArgs.Data), ipaddr)
-		messages := make([][]byte, 1)
-		messages[0] = dialoutArgs.Data
-		if bytes := wrapMessageToTelemetry(module.config, ipaddr, uint32(module.port), messages); bytes != nil {
-			sendBytes(module.GetID(), module.config, module.sink, bytes)
-		}
+		source := telemetrySource{address: ipaddr, port: uint32(module.port)}
+		addTelemetryMessages(module.limiter, module.aggregator, source, [][]byte{dialoutArgs.Data})
 	}
 	log.Warnf("Terminating NX-OS handler")
 	return nil
@@ -0,0 +1,219 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/gominion/log"
+	"gopkg.in/mcuadros/go-syslog.v2"
+)
+
+// relpMaxDataSize represents the maximum size of the data of a RELP frame
+const relpMaxDataSize = 128 * 1024
+
+// relpOffers represents the response to the open command of the RELP clients
+const relpOffers = "200 OK\nrelp_version=0\nrelp_software=gominion\ncommands=syslog"
+
+// relpFrame represents a RELP frame: TXNR SP COMMAND SP DATALEN [SP DATA] TRAILER
+type relpFrame struct {
+	txnr    int
+	command string
+	data    []byte
+}
+
+// relpServer receives Syslog messages through RELP (Reliable Event Logging Protocol).
+// Each message is acknowledged after being passed to the handler, so the clients retransmit the ones that were not acknowledged.
+type relpServer struct {
+	listener net.Listener
+	handler  syslog.Handler
+	conns    map[net.Conn]bool
+	closed   bool
+	mutex    sync.Mutex
+	wait     sync.WaitGroup
+}
+
+// Creates a RELP server on a given TCP port, with TLS when a configuration is provided
+func newRELPServer(port int, tlsConfig *tls.Config, handler syslog.Handler) (*relpServer, error) {
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	var listener net.Listener
+	var err error
+	if tlsConfig != nil {
+		listener, err = tls.Listen("tcp", addr, tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot start RELP listener: %v", err)
+	}
+	return &relpServer{
+		listener: listener,
+		handler:  handler,
+		conns:    make(map[net.Conn]bool),
+	}, nil
+}
+
+// Accepts the RELP connections in the background
+func (srv *relpServer) start() {
+	srv.wait.Add(1)
+	go func() {
+		defer srv.wait.Done()
+		for {
+			conn, err := srv.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Errorf("Cannot accept RELP connection: %v", err)
+				}
+				return
+			}
+			srv.mutex.Lock()
+			if srv.closed {
+				srv.mutex.Unlock()
+				conn.Close()
+				return
+			}
+			srv.conns[conn] = true
+			srv.wait.Add(1)
+			srv.mutex.Unlock()
+			go srv.serve(conn)
+		}
+	}()
+}
+
+// Stops accepting connections, notifies the connected clients, and waits for the messages being processed
+func (srv *relpServer) stop() {
+	srv.listener.Close()
+	srv.mutex.Lock()
+	srv.closed = true
+	for conn := range srv.conns {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		conn.Write([]byte("0 serverclose 0\n"))
+		conn.Close()
+	}
+	srv.mutex.Unlock()
+	srv.wait.Wait()
+}
+
+// Processes the RELP commands of a given client until the session is closed
+func (srv *relpServer) serve(conn net.Conn) {
+	defer func() {
+		srv.mutex.Lock()
+		delete(srv.conns, conn)
+		srv.mutex.Unlock()
+		conn.Close()
+		srv.wait.Done()
+	}()
+	client := conn.RemoteAddr().String()
+	tlsPeer := ""
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			log.Warnf("RELP TLS handshake with %s failed: %v", client, err)
+			return
+		}
+		if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+			tlsPeer = certs[0].Subject.CommonName
+		}
+	}
+	reader := bufio.NewReader(conn)
+	opened := false
+	for {
+		frame, err := readRELPFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Warnf("Closing RELP session with %s: %v", client, err)
+			}
+			return
+		}
+		var response string
+		switch {
+		case frame.command == "open":
+			opened = true
+			response = relpOffers
+		case frame.command == "close":
+			srv.respond(conn, frame.txnr, "")
+			return
+		case frame.command == "syslog" && opened:
+			handleSyslogMessage(srv.handler, bytes.TrimRight(frame.data, "\r\n"), client, tlsPeer)
+			response = "200 OK"
+		case !opened:
+			response = "500 session not opened"
+		default:
+			response = "500 unsupported command " + frame.command
+		}
+		if err := srv.respond(conn, frame.txnr, response); err != nil {
+			log.Warnf("Cannot send RELP response to %s: %v", client, err)
+			return
+		}
+	}
+}
+
+// Sends the response for a given transaction
+func (srv *relpServer) respond(conn net.Conn, txnr int, response string) error {
+	frame := fmt.Sprintf("%d rsp %d", txnr, len(response))
+	if response != "" {
+		frame += " " + response
+	}
+	_, err := conn.Write([]byte(frame + "\n"))
+	return err
+}
+
+// Reads a RELP frame
+func readRELPFrame(reader *bufio.Reader) (*relpFrame, error) {
+	readField := func(name string) (string, byte, error) {
+		var field strings.Builder
+		for field.Len() <= 32 {
+			c, err := reader.ReadByte()
+			if err != nil {
+				return "", 0, err
+			}
+			if c == ' ' || c == '\n' {
+				return field.String(), c, nil
+			}
+			field.WriteByte(c)
+		}
+		return "", 0, fmt.Errorf("invalid %s", name)
+	}
+	txnrText, _, err := readField("transaction number")
+	if err != nil {
+		return nil, err
+	}
+	txnr, err := strconv.Atoi(txnrText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction number %q", txnrText)
+	}
+	command, _, err := readField("command")
+	if err != nil {
+		return nil, err
+	}
+	sizeText, separator, err := readField("data length")
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.Atoi(sizeText)
+	if err != nil || size < 0 || size > relpMaxDataSize {
+		return nil, fmt.Errorf("invalid data length %q", sizeText)
+	}
+	frame := &relpFrame{txnr: txnr, command: command}
+	if separator == '\n' {
+		if size > 0 {
+			return nil, fmt.Errorf("missing data for %s", command)
+		}
+		return frame, nil
+	}
+	frame.data = make([]byte, size)
+	if _, err := io.ReadFull(reader, frame.data); err != nil {
+		return nil, err
+	}
+	if trailer, err := reader.ReadByte(); err != nil || trailer != '\n' {
+		return nil, fmt.Errorf("invalid frame trailer for %s", command)
+	}
+	return frame, nil
+}
@@ -0,0 +1,296 @@
+package sink
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"math/big"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/tools"
+	"github.com/gosnmp/gosnmp"
+)
+
+// syslogEntry represents the fields of a Syslog message evaluated by the rules
+type syslogEntry struct {
+	source   net.IP
+	facility int
+	severity int
+	host     string
+	message  string
+}
+
+// Gets the content of the Syslog message, with its priority
+func (entry *syslogEntry) content() string {
+	return fmt.Sprintf("<%d>%s", entry.facility*8+entry.severity, entry.message)
+}
+
+// sinkRule represents a rule with its conditions parsed
+type sinkRule struct {
+	api.SinkRule
+	source   *net.IPNet
+	varbinds map[string]*regexp.Regexp
+	facility int
+	severity int
+	host     *regexp.Regexp
+	message  *regexp.Regexp
+}
+
+// ruleEngine drops, rewrites or tags the messages of a Sink module based on the configured rules, evaluated in order.
+// A message is discarded by the first drop rule it matches; otherwise, the changes of all the matching rules are applied.
+// A nil engine accepts all the messages unchanged.
+type ruleEngine struct {
+	moduleID string
+	minionID string
+	rules    []*sinkRule
+	metrics  *api.Metrics
+}
+
+// Creates a rule engine for a given Sink module
+func newRuleEngine(moduleID string, config *api.MinionConfig, sink api.Sink) (*ruleEngine, error) {
+	engine := &ruleEngine{moduleID: moduleID, minionID: config.ID}
+	if provider, ok := sink.(api.MetricsProvider); ok {
+		engine.metrics = provider.GetMetrics()
+	}
+	for _, cfg := range config.GetRules(moduleID) {
+		if err := cfg.IsValid(moduleID); err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %v", cfg.Name, err)
+		}
+		rule := &sinkRule{SinkRule: cfg, varbinds: make(map[string]*regexp.Regexp)}
+		if cfg.Match.Source != "" {
+			rule.source, _ = api.ParseSource(cfg.Match.Source)
+		}
+		for oid, expr := range cfg.Match.Varbinds {
+			rule.varbinds[normalizeOID(oid)] = regexp.MustCompile(expr)
+		}
+		rule.facility, _ = api.ParseSyslogCode(cfg.Match.Facility, api.SyslogFacilities)
+		rule.severity, _ = api.ParseSyslogCode(cfg.Match.Severity, api.SyslogSeverities)
+		if cfg.Match.Host != "" {
+			rule.host = regexp.MustCompile(cfg.Match.Host)
+		}
+		if cfg.Match.Message != "" {
+			rule.message = regexp.MustCompile(cfg.Match.Message)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	if len(engine.rules) > 0 {
+		log.Infof("Loaded %d rules for the %s module", len(engine.rules), moduleID)
+	}
+	return engine, nil
+}
+
+// Applies the rules to a given trap; returns false when it has to be dropped
+func (engine *ruleEngine) applyTrap(source net.IP, trap *api.TrapDTO) bool {
+	if engine == nil {
+		return true
+	}
+	for _, rule := range engine.rules {
+		if !rule.matchesTrap(source, trap) {
+			continue
+		}
+		engine.matched(rule)
+		switch rule.Action {
+		case api.RuleActionDrop:
+			log.Debugf("Dropping SNMP trap from %s by rule %s", source, rule.Name)
+			return false
+		case api.RuleActionRewrite:
+			rewriteTrap(trap, rule.Set)
+		case api.RuleActionTag:
+			for _, oid := range slices.Sorted(maps.Keys(rule.Tags)) {
+				trap.AddResult(buildTrapResult(normalizeOID(oid), rule.Tags[oid], int(gosnmp.OctetString)))
+			}
+		}
+	}
+	return true
+}
+
+// Applies the rules to a given Syslog message; returns false when it has to be dropped
+func (engine *ruleEngine) applySyslog(entry *syslogEntry) bool {
+	if engine == nil {
+		return true
+	}
+	for _, rule := range engine.rules {
+		if !rule.matchesSyslog(entry) {
+			continue
+		}
+		engine.matched(rule)
+		switch rule.Action {
+		case api.RuleActionDrop:
+			log.Debugf("Dropping Syslog message from %s by rule %s", entry.source, rule.Name)
+			return false
+		case api.RuleActionRewrite:
+			rule.rewriteSyslog(entry)
+		case api.RuleActionTag:
+			for _, tag := range slices.Sorted(maps.Keys(rule.Tags)) {
+				entry.message += fmt.Sprintf(" %s=%q", tag, rule.Tags[tag])
+			}
+		}
+	}
+	return true
+}
+
+func (engine *ruleEngine) matched(rule *sinkRule) {
+	if engine.metrics != nil {
+		engine.metrics.SinkRuleMatches.WithLabelValues(engine.minionID, engine.moduleID, rule.Name, rule.Action).Inc()
+	}
+}
+
+// Returns true when a given trap matches all the conditions of the rule
+func (rule *sinkRule) matchesTrap(source net.IP, trap *api.TrapDTO) bool {
+	if rule.source != nil && !rule.source.Contains(source) {
+		return false
+	}
+	match := rule.Match
+	if match.EnterpriseID != "" || match.Generic != nil || match.Specific != nil {
+		identity := trap.TrapIdentity
+		if identity == nil {
+			return false
+		}
+		if match.EnterpriseID != "" && !isOIDUnder(normalizeOID(identity.EnterpriseID), normalizeOID(match.EnterpriseID)) {
+			return false
+		}
+		if match.Generic != nil && *match.Generic != identity.Generic {
+			return false
+		}
+		if match.Specific != nil && *match.Specific != identity.Specific {
+			return false
+		}
+	}
+	for oid, expr := range rule.varbinds {
+		if trap.Results == nil || !slices.ContainsFunc(trap.Results.Results, func(result api.SNMPResultDTO) bool {
+			return isOIDUnder(result.Base+result.Instance, oid) && expr.MatchString(getTrapResultValue(result))
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true when a given Syslog message matches all the conditions of the rule
+func (rule *sinkRule) matchesSyslog(entry *syslogEntry) bool {
+	if rule.source != nil && !rule.source.Contains(entry.source) {
+		return false
+	}
+	if rule.facility >= 0 && rule.facility != entry.facility {
+		return false
+	}
+	if rule.severity >= 0 && rule.severity != entry.severity {
+		return false
+	}
+	if rule.host != nil && !rule.host.MatchString(entry.host) {
+		return false
+	}
+	if rule.message != nil && !rule.message.MatchString(entry.message) {
+		return false
+	}
+	return true
+}
+
+// Replaces the fields of a Syslog message; the message can refer to the groups of the message expression, like ${1}
+func (rule *sinkRule) rewriteSyslog(entry *syslogEntry) {
+	for field, value := range rule.Set {
+		switch strings.ToLower(field) {
+		case "facility":
+			entry.facility, _ = api.ParseSyslogCode(value, api.SyslogFacilities)
+		case "severity":
+			entry.severity, _ = api.ParseSyslogCode(value, api.SyslogSeverities)
+		case "message":
+			if rule.message != nil {
+				entry.message = rule.message.ReplaceAllString(entry.message, value)
+			} else {
+				entry.message = value
+			}
+		}
+	}
+}
+
+// Replaces the fields or the varbind values of a trap
+func rewriteTrap(trap *api.TrapDTO, fields map[string]string) {
+	identity := func() *api.TrapIdentityDTO {
+		if trap.TrapIdentity == nil {
+			trap.TrapIdentity = &api.TrapIdentityDTO{}
+		}
+		return trap.TrapIdentity
+	}
+	for field, value := range fields {
+		switch strings.ToLower(field) {
+		case "agentaddress":
+			trap.AgentAddress = value
+		case "community":
+			trap.Community = value
+		case "enterpriseid":
+			identity().EnterpriseID = normalizeOID(value)
+		case "generic":
+			identity().Generic, _ = strconv.Atoi(value)
+		case "specific":
+			identity().Specific, _ = strconv.Atoi(value)
+		default:
+			if trap.Results == nil {
+				continue
+			}
+			oid := normalizeOID(field)
+			for i, result := range trap.Results.Results {
+				if result.Base+result.Instance == oid {
+					trap.Results.Results[i] = buildTrapResult(oid, value, result.Value.Type)
+				}
+			}
+		}
+	}
+}
+
+// Builds a trap varbind with a given value, as an octet string when the value cannot be represented with the given type
+func buildTrapResult(oid string, value string, valueType int) api.SNMPResultDTO {
+	data := []byte(value)
+	switch gosnmp.Asn1BER(valueType) {
+	case gosnmp.OctetString, gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+	default:
+		if number, ok := new(big.Int).SetString(value, 10); ok && number.Sign() >= 0 {
+			data = tools.BytesToJavaBigIntegerBytes(number.Bytes())
+		} else {
+			valueType = int(gosnmp.OctetString)
+		}
+	}
+	return api.SNMPResultDTO{
+		Base: oid,
+		Value: api.SNMPValueDTO{
+			Type:  valueType,
+			Value: base64.StdEncoding.EncodeToString(data),
+		},
+	}
+}
+
+// Gets the value of a trap varbind as a string
+func getTrapResultValue(result api.SNMPResultDTO) string {
+	data, err := base64.StdEncoding.DecodeString(result.Value.Value)
+	if err != nil {
+		return ""
+	}
+	switch gosnmp.Asn1BER(result.Value.Type) {
+	case gosnmp.OctetString, gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+		return string(data)
+	}
+	number := new(big.Int).SetBytes(data)
+	if len(data) > 0 && data[0]&0x80 != 0 { // Negative, in two's complement like Java's BigInteger
+		number.Sub(number, new(big.Int).Lsh(big.NewInt(1), uint(8*len(data))))
+	}
+	return number.String()
+}
+
+// Adds the leading dot to a given OID
+func normalizeOID(oid string) string {
+	if strings.HasPrefix(oid, ".") {
+		return oid
+	}
+	return "." + oid
+}
+
+// Returns true when a given OID is equal to or under a given parent OID
+func isOIDUnder(oid string, parent string) bool {
+	return oid == parent || strings.HasPrefix(oid, parent+".")
+}
@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/tools"
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"gotest.tools/v3/assert"
+)
+
+func buildTestRuleTrap(specific int, ifName string) *api.TrapDTO {
+	trap := &api.TrapDTO{
+		AgentAddress: "10.0.0.1",
+		Community:    "public",
+		Version:      "v2c",
+		TrapIdentity: &api.TrapIdentityDTO{EnterpriseID: ".1.3.6.1.6.3.1.1.5", Generic: 6, Specific: specific},
+	}
+	trap.AddResult(tools.GetResultForPDU(gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.1.5", Type: gosnmp.Integer, Value: 5}, ".1.3.6.1.2.1.2.2.1.1.5"))
+	trap.AddResult(tools.GetResultForPDU(gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.1.5", Type: gosnmp.OctetString, Value: []byte(ifName)}, ".1.3.6.1.2.1.31.1.1.1.1.5"))
+	return trap
+}
+
+func TestTrapRules(t *testing.T) {
+	linkDown := 3
+	config := &api.MinionConfig{
+		ID: "minion1",
+		Rules: map[string][]api.SinkRule{
+			"trap": {
+				{
+					Name:   "access-ports",
+					Match:  api.SinkRuleMatch{Source: "10.0.0.0/24", EnterpriseID: "1.3.6.1.6.3.1.1", Specific: &linkDown, Varbinds: map[string]string{".1.3.6.1.2.1.31.1.1.1.1": "^Gi1/0/"}},
+					Action: api.RuleActionDrop,
+				},
+				{
+					Name:   "uplinks",
+					Match:  api.SinkRuleMatch{Varbinds: map[string]string{".1.3.6.1.2.1.2.2.1.1": "^[1-9]$"}},
+					Action: api.RuleActionRewrite,
+					Set:    map[string]string{"community": "edge", ".1.3.6.1.2.1.2.2.1.1.5": "105"},
+				},
+				{
+					Name:   "site",
+					Match:  api.SinkRuleMatch{Source: "10.0.0.1"},
+					Action: api.RuleActionTag,
+					Tags:   map[string]string{".1.3.6.1.4.1.5813.20.1.1": "site-a"},
+				},
+			},
+		},
+	}
+	sink := &MockMetricsSink{metrics: api.NewMetrics()}
+	engine, err := newRuleEngine("Trap", config, sink)
+	assert.NilError(t, err)
+	source := net.ParseIP("10.0.0.1")
+
+	// Link down for an access port
+	assert.Assert(t, !engine.applyTrap(source, buildTestRuleTrap(3, "Gi1/0/24")))
+
+	// Link up for an access port, from another network
+	trap := buildTestRuleTrap(4, "Gi1/0/24")
+	assert.Assert(t, engine.applyTrap(net.ParseIP("10.0.1.1"), trap))
+	assert.Equal(t, "edge", trap.Community)
+	assert.Equal(t, "105", getTrapResultValue(trap.Results.Results[0]))
+	assert.Equal(t, int(gosnmp.Integer), trap.Results.Results[0].Value.Type)
+	assert.Equal(t, 2, len(trap.Results.Results))
+
+	// Link down for an uplink
+	trap = buildTestRuleTrap(3, "Te1/1/1")
+	assert.Assert(t, engine.applyTrap(source, trap))
+	assert.Equal(t, 3, len(trap.Results.Results))
+	tag := trap.Results.Results[2]
+	assert.Equal(t, ".1.3.6.1.4.1.5813.20.1.1", tag.Base)
+	assert.Equal(t, "site-a", getTrapResultValue(tag))
+
+	matches := sink.metrics.SinkRuleMatches
+	assert.Equal(t, 1.0, testutil.ToFloat64(matches.WithLabelValues("minion1", "Trap", "access-ports", "drop")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(matches.WithLabelValues("minion1", "Trap", "uplinks", "rewrite")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(matches.WithLabelValues("minion1", "Trap", "site", "tag")))
+
+	// Invalid rules are rejected
+	config.Rules["trap"][0].Action = "discard"
+	_, err = newRuleEngine("Trap", config, sink)
+	assert.ErrorContains(t, err, "invalid rule access-ports: invalid action discard")
+}
+
+func TestSyslogRules(t *testing.T) {
+	config := &api.MinionConfig{
+		ID: "minion1",
+		Rules: map[string][]api.SinkRule{
+			"Syslog": {
+				{Name: "debug", Match: api.SinkRuleMatch{Severity: "debug"}, Action: api.RuleActionDrop},
+				{Name: "flaps", Match: api.SinkRuleMatch{Facility: "local7", Host: "^sw-", Message: `Interface (\S+) is down`}, Action: api.RuleActionRewrite, Set: map[string]string{"severity": "warning", "message": "Interface ${1} went down"}},
+				{Name: "dc", Match: api.SinkRuleMatch{Source: "192.168.0.0/16"}, Action: api.RuleActionTag, Tags: map[string]string{"site": "dc1"}},
+			},
+		},
+	}
+	engine, err := newRuleEngine("Syslog", config, nil)
+	assert.NilError(t, err)
+
+	entry := &syslogEntry{source: net.ParseIP("192.168.1.1"), facility: 23, severity: 7, host: "sw-01", message: "debugging"}
+	assert.Assert(t, !engine.applySyslog(entry))
+
+	entry = &syslogEntry{source: net.ParseIP("192.168.1.1"), facility: 23, severity: 5, host: "sw-01", message: "%ETHPORT-5-IF_DOWN_LINK_FAILURE: Interface eth1 is down (Link failure)"}
+	assert.Assert(t, engine.applySyslog(entry))
+	assert.Equal(t, `<188>%ETHPORT-5-IF_DOWN_LINK_FAILURE: Interface eth1 went down (Link failure) site="dc1"`, entry.content())
+
+	entry = &syslogEntry{source: net.ParseIP("10.0.0.1"), facility: 23, severity: 5, host: "rtr-01", message: "Interface eth1 is down"}
+	assert.Assert(t, engine.applySyslog(entry))
+	assert.Equal(t, "<189>Interface eth1 is down", entry.content())
+
+	// The rules are applied to the messages received by the module
+	module := &SyslogModule{config: config, rules: engine}
+	logParts := map[string]interface{}{
+		"client":    "192.168.1.1:64557",
+		"content":   "debugging",
+		"hostname":  "sw-01",
+		"priority":  191,
+		"timestamp": time.Now(),
+	}
+	assert.Assert(t, module.buildMessageLog(logParts) == nil)
+}
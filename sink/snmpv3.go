@@ -0,0 +1,255 @@
+package sink
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/gosnmp/gosnmp"
+)
+
+// usmStatsUnknownEngineIDsOID represents the counter reported to the agents discovering the local engine ID (RFC 3414)
+const usmStatsUnknownEngineIDsOID = ".1.3.6.1.6.3.15.1.1.4.0"
+
+// openNMSEnterpriseID represents the OpenNMS private enterprise number, used for the default local engine ID
+const openNMSEnterpriseID = 5813
+
+// Reasons why a trap cannot be decoded, used as the label of the decoding errors metric
+const (
+	trapErrorDecode      = "decode"       // Malformed message
+	trapErrorUnknownUser = "unknown_user" // SNMPv3 user not configured
+	trapErrorAuth        = "auth_failure" // SNMPv3 message that cannot be authenticated or decrypted
+	trapErrorNotAllowed  = "not_allowed"  // Engine ID or security level not allowed for the SNMPv3 user
+)
+
+// trapUnknownUser represents the user label of the decoding errors metric for the messages without a configured SNMPv3 user,
+// as the user names are chosen by the senders
+const trapUnknownUser = "unknown"
+
+// snmpV3Message represents an SNMPv3 message (RFC 3412), parsed to identify its user before decoding it
+type snmpV3Message struct {
+	Version            int
+	GlobalData         snmpV3GlobalData
+	SecurityParameters []byte
+	ScopedPDU          asn1.RawValue
+}
+
+// snmpV3GlobalData represents the header data of an SNMPv3 message
+type snmpV3GlobalData struct {
+	ID            int
+	MaxSize       int
+	Flags         []byte
+	SecurityModel int
+}
+
+// usmParameters represents the security parameters of an SNMPv3 message (RFC 3414)
+type usmParameters struct {
+	EngineID       []byte
+	EngineBoots    int
+	EngineTime     int
+	UserName       []byte
+	AuthParameters []byte
+	PrivParameters []byte
+}
+
+// usmUser represents the restrictions of a configured USM user
+type usmUser struct {
+	engineID string                // The remote engine ID, or empty to accept any
+	level    gosnmp.SnmpV3MsgFlags // The minimum security level
+}
+
+// trapDecoder decodes SNMPv1, SNMPv2c and SNMPv3 traps and informs, authenticating the SNMPv3 ones with the configured USM users
+type trapDecoder struct {
+	params           *gosnmp.GoSNMP
+	engineID         string // The local engine ID
+	users            map[string][]usmUser
+	unknownEngineIDs atomic.Uint32
+	started          time.Time
+}
+
+// Creates a trap decoder for the USM users of a given configuration
+func newTrapDecoder(config *api.MinionConfig) (*trapDecoder, error) {
+	engineID := getDefaultEngineID(config.ID)
+	var users []api.SnmpV3User
+	if config.Traps != nil {
+		if config.Traps.EngineID != "" {
+			id, err := api.ParseEngineID(config.Traps.EngineID)
+			if err != nil {
+				return nil, err
+			}
+			engineID = id
+		}
+		users = config.Traps.Users
+	}
+	decoder := &trapDecoder{
+		engineID: string(engineID),
+		users:    make(map[string][]usmUser),
+		started:  time.Now(),
+		params: &gosnmp.GoSNMP{
+			Version:            gosnmp.Version3,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{AuthoritativeEngineID: string(engineID)},
+		},
+	}
+	if len(users) > 0 {
+		decoder.params.TrapSecurityParametersTable = gosnmp.NewSnmpV3SecurityParametersTable(gosnmp.Logger{})
+	}
+	for _, user := range users {
+		if err := user.IsValid(); err != nil {
+			return nil, fmt.Errorf("invalid SNMPv3 user %s: %v", user.SecurityName, err)
+		}
+		params := &gosnmp.UsmSecurityParameters{
+			UserName:                 user.SecurityName,
+			AuthenticationProtocol:   getAuthProtocol(user.AuthProtocol),
+			AuthenticationPassphrase: user.AuthPassphrase,
+			PrivacyProtocol:          getPrivProtocol(user.PrivacyProtocol),
+			PrivacyPassphrase:        user.PrivacyPassphrase,
+		}
+		restriction := usmUser{level: gosnmp.NoAuthNoPriv}
+		if user.EngineID != "" {
+			id, _ := api.ParseEngineID(user.EngineID)
+			params.AuthoritativeEngineID = string(id)
+			restriction.engineID = string(id)
+		}
+		if params.AuthenticationProtocol != gosnmp.NoAuth {
+			restriction.level = gosnmp.AuthNoPriv
+		}
+		if params.PrivacyProtocol != gosnmp.NoPriv {
+			restriction.level = gosnmp.AuthPriv
+		}
+		if err := decoder.params.TrapSecurityParametersTable.Add(user.SecurityName, params); err != nil {
+			return nil, fmt.Errorf("invalid SNMPv3 user %s: %v", user.SecurityName, err)
+		}
+		decoder.users[user.SecurityName] = append(decoder.users[user.SecurityName], restriction)
+	}
+	return decoder, nil
+}
+
+// Decodes a given message; when it fails, returns the configured SNMPv3 user (or trapUnknownUser) and the reason
+func (d *trapDecoder) decode(msg []byte) (*gosnmp.SnmpPacket, string, string, error) {
+	header, usm, err := parseSnmpV3Message(msg)
+	if err != nil {
+		packet, err := d.params.UnmarshalTrap(msg, false)
+		if err != nil {
+			return nil, trapUnknownUser, trapErrorDecode, err
+		}
+		return packet, "", "", nil
+	}
+	userName := string(usm.UserName)
+	users, ok := d.users[userName]
+	if !ok {
+		return nil, trapUnknownUser, trapErrorUnknownUser, fmt.Errorf("unknown SNMPv3 user %q", userName)
+	}
+	packet, err := d.params.UnmarshalTrap(msg, false)
+	if err != nil {
+		return nil, userName, trapErrorAuth, fmt.Errorf("invalid message for SNMPv3 user %s: %v", userName, err)
+	}
+	level := gosnmp.SnmpV3MsgFlags(header.GlobalData.Flags[0]) & gosnmp.AuthPriv
+	engineID := string(usm.EngineID)
+	for _, user := range users {
+		engineMatches := user.engineID == "" || user.engineID == engineID || engineID == d.engineID
+		if engineMatches && level >= user.level {
+			return packet, "", "", nil
+		}
+	}
+	return nil, userName, trapErrorNotAllowed, fmt.Errorf("engine ID %x or security level %s not allowed for SNMPv3 user %s", usm.EngineID, level, userName)
+}
+
+// Returns the report with the local engine ID for the messages of agents performing engine discovery (RFC 3414 section 4), or nil for any other message
+func (d *trapDecoder) getDiscoveryReport(msg []byte) ([]byte, error) {
+	header, usm, err := parseSnmpV3Message(msg)
+	if err != nil || gosnmp.SnmpV3MsgFlags(header.GlobalData.Flags[0])&gosnmp.Reportable == 0 || len(usm.EngineID) >= 5 {
+		return nil, nil
+	}
+	probe := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{},
+	}
+	packet, err := probe.UnmarshalTrap(msg, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine discovery request: %v", err)
+	}
+	packet.PDUType = gosnmp.Report
+	packet.MsgFlags = gosnmp.NoAuthNoPriv
+	packet.ContextEngineID = d.engineID
+	packet.SecurityParameters = &gosnmp.UsmSecurityParameters{
+		AuthoritativeEngineID:    d.engineID,
+		AuthoritativeEngineBoots: 1,
+		AuthoritativeEngineTime:  uint32(time.Since(d.started).Seconds()),
+		UserName:                 string(usm.UserName),
+	}
+	packet.Variables = []gosnmp.SnmpPDU{
+		{Name: usmStatsUnknownEngineIDsOID, Type: gosnmp.Counter32, Value: d.unknownEngineIDs.Add(1)},
+	}
+	return packet.MarshalMsg()
+}
+
+// Parses the header and the USM security parameters of an SNMPv3 message; fails for other versions
+func parseSnmpV3Message(msg []byte) (*snmpV3Message, *usmParameters, error) {
+	header := &snmpV3Message{}
+	if _, err := asn1.Unmarshal(msg, header); err != nil {
+		return nil, nil, err
+	}
+	if header.Version != int(gosnmp.Version3) || header.GlobalData.SecurityModel != int(gosnmp.UserSecurityModel) || len(header.GlobalData.Flags) != 1 {
+		return nil, nil, fmt.Errorf("not an SNMPv3 USM message")
+	}
+	usm := &usmParameters{}
+	if _, err := asn1.Unmarshal(header.SecurityParameters, usm); err != nil {
+		return nil, nil, err
+	}
+	return header, usm, nil
+}
+
+// Gets the default local engine ID, based on the Minion ID as administratively assigned text (RFC 3411)
+func getDefaultEngineID(minionID string) []byte {
+	engineID := []byte{0x80, 0x00, byte(openNMSEnterpriseID >> 8), byte(openNMSEnterpriseID & 0xff), 0x04}
+	text := minionID
+	if text == "" {
+		text = "gominion"
+	}
+	if len(text) > 27 {
+		text = text[:27]
+	}
+	return append(engineID, text...)
+}
+
+func getAuthProtocol(protocol string) gosnmp.SnmpV3AuthProtocol {
+	switch strings.ToUpper(protocol) {
+	case "MD5":
+		return gosnmp.MD5
+	case "SHA":
+		return gosnmp.SHA
+	case "SHA-224":
+		return gosnmp.SHA224
+	case "SHA-256":
+		return gosnmp.SHA256
+	case "SHA-384":
+		return gosnmp.SHA384
+	case "SHA-512":
+		return gosnmp.SHA512
+	}
+	return gosnmp.NoAuth
+}
+
+func getPrivProtocol(protocol string) gosnmp.SnmpV3PrivProtocol {
+	switch strings.ToUpper(protocol) {
+	case "DES":
+		return gosnmp.DES
+	case "AES":
+		return gosnmp.AES
+	case "AES-192":
+		return gosnmp.AES192
+	case "AES-256":
+		return gosnmp.AES256
+	case "AES-192C":
+		return gosnmp.AES192C
+	case "AES-256C":
+		return gosnmp.AES256C
+	}
+	return gosnmp.NoPriv
+}
@@ -0,0 +1,71 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/agalue/gominion/log"
+	"gopkg.in/mcuadros/go-syslog.v2"
+)
+
+// syslogUDPReadBufferSize represents the maximum size of a Syslog datagram
+const syslogUDPReadBufferSize = 64 * 1024
+
+// syslogUDPServer receives Syslog messages through UDP.
+// The go-syslog server is not used for UDP, as it can pass a datagram to a closed channel while it is being killed.
+type syslogUDPServer struct {
+	conn    net.PacketConn
+	handler syslog.Handler
+	wait    sync.WaitGroup
+}
+
+// Creates a Syslog server on a given UDP port
+func newSyslogUDPServer(port int, handler syslog.Handler) (*syslogUDPServer, error) {
+	conn, err := net.ListenPacket("udp", fmt.Sprintf("0.0.0.0:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("cannot start Syslog UDP listener: %v", err)
+	}
+	if udpConn, ok := conn.(*net.UDPConn); ok {
+		udpConn.SetReadBuffer(syslogUDPReadBufferSize)
+	}
+	return &syslogUDPServer{conn: conn, handler: handler}, nil
+}
+
+// Receives the Syslog datagrams in the background
+func (srv *syslogUDPServer) start() {
+	srv.wait.Add(1)
+	go func() {
+		defer srv.wait.Done()
+		buffer := make([]byte, syslogUDPReadBufferSize)
+		for {
+			n, addr, err := srv.conn.ReadFrom(buffer)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				log.Warnf("Cannot read Syslog datagram: %v", err)
+				continue
+			}
+			// Ignore trailing control characters and NULs, like the go-syslog server does
+			for n > 0 && buffer[n-1] < 32 {
+				n--
+			}
+			if n == 0 {
+				continue
+			}
+			_, data, err := syslog.Automatic.GetSplitFunc()(buffer[:n], true)
+			if err != nil || data == nil {
+				continue
+			}
+			handleSyslogMessage(srv.handler, data, addr.String(), "")
+		}
+	}()
+}
+
+// Stops receiving datagrams, and waits for the message being processed
+func (srv *syslogUDPServer) stop() {
+	srv.conn.Close()
+	srv.wait.Wait()
+}
@@ -1,8 +1,11 @@
 package sink
 
 import (
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -10,14 +13,55 @@ import (
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
 	"gopkg.in/mcuadros/go-syslog.v2"
+	"gopkg.in/mcuadros/go-syslog.v2/format"
 )
 
-// SyslogModule represents the heartbeat module
+// SyslogModule represents the Syslog receiver module
 type SyslogModule struct {
-	sink    api.Sink
-	config  *api.MinionConfig
-	server  *syslog.Server
+	sink       api.Sink
+	config     *api.MinionConfig
+	server     *syslog.Server
+	handler    *syslogHandler
+	stopped    chan struct{}
+	aggregator *aggregator[syslogSource, api.SyslogMessageDTO]
+	limiter    *rateLimiter
+	rules      *ruleEngine
+	udp        *syslogUDPServer
+	relp       *relpServer
+}
+
+// syslogSource represents the source of a Syslog message
+type syslogSource struct {
+	address string
+	port    int
+}
+
+// syslogHandler passes the messages received by the Syslog servers to the module until it stops.
+// The channel is never closed, as the go-syslog server keeps passing the messages being parsed after it has been killed.
+type syslogHandler struct {
 	channel syslog.LogPartsChannel
+	done    chan struct{}
+}
+
+// Handle passes a given message to the module, or discards it when the module has stopped
+func (h *syslogHandler) Handle(logParts format.LogParts, msgLen int64, err error) {
+	select {
+	case h.channel <- logParts:
+	case <-h.done:
+	}
+}
+
+// Parses a given Syslog message and passes it to the handler, like the go-syslog server does
+func handleSyslogMessage(handler syslog.Handler, data []byte, client string, tlsPeer string) {
+	parser := syslog.Automatic.GetParser(data)
+	err := parser.Parse()
+	logParts := parser.Dump()
+	logParts["client"] = client
+	if logParts["hostname"] == "" {
+		logParts["hostname"], _, _ = net.SplitHostPort(client)
+	}
+	logParts["tls_peer"] = tlsPeer
+	handler.Handle(logParts, int64(len(data)), err)
 }
 
 // GetID gets the ID of the sink module
@@ -25,53 +69,144 @@ func (module *SyslogModule) GetID() string {
 	return "Syslog"
 }
 
-// Start initiates a Syslog UDP and TCP receiver
+// Start initiates the Syslog UDP, TCP, TLS and RELP receivers
 func (module *SyslogModule) Start(config *api.MinionConfig, sink api.Sink) error {
-	if config.SyslogPort == 0 {
+	settings := config.Syslog
+	if settings == nil {
+		settings = &api.SyslogConfig{}
+	}
+	if config.SyslogPort == 0 && settings.TLSPort == 0 && settings.RELPPort == 0 {
 		log.Warnf("Syslog Module disabled")
 		return nil
 	}
 
-	log.Infof("Starting Syslog receiver on port UDP/TCP %d", config.SyslogPort)
-
+	var tlsConfig *tls.Config
+	if settings.IsTLSEnabled() {
+		if err := settings.IsValid(); err != nil {
+			return fmt.Errorf("invalid Syslog listeners: %v", err)
+		}
+		var err error
+		if tlsConfig, err = newServerTLSConfig(settings.CertFile, settings.KeyFile, settings.ClientCAFile); err != nil {
+			return err
+		}
+	}
+	rules, err := newRuleEngine(module.GetID(), config, sink)
+	if err != nil {
+		return err
+	}
 	module.config = config
 	module.sink = sink
+	module.rules = rules
+	module.limiter = newRateLimiter(module.GetID(), config, sink)
+	module.aggregator = newAggregator(module.GetID(), config, module.sendMessages)
 
-	listenAddr := fmt.Sprintf("0.0.0.0:%d", config.SyslogPort)
-	module.channel = make(syslog.LogPartsChannel)
+	handler := &syslogHandler{channel: make(syslog.LogPartsChannel), done: make(chan struct{})}
+	module.handler = handler
+	module.stopped = make(chan struct{})
+	go func() {
+		defer close(module.stopped)
+		for {
+			select {
+			case logParts := <-handler.channel:
+				if messageLog := module.buildMessageLog(logParts); messageLog != nil {
+					source := syslogSource{address: messageLog.SourceAddress, port: messageLog.SourcePort}
+					for _, message := range messageLog.Messages {
+						module.limiter.submit(source.address, func() {
+							module.aggregator.add(source, message)
+						})
+					}
+				}
+			case <-handler.done:
+				return
+			}
+		}
+	}()
 	module.server = syslog.NewServer()
 	module.server.SetFormat(syslog.Automatic)
-	module.server.SetHandler(syslog.NewChannelHandler(module.channel))
-	if err := module.server.ListenUDP(listenAddr); err != nil {
-		return fmt.Errorf("cannot start Syslog UDP listener: %s", err)
+	module.server.SetHandler(handler)
+	if config.SyslogPort > 0 {
+		log.Infof("Starting Syslog receiver on port UDP/TCP %d", config.SyslogPort)
+		listenAddr := fmt.Sprintf("0.0.0.0:%d", config.SyslogPort)
+		if module.udp, err = newSyslogUDPServer(config.SyslogPort, handler); err != nil {
+			return err
+		}
+		if err := module.server.ListenTCP(listenAddr); err != nil {
+			return fmt.Errorf("cannot start Syslog TCP listener: %s", err)
+		}
 	}
-	if err := module.server.ListenTCP(listenAddr); err != nil {
-		return fmt.Errorf("cannot start Syslog TCP listener: %s", err)
+	if settings.TLSPort > 0 {
+		log.Infof("Starting Syslog receiver on port TCP %d with TLS", settings.TLSPort)
+		if err := module.server.ListenTCPTLS(fmt.Sprintf("0.0.0.0:%d", settings.TLSPort), tlsConfig); err != nil {
+			return fmt.Errorf("cannot start Syslog TLS listener: %s", err)
+		}
+	}
+	if settings.RELPPort > 0 {
+		var relpTLSConfig *tls.Config
+		if settings.RELPTLS {
+			relpTLSConfig = tlsConfig
+		}
+		log.Infof("Starting Syslog RELP receiver on port TCP %d (TLS: %t)", settings.RELPPort, settings.RELPTLS)
+		if module.relp, err = newRELPServer(settings.RELPPort, relpTLSConfig, handler); err != nil {
+			return err
+		}
 	}
 	if err := module.server.Boot(); err != nil {
 		return fmt.Errorf("cannot boot Syslog server: %s", err)
 	}
-	go func(channel syslog.LogPartsChannel) {
-		for logParts := range channel {
-			if messageLog := module.buildMessageLog(logParts); messageLog != nil {
-				sendXMLResponse(module.GetID(), module.config, module.sink, messageLog)
-			}
-		}
-	}(module.channel)
+	if module.udp != nil {
+		module.udp.start()
+	}
+	if module.relp != nil {
+		module.relp.start()
+	}
 	return nil
 }
 
 // Stop shutdowns the sink module
 func (module *SyslogModule) Stop() {
 	log.Warnf("Stopping Syslog receiver")
+	if module.relp != nil {
+		module.relp.stop()
+		module.relp = nil
+	}
+	if module.udp != nil {
+		module.udp.stop()
+		module.udp = nil
+	}
 	if module.server != nil {
-		close(module.channel)
 		module.server.Kill()
+		close(module.handler.done)
+		<-module.stopped
+		module.server = nil
+	}
+	if module.aggregator != nil {
+		module.limiter.stop()
+		module.aggregator.stop()
 	}
 }
 
+// RequiresRestart returns true when the Syslog ports, the Syslog listener settings or the Sink settings of the module changed
+func (module *SyslogModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return current.SyslogPort != updated.SyslogPort || !reflect.DeepEqual(current.Syslog, updated.Syslog) || sinkSettingsChanged(module.GetID(), current, updated)
+}
+
+func (module *SyslogModule) sendMessages(source syslogSource, messages []api.SyslogMessageDTO) {
+	messageLog := &api.SyslogMessageLogDTO{
+		Location:      module.config.Location,
+		SystemID:      module.config.ID,
+		SourceAddress: source.address,
+		SourcePort:    source.port,
+		Messages:      messages,
+	}
+	sendXMLResponse(module.GetID(), module.config, module.sink, messageLog)
+}
+
 func (module *SyslogModule) buildMessageLog(logParts map[string]interface{}) *api.SyslogMessageLogDTO {
-	if logParts["content"].(string) == "X" {
+	content, ok := logParts["content"].(string) // RFC 3164
+	if !ok {
+		content, _ = logParts["message"].(string) // RFC 5424
+	}
+	if content == "X" {
 		return nil
 	}
 	clientParts := strings.Split(logParts["client"].(string), ":")
@@ -84,10 +219,20 @@ func (module *SyslogModule) buildMessageLog(logParts map[string]interface{}) *ap
 	}
 	timestamp := logParts["timestamp"].(time.Time)
 	log.Debugf("Received Syslog message from %s", messageLog.SourceAddress)
-	content := fmt.Sprintf("<%d>%s", logParts["priority"].(int), logParts["content"].(string))
+	priority := logParts["priority"].(int)
+	entry := &syslogEntry{
+		source:   net.ParseIP(messageLog.SourceAddress),
+		facility: priority / 8,
+		severity: priority % 8,
+		message:  content,
+	}
+	entry.host, _ = logParts["hostname"].(string)
+	if !module.rules.applySyslog(entry) {
+		return nil
+	}
 	message := api.SyslogMessageDTO{
 		Timestamp: timestamp.Format(api.TimeFormat),
-		Content:   []byte(base64.StdEncoding.EncodeToString([]byte(content))),
+		Content:   []byte(base64.StdEncoding.EncodeToString([]byte(entry.content()))),
 	}
 	messageLog.AddMessage(message)
 	return messageLog
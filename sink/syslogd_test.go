@@ -1,13 +1,29 @@
 package sink
 
 import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/pem"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
 
 	"gotest.tools/v3/assert"
 )
@@ -52,3 +68,172 @@ func TestSyslogBuildMessageLog(t *testing.T) {
 	logMsg = module.buildMessageLog(logParts)
 	assert.Assert(t, logMsg == nil)
 }
+
+// Creates a self-signed certificate for localhost, valid for servers and clients, and returns the paths of the certificate and its key
+func createTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	certFile := filepath.Join(t.TempDir(), "server.crt")
+	keyFile := filepath.Join(t.TempDir(), "server.key")
+	assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600))
+	assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600))
+	return certFile, keyFile
+}
+
+func getFreeTCPPort(t *testing.T) int {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func (sink *channelSink) waitForSyslog(t *testing.T) string {
+	select {
+	case msg := <-sink.messages:
+		messageLog := api.SyslogMessageLogDTO{}
+		assert.NilError(t, xml.Unmarshal(msg.Content, &messageLog))
+		assert.Equal(t, 1, len(messageLog.Messages))
+		content, err := base64.StdEncoding.DecodeString(string(messageLog.Messages[0].Content))
+		assert.NilError(t, err)
+		return string(content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a Syslog message")
+	}
+	return ""
+}
+
+func TestSyslogTLS(t *testing.T) {
+	certFile, keyFile := createTestCertificate(t)
+	config := &api.MinionConfig{
+		ID:       "minion1",
+		Location: "Test",
+		Syslog:   &api.SyslogConfig{TLSPort: getFreeTCPPort(t), CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile},
+	}
+	sink := &channelSink{messages: make(chan *ipc.SinkMessage, 10)}
+	module := &SyslogModule{}
+	assert.NilError(t, module.Start(config, sink))
+	defer module.Stop()
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NilError(t, err)
+	certPool := x509.NewCertPool()
+	data, err := os.ReadFile(certFile)
+	assert.NilError(t, err)
+	certPool.AppendCertsFromPEM(data)
+	conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", config.Syslog.TLSPort), &tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{certificate},
+		ServerName:   "localhost",
+	})
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	// RFC 5425 uses octet counting framing, usually with RFC 5424 messages
+	msg := "<165>1 2026-10-17T22:14:15.003Z fw01 kernel - - - Deny TCP 10.0.0.1:443"
+	_, err = fmt.Fprintf(conn, "%d %s", len(msg), msg)
+	assert.NilError(t, err)
+	assert.Equal(t, "<165>Deny TCP 10.0.0.1:443", sink.waitForSyslog(t))
+}
+
+func TestSyslogRELP(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:       "minion1",
+		Location: "Test",
+		Syslog:   &api.SyslogConfig{RELPPort: getFreeTCPPort(t)},
+	}
+	sink := &channelSink{messages: make(chan *ipc.SinkMessage, 10)}
+	module := &SyslogModule{}
+	assert.NilError(t, module.Start(config, sink))
+	defer module.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", config.Syslog.RELPPort))
+	assert.NilError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	send := func(txnr int, command string, data string) string {
+		frame := fmt.Sprintf("%d %s %d", txnr, command, len(data))
+		if data != "" {
+			frame += " " + data
+		}
+		_, err := conn.Write([]byte(frame + "\n"))
+		assert.NilError(t, err)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		response, err := readRELPFrame(reader)
+		assert.NilError(t, err)
+		assert.Equal(t, txnr, response.txnr)
+		assert.Equal(t, "rsp", response.command)
+		return string(response.data)
+	}
+
+	msg := "<134>Oct 17 22:14:15 fw01 firewall: session closed"
+	assert.Equal(t, "500 session not opened", send(1, "syslog", msg))
+	assert.Equal(t, relpOffers, send(2, "open", "relp_version=0\nrelp_software=test\ncommands=syslog"))
+	assert.Equal(t, "200 OK", send(3, "syslog", msg))
+	assert.Equal(t, "<134>session closed", sink.waitForSyslog(t))
+	assert.Equal(t, "500 unsupported command rsp", send(4, "rsp", ""))
+	assert.Equal(t, "", send(5, "close", ""))
+	_, err = reader.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+// countingSink counts the messages sent, and it is safe for concurrent use
+type countingSink struct {
+	sent atomic.Int64
+}
+
+func (sink *countingSink) Send(msg *ipc.SinkMessage) error {
+	sink.sent.Add(1)
+	return nil
+}
+
+func TestSyslogStopUnderTraffic(t *testing.T) {
+	config := &api.MinionConfig{
+		ID:         "minion1",
+		Location:   "Test",
+		SyslogPort: getFreeTCPPort(t),
+	}
+	sink := &countingSink{}
+	module := &SyslogModule{}
+	assert.NilError(t, module.Start(config, sink))
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", config.SyslogPort))
+	assert.NilError(t, err)
+	defer conn.Close()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				conn.Write([]byte("<189>Interface eth1 is down"))
+			}
+		}
+	}()
+	assert.Assert(t, waitForCounter(func() float64 { return float64(sink.sent.Load()) }, 100))
+
+	// The module stops while the messages are being received, without panicking
+	module.Stop()
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
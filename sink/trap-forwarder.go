@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// trapTarget represents a downstream receiver for the SNMP traps
+type trapTarget struct {
+	address   string
+	community string
+	conn      net.Conn
+}
+
+// trapForwarder re-emits the received SNMP traps to downstream receivers, like SIEMs or secondary trap daemons
+type trapForwarder struct {
+	targets []*trapTarget
+}
+
+// Creates a trap forwarder for the given downstream receivers
+func newTrapForwarder(forwarders []api.TrapForwarder) (*trapForwarder, error) {
+	fwd := &trapForwarder{}
+	for _, cfg := range forwarders {
+		if err := cfg.IsValid(); err != nil {
+			fwd.stop()
+			return nil, fmt.Errorf("invalid trap forwarder: %v", err)
+		}
+		conn, err := net.Dial("udp", cfg.Address)
+		if err != nil {
+			fwd.stop()
+			return nil, fmt.Errorf("cannot create trap forwarder for %s: %v", cfg.Address, err)
+		}
+		log.Infof("Forwarding SNMP traps to %s", cfg.Address)
+		fwd.targets = append(fwd.targets, &trapTarget{address: cfg.Address, community: cfg.Community, conn: conn})
+	}
+	return fwd, nil
+}
+
+// Sends a given trap to all the downstream receivers, either with its original bytes or re-encoded with the community of each receiver
+func (fwd *trapForwarder) forward(msg []byte, packet *gosnmp.SnmpPacket) {
+	for _, target := range fwd.targets {
+		data := msg
+		if target.community != "" {
+			var err error
+			if data, err = reencodeTrap(packet, target.community); err != nil {
+				log.Warnf("Cannot re-encode SNMP trap for %s: %v", target.address, err)
+				continue
+			}
+		}
+		if _, err := target.conn.Write(data); err != nil {
+			log.Warnf("Cannot forward SNMP trap to %s: %v", target.address, err)
+		}
+	}
+}
+
+// Closes the connections with the downstream receivers
+func (fwd *trapForwarder) stop() {
+	for _, target := range fwd.targets {
+		target.conn.Close()
+	}
+}
+
+// Re-encodes a trap with a given community; SNMPv3 traps are converted to SNMPv2c, and informs to traps, as the responses are not processed
+func reencodeTrap(packet *gosnmp.SnmpPacket, community string) ([]byte, error) {
+	trap := *packet
+	trap.Community = community
+	if trap.Version == gosnmp.Version3 {
+		trap.Version = gosnmp.Version2c
+		trap.MsgFlags = gosnmp.NoAuthNoPriv
+		trap.SecurityModel = 0
+		trap.SecurityParameters = nil
+		trap.ContextEngineID = ""
+		trap.ContextName = ""
+	}
+	if trap.PDUType == gosnmp.InformRequest {
+		trap.PDUType = gosnmp.SNMPv2Trap
+	}
+	return trap.MarshalMsg()
+}
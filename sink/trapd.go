@@ -1,8 +1,10 @@
 package sink
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -15,9 +17,16 @@ import (
 
 // SnmpTrapModule represents the SNMP trap receiver module
 type SnmpTrapModule struct {
-	sink     api.Sink
-	config   *api.MinionConfig
-	listener *gosnmp.TrapListener
+	sink       api.Sink
+	config     *api.MinionConfig
+	conn       *net.UDPConn
+	decoder    *trapDecoder
+	forwarder  *trapForwarder
+	rules      *ruleEngine
+	metrics    *api.Metrics
+	aggregator *aggregator[string, api.TrapDTO]
+	limiter    *rateLimiter
+	state      *listenerState
 }
 
 // GetID gets the ID of the sink module
@@ -25,7 +34,7 @@ func (module *SnmpTrapModule) GetID() string {
 	return "Trap"
 }
 
-// Start initiates a Syslog UDP and TCP receiver
+// Start initiates an SNMP trap and inform receiver on UDP
 func (module *SnmpTrapModule) Start(config *api.MinionConfig, sink api.Sink) error {
 	if config.TrapPort == 0 {
 		log.Warnf("Trap Module disabled")
@@ -34,41 +43,155 @@ func (module *SnmpTrapModule) Start(config *api.MinionConfig, sink api.Sink) err
 
 	log.Infof("Starting SNMP Trap receiver on port UDP %d", config.TrapPort)
 
+	decoder, err := newTrapDecoder(config)
+	if err != nil {
+		return err
+	}
+	rules, err := newRuleEngine(module.GetID(), config, sink)
+	if err != nil {
+		return err
+	}
 	module.config = config
 	module.sink = sink
-	module.listener = gosnmp.NewTrapListener()
-	module.listener.OnNewTrap = module.trapHandler
-	module.listener.Params = gosnmp.Default
-
-	// Test Listener
-	lis, err := createUDPListener(config.TrapPort)
-	if err == nil {
-		lis.Close()
-	} else {
+	module.decoder = decoder
+	module.rules = rules
+	if provider, ok := sink.(api.MetricsProvider); ok {
+		module.metrics = provider.GetMetrics()
+	}
+	module.limiter = newRateLimiter(module.GetID(), config, sink)
+	module.aggregator = newAggregator(module.GetID(), config, module.sendTraps)
+
+	module.state = newListenerState("SNMP trap listener")
+	if module.conn, err = createUDPListener(config.TrapPort); err != nil {
 		return err
 	}
+	var forwarders []api.TrapForwarder
+	if config.Traps != nil {
+		forwarders = config.Traps.Forwarders
+	}
+	if module.forwarder, err = newTrapForwarder(forwarders); err != nil {
+		module.conn.Close()
+		module.conn = nil
+		return err
+	}
+	module.state.setBound()
 
 	// Start Trap Receiver
-	go func() {
-		err := module.listener.Listen(fmt.Sprintf("0.0.0.0:%d", config.TrapPort))
-		if err != nil {
-			log.Errorf("Cannot start SNMP trap listener: %s", err)
+	go func(conn *net.UDPConn) {
+		buffer := make([]byte, 65535)
+		for {
+			size, addr, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				log.Errorf("Cannot read SNMP trap: %v", err)
+				continue
+			}
+			msg := make([]byte, size)
+			copy(msg, buffer[:size])
+			module.processMessage(conn, msg, addr)
 		}
-	}()
+	}(module.conn)
 	return nil
 }
 
+// CheckReadiness returns an error when the trap listener is not bound to its port
+func (module *SnmpTrapModule) CheckReadiness() error {
+	return module.state.check()
+}
+
 // Stop shutdowns the sink module
 func (module *SnmpTrapModule) Stop() {
 	log.Warnf("Stopping SNMP Trap receiver")
-	if module.listener != nil {
-		module.listener.Close()
+	if module.conn != nil {
+		module.conn.Close()
+		module.conn = nil
+	}
+	if module.forwarder != nil {
+		module.forwarder.stop()
+	}
+	if module.aggregator != nil {
+		module.limiter.stop()
+		module.aggregator.stop()
+	}
+}
+
+// RequiresRestart returns true when the trap port, the trap receiver settings or the Sink settings of the module changed
+func (module *SnmpTrapModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return current.TrapPort != updated.TrapPort || !reflect.DeepEqual(current.Traps, updated.Traps) || sinkSettingsChanged(module.GetID(), current, updated)
+}
+
+// Decodes a given message, answering engine discovery requests and acknowledging informs
+func (module *SnmpTrapModule) processMessage(conn *net.UDPConn, msg []byte, addr *net.UDPAddr) {
+	report, err := module.decoder.getDiscoveryReport(msg)
+	if err != nil {
+		log.Warnf("Cannot answer SNMPv3 engine discovery from %s: %v", addr.IP, err)
+		return
+	}
+	if report != nil {
+		log.Debugf("Sending SNMPv3 engine ID to %s", addr.IP)
+		module.reply(conn, report, addr)
+		return
+	}
+	packet, user, reason, err := module.decoder.decode(msg)
+	if err != nil {
+		log.Warnf("Cannot decode SNMP trap from %s: %v", addr.IP, err)
+		if module.metrics != nil {
+			module.metrics.SinkTrapDecodingErrors.WithLabelValues(module.config.ID, user, reason).Inc()
+		}
+		return
+	}
+	inform := packet.PDUType == gosnmp.InformRequest
+	if module.metrics != nil {
+		trapType := "trap"
+		if inform {
+			trapType = "inform"
+		}
+		module.metrics.SinkTrapsReceived.WithLabelValues(module.config.ID, trapType).Inc()
+	}
+	module.trapHandler(packet, msg, addr)
+	module.forwarder.forward(msg, packet)
+	if inform {
+		if err := module.acknowledge(conn, packet, addr); err != nil {
+			log.Errorf("Cannot acknowledge SNMP inform from %s: %v", addr.IP, err)
+			if module.metrics != nil {
+				module.metrics.SinkInformAckFailed.WithLabelValues(module.config.ID).Inc()
+			}
+		}
+	}
+}
+
+// Sends the response PDU for a given inform, with the same request ID and variables, to stop the agent from retransmitting it
+func (module *SnmpTrapModule) acknowledge(conn *net.UDPConn, packet *gosnmp.SnmpPacket, addr *net.UDPAddr) error {
+	response := *packet
+	response.PDUType = gosnmp.GetResponse
+	response.MsgFlags &^= gosnmp.Reportable
+	response.Error = gosnmp.NoError
+	response.ErrorIndex = 0
+	msg, err := response.MarshalMsg()
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteToUDP(msg, addr)
+	return err
+}
+
+// Sends a response to an SNMP agent
+func (module *SnmpTrapModule) reply(conn *net.UDPConn, response []byte, addr *net.UDPAddr) {
+	if _, err := conn.WriteToUDP(response, addr); err != nil {
+		log.Errorf("Cannot send SNMP response to %s: %v", addr.IP, err)
 	}
 }
 
-func (module *SnmpTrapModule) trapHandler(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+func (module *SnmpTrapModule) trapHandler(packet *gosnmp.SnmpPacket, msg []byte, addr *net.UDPAddr) {
 	version := fmt.Sprintf("v%s", packet.Version)
-	log.Debugf("Received SNMP%s trap (type: 0x%X) from %s", version, packet.PDUType, addr.IP)
+	inform := packet.PDUType == gosnmp.InformRequest
+	if inform {
+		log.Debugf("Received SNMP%s inform from %s", version, addr.IP)
+	} else {
+		log.Debugf("Received SNMP%s trap (type: 0x%X) from %s", version, packet.PDUType, addr.IP)
+	}
 
 	trap := api.TrapDTO{
 		AgentAddress: addr.IP.String(),
@@ -77,22 +200,22 @@ func (module *SnmpTrapModule) trapHandler(packet *gosnmp.SnmpPacket, addr *net.U
 		Timestamp:    int64(packet.Timestamp),
 		Community:    packet.Community,
 		Version:      version,
+		Inform:       inform,
 	}
-
-	trapLog := api.TrapLogDTO{
-		Location: module.config.Location,
-		SystemID: module.config.ID,
+	if module.config.Traps != nil && module.config.Traps.IncludeRawMessage {
+		trap.RawMessage = msg
 	}
 
+	var trapAddress string
 	if packet.PDUType == gosnmp.Trap {
 		trap.TrapIdentity = &api.TrapIdentityDTO{
 			EnterpriseID: packet.Enterprise,
 			Generic:      packet.GenericTrap,
 			Specific:     packet.SpecificTrap,
 		}
-		trapLog.TrapAddress = packet.AgentAddress
+		trapAddress = packet.AgentAddress
 	} else {
-		trapLog.TrapAddress = addr.IP.String()
+		trapAddress = addr.IP.String()
 	}
 
 	for _, pdu := range packet.Variables {
@@ -104,7 +227,7 @@ func (module *SnmpTrapModule) trapHandler(packet *gosnmp.SnmpPacket, addr *net.U
 		case ".1.3.6.1.6.3.18.1.3.0":
 			if pdu.Type == gosnmp.IPAddress {
 				ip := net.ParseIP(pdu.Value.(string))
-				trapLog.TrapAddress = ip.String()
+				trapAddress = ip.String()
 			}
 		default:
 			result := tools.GetResultForPDU(pdu, pdu.Name)
@@ -112,7 +235,21 @@ func (module *SnmpTrapModule) trapHandler(packet *gosnmp.SnmpPacket, addr *net.U
 		}
 	}
 
-	trapLog.AddTrap(trap)
+	// The sender can set the agent address, so the rate limits apply to the address of the UDP peer
+	if module.rules.applyTrap(addr.IP, &trap) {
+		module.limiter.submit(addr.IP.String(), func() {
+			module.aggregator.add(trapAddress, trap)
+		})
+	}
+}
+
+func (module *SnmpTrapModule) sendTraps(trapAddress string, traps []api.TrapDTO) {
+	trapLog := api.TrapLogDTO{
+		Location:    module.config.Location,
+		SystemID:    module.config.ID,
+		TrapAddress: trapAddress,
+		Messages:    traps,
+	}
 	sendXMLResponse(module.GetID(), module.config, module.sink, trapLog)
 }
 
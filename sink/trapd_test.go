@@ -0,0 +1,300 @@
+package sink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/agalue/gominion/api"
+	"github.com/agalue/gominion/protobuf/ipc"
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"gotest.tools/v3/assert"
+)
+
+// channelSink forwards the Sink messages to a channel, as they are sent from the receiver goroutines
+type channelSink struct {
+	messages chan *ipc.SinkMessage
+	metrics  *api.Metrics
+}
+
+func (sink *channelSink) Send(msg *ipc.SinkMessage) error {
+	sink.messages <- msg
+	return nil
+}
+
+func (sink *channelSink) GetMetrics() *api.Metrics {
+	return sink.metrics
+}
+
+func (sink *channelSink) waitForTrap(t *testing.T) api.TrapDTO {
+	select {
+	case msg := <-sink.messages:
+		trapLog := api.TrapLogDTO{}
+		assert.NilError(t, xml.Unmarshal(msg.Content, &trapLog))
+		assert.Equal(t, 1, len(trapLog.Messages))
+		return trapLog.Messages[0]
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a trap")
+	}
+	return api.TrapDTO{}
+}
+
+// Starts a trap receiver on a random port, and returns its port
+func startTestTrapModule(t *testing.T, sink api.Sink, traps *api.TrapConfig) int {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NilError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	config := &api.MinionConfig{ID: "minion1", Location: "Test", TrapPort: port, Traps: traps}
+	module := &SnmpTrapModule{}
+	assert.NilError(t, module.Start(config, sink))
+	t.Cleanup(module.Stop)
+	assert.NilError(t, module.CheckReadiness())
+	return port
+}
+
+// Builds an SNMP client; uses SNMPv3 when the USM parameters are provided, or SNMPv2c otherwise
+func buildTestTrapSender(t *testing.T, port int, usm *gosnmp.UsmSecurityParameters) *gosnmp.GoSNMP {
+	sender := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(port),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   2 * time.Second,
+		Retries:   1,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if usm != nil {
+		sender.Version = gosnmp.Version3
+		sender.SecurityModel = gosnmp.UserSecurityModel
+		sender.SecurityParameters = usm
+		sender.MsgFlags = gosnmp.NoAuthNoPriv
+		if usm.AuthenticationProtocol != gosnmp.NoAuth {
+			sender.MsgFlags = gosnmp.AuthNoPriv
+		}
+		if usm.PrivacyProtocol != gosnmp.NoPriv {
+			sender.MsgFlags = gosnmp.AuthPriv
+		}
+	}
+	assert.NilError(t, sender.Connect())
+	t.Cleanup(func() { sender.Conn.Close() })
+	return sender
+}
+
+func buildTestTrap(inform bool) gosnmp.SnmpTrap {
+	return gosnmp.SnmpTrap{
+		IsInform: inform,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9.9.41.2.0.1"},
+			{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: "router1"},
+		},
+	}
+}
+
+func TestSnmpV3Traps(t *testing.T) {
+	sink := &channelSink{messages: make(chan *ipc.SinkMessage, 10), metrics: api.NewMetrics()}
+	port := startTestTrapModule(t, sink, &api.TrapConfig{
+		Users: []api.SnmpV3User{
+			{SecurityName: "admin", AuthProtocol: "SHA", AuthPassphrase: "0p3nNMSv3", PrivacyProtocol: "AES", PrivacyPassphrase: "0p3nNMSv3"},
+			{SecurityName: "legacy", EngineID: "0x8000000001020304", AuthProtocol: "MD5", AuthPassphrase: "0p3nNMSv3"},
+		},
+	})
+
+	// SNMPv2c traps are still accepted
+	sender := buildTestTrapSender(t, port, nil)
+	_, err := sender.SendTrap(buildTestTrap(false))
+	assert.NilError(t, err)
+	trap := sink.waitForTrap(t)
+	assert.Equal(t, "v2c", trap.Version)
+	assert.Equal(t, "public", trap.Community)
+	assert.Assert(t, !trap.Inform)
+
+	// SNMPv2c informs are acknowledged
+	response, err := sender.SendTrap(buildTestTrap(true))
+	assert.NilError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+	trap = sink.waitForTrap(t)
+	assert.Equal(t, "v2c", trap.Version)
+	assert.Assert(t, trap.Inform)
+
+	// SNMPv3 trap with authentication and privacy, from an agent with its own engine ID
+	sender = buildTestTrapSender(t, port, &gosnmp.UsmSecurityParameters{
+		UserName:                 "admin",
+		AuthoritativeEngineID:    "\x80\x00\x00\x00\x01\x0a\x0b\x0c",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "0p3nNMSv3",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "0p3nNMSv3",
+	})
+	_, err = sender.SendTrap(buildTestTrap(false))
+	assert.NilError(t, err)
+	trap = sink.waitForTrap(t)
+	assert.Equal(t, "v3", trap.Version)
+	assert.Equal(t, ".1.3.6.1.4.1.9.9.41.2", trap.TrapIdentity.EnterpriseID)
+
+	// SNMPv3 inform, discovering the engine ID of the receiver, and waiting for the acknowledgement
+	sender = buildTestTrapSender(t, port, &gosnmp.UsmSecurityParameters{
+		UserName:                 "admin",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "0p3nNMSv3",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "0p3nNMSv3",
+	})
+	response, err = sender.SendTrap(buildTestTrap(true))
+	assert.NilError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+	assert.Equal(t, "\x80\x00\x16\xb5\x04minion1", sender.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineID)
+	trap = sink.waitForTrap(t)
+	assert.Equal(t, "v3", trap.Version)
+	assert.Assert(t, trap.Inform)
+
+	received := sink.metrics.SinkTrapsReceived
+	assert.Equal(t, 2.0, testutil.ToFloat64(received.WithLabelValues("minion1", "trap")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(received.WithLabelValues("minion1", "inform")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(sink.metrics.SinkInformAckFailed.WithLabelValues("minion1")))
+
+	// Wrong passphrase, unknown user, and a user restricted to another engine ID
+	for _, user := range []*gosnmp.UsmSecurityParameters{
+		{UserName: "admin", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "wr0ngPassw0rd", PrivacyProtocol: gosnmp.AES, PrivacyPassphrase: "0p3nNMSv3"},
+		{UserName: "guest", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "0p3nNMSv3", PrivacyProtocol: gosnmp.NoPriv},
+		{UserName: "legacy", AuthenticationProtocol: gosnmp.MD5, AuthenticationPassphrase: "0p3nNMSv3", PrivacyProtocol: gosnmp.NoPriv},
+	} {
+		user.AuthoritativeEngineID = "\x80\x00\x00\x00\x01\x0a\x0b\x0c"
+		sender = buildTestTrapSender(t, port, user)
+		_, err = sender.SendTrap(buildTestTrap(false))
+		assert.NilError(t, err)
+	}
+	// Malformed message
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.NilError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0x30, 0x03, 0x02, 0x01, 0x07})
+	assert.NilError(t, err)
+
+	// The errors are counted per configured user and reason, as the user names are chosen by the senders
+	errors := sink.metrics.SinkTrapDecodingErrors
+	assert.Assert(t, waitForCounter(func() float64 {
+		return float64(testutil.CollectAndCount(errors))
+	}, 4))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("minion1", "admin", "auth_failure")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("minion1", "unknown", "unknown_user")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("minion1", "legacy", "not_allowed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("minion1", "unknown", "decode")))
+	assert.Equal(t, 0, len(sink.messages))
+}
+
+// Waits until a counter reaches a given value
+func waitForCounter(counter func() float64, expected float64) bool {
+	for range 50 {
+		if counter() >= expected {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestTrapForwarding(t *testing.T) {
+	receive := func(conn *net.UDPConn) []byte {
+		buffer := make([]byte, 65535)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		size, _, err := conn.ReadFromUDP(buffer)
+		assert.NilError(t, err)
+		return buffer[:size]
+	}
+	raw, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NilError(t, err)
+	defer raw.Close()
+	siem, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NilError(t, err)
+	defer siem.Close()
+
+	sink := &channelSink{messages: make(chan *ipc.SinkMessage, 10), metrics: api.NewMetrics()}
+	port := startTestTrapModule(t, sink, &api.TrapConfig{
+		IncludeRawMessage: true,
+		Users:             []api.SnmpV3User{{SecurityName: "admin", AuthProtocol: "SHA", AuthPassphrase: "0p3nNMSv3"}},
+		Forwarders: []api.TrapForwarder{
+			{Address: raw.LocalAddr().String()},
+			{Address: siem.LocalAddr().String(), Community: "siem"},
+		},
+	})
+	decoder := &gosnmp.GoSNMP{}
+
+	// SNMPv2c traps are forwarded unchanged, and with the community of the receiver
+	sender := buildTestTrapSender(t, port, nil)
+	_, err = sender.SendTrap(buildTestTrap(false))
+	assert.NilError(t, err)
+	trap := sink.waitForTrap(t)
+	original := receive(raw)
+	assert.DeepEqual(t, original, []byte(trap.RawMessage))
+	packet, err := decoder.UnmarshalTrap(receive(siem), false)
+	assert.NilError(t, err)
+	assert.Equal(t, gosnmp.Version2c, packet.Version)
+	assert.Equal(t, "siem", packet.Community)
+	assert.Equal(t, 3, len(packet.Variables))
+
+	// SNMPv3 informs are forwarded as SNMPv2c traps when re-encoded
+	sender = buildTestTrapSender(t, port, &gosnmp.UsmSecurityParameters{
+		UserName:                 "admin",
+		AuthoritativeEngineID:    "\x80\x00\x00\x00\x01\x0a\x0b\x0c",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "0p3nNMSv3",
+		PrivacyProtocol:          gosnmp.NoPriv,
+	})
+	_, err = sender.SendTrap(buildTestTrap(true))
+	assert.NilError(t, err)
+	trap = sink.waitForTrap(t)
+	assert.Assert(t, trap.Inform)
+	assert.DeepEqual(t, receive(raw), []byte(trap.RawMessage))
+	packet, err = decoder.UnmarshalTrap(receive(siem), false)
+	assert.NilError(t, err)
+	assert.Equal(t, gosnmp.Version2c, packet.Version)
+	assert.Equal(t, gosnmp.SNMPv2Trap, packet.PDUType)
+	assert.Equal(t, "siem", packet.Community)
+}
+
+func TestTrapRateLimits(t *testing.T) {
+	sink := &channelSink{messages: make(chan *ipc.SinkMessage, 10), metrics: api.NewMetrics()}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NilError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	config := &api.MinionConfig{
+		ID:          "minion1",
+		Location:    "Test",
+		TrapPort:    port,
+		Aggregation: map[string]api.AggregationConfig{"Trap": {BatchSize: 10, BatchInterval: 60000}},
+		RateLimits:  map[string]api.RateLimitConfig{"Trap": {SourceRate: 0.1, SourceBurst: 2}},
+	}
+	module := &SnmpTrapModule{}
+	assert.NilError(t, module.Start(config, sink))
+	assert.NilError(t, module.CheckReadiness())
+
+	// Each trap takes a token from the bucket of the UDP peer, regardless of the agent address set by the sender
+	sender := buildTestTrapSender(t, port, nil)
+	sender.Version = gosnmp.Version1
+	for i := 1; i <= 5; i++ {
+		trap := buildTestTrap(false)
+		trap.Enterprise = ".1.3.6.1.4.1.9"
+		trap.AgentAddress = fmt.Sprintf("10.0.0.%d", i)
+		_, err := sender.SendTrap(trap)
+		assert.NilError(t, err)
+	}
+	shed := sink.metrics.SinkMsgShed.WithLabelValues("minion1", "Trap", "127.0.0.1")
+	assert.Assert(t, waitForCounter(func() float64 { return testutil.ToFloat64(shed) }, 3))
+
+	// The traps that passed the limits are sent when stopping
+	module.Stop()
+	traps := 0
+	for len(sink.messages) > 0 {
+		trapLog := api.TrapLogDTO{}
+		assert.NilError(t, xml.Unmarshal((<-sink.messages).Content, &trapLog))
+		traps += len(trapLog.Messages)
+	}
+	assert.Equal(t, 2, traps)
+	assert.Equal(t, 3.0, testutil.ToFloat64(shed))
+}
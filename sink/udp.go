@@ -1,10 +1,13 @@
 package sink
 
 import (
+	"errors"
 	"net"
+	"reflect"
 
 	"github.com/agalue/gominion/api"
 	"github.com/agalue/gominion/log"
+	"github.com/agalue/gominion/protobuf/telemetry"
 )
 
 // UDPForwardParser represents org.opennms.netmgt.telemetry.protocols.common.parser.ForwardParser
@@ -13,11 +16,13 @@ const UDPForwardParser = "ForwardParser"
 // UDPForwardModule represents a generic UDP forward module
 // It starts a UDP Listener, and forwards the received data to OpenNMS without alteration
 type UDPForwardModule struct {
-	name     string
-	sink     api.Sink
-	config   *api.MinionConfig
-	conn     *net.UDPConn
-	stopping bool
+	name       string
+	sink       api.Sink
+	config     *api.MinionConfig
+	conn       *net.UDPConn
+	stopping   bool
+	aggregator *aggregator[telemetrySource, *telemetry.TelemetryMessage]
+	limiter    *rateLimiter
 }
 
 // GetID gets the ID of the sink module
@@ -37,17 +42,22 @@ func (module *UDPForwardModule) Start(config *api.MinionConfig, sink api.Sink) e
 	module.stopping = false
 	module.sink = sink
 	module.config = config
+	module.limiter = newRateLimiter(module.name, config, sink)
+	module.aggregator = newTelemetryAggregator(module.name, module.name, config, sink)
 
 	module.conn, err = createUDPListener(listener.Port)
 	if err != nil {
 		return err
 	}
 	log.Infof("Starting %s receiver on port UDP %d", module.name, listener.Port)
-	go func() {
+	go func(conn *net.UDPConn) {
 		payload := make([]byte, 1024)
 		for {
-			size, pktAddr, err := module.conn.ReadFromUDP(payload)
+			size, pktAddr, err := conn.ReadFromUDP(payload)
 			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
 				if !module.stopping {
 					log.Errorf("%s cannot read from UDP: %s", module.name, err)
 				}
@@ -56,21 +66,29 @@ func (module *UDPForwardModule) Start(config *api.MinionConfig, sink api.Sink) e
 			payloadCut := make([]byte, size)
 			copy(payloadCut, payload[0:size])
 			log.Debugf("Received %d bytes from %s", size, pktAddr)
-			messages := make([][]byte, 1)
-			messages[0] = payloadCut
-			if bytes := wrapMessageToTelemetry(module.config, pktAddr.IP.String(), uint32(pktAddr.Port), messages); bytes != nil {
-				sendBytes(module.GetID(), module.config, module.sink, bytes)
-			}
+			source := telemetrySource{address: pktAddr.IP.String(), port: uint32(pktAddr.Port)}
+			addTelemetryMessages(module.limiter, module.aggregator, source, [][]byte{payloadCut})
 		}
-	}()
+	}(module.conn)
 	return nil
 }
 
+// RequiresRestart returns true when the listener or the Sink settings of the module changed
+func (module *UDPForwardModule) RequiresRestart(current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return !reflect.DeepEqual(current.GetListener(module.name), updated.GetListener(module.name)) ||
+		sinkSettingsChanged(module.name, current, updated)
+}
+
 // Stop shutdowns the sink module
 func (module *UDPForwardModule) Stop() {
 	log.Warnf("Stopping %s receiver", module.name)
 	module.stopping = true
 	if module.conn != nil {
 		module.conn.Close()
+		module.conn = nil
+	}
+	if module.aggregator != nil {
+		module.limiter.stop()
+		module.aggregator.stop()
 	}
 }
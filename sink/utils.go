@@ -1,9 +1,13 @@
 package sink
 
 import (
-	"encoding/xml"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/agalue/gominion/api"
@@ -15,15 +19,16 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-func sendXMLResponse(moduleID string, config *api.MinionConfig, sink api.Sink, object interface{}) {
-	bytes, err := xml.MarshalIndent(object, "", "   ")
+func sendXMLResponse(moduleID string, config *api.MinionConfig, sink api.Sink, object interface{}) error {
+	bytes, err := api.MarshalXMLPayload(object)
 	if err != nil {
 		log.Errorf("Cannot parse Sink API response: %v", err)
+		return err
 	}
-	sendBytes(moduleID, config, sink, bytes)
+	return sendBytes(moduleID, config, sink, bytes)
 }
 
-func sendBytes(moduleID string, config *api.MinionConfig, sink api.Sink, bytes []byte) {
+func sendBytes(moduleID string, config *api.MinionConfig, sink api.Sink, bytes []byte) error {
 	msg := &ipc.SinkMessage{
 		MessageId: uuid.New().String(),
 		ModuleId:  moduleID,
@@ -32,27 +37,38 @@ func sendBytes(moduleID string, config *api.MinionConfig, sink api.Sink, bytes [
 		Content:   bytes,
 	}
 	if sink == nil {
-		return
+		return nil
 	}
 	if err := sink.Send(msg); err != nil {
 		log.Errorf("%s cannot send message via Sink API: %v", moduleID, err)
+		return err
 	}
+	return nil
 }
 
 func wrapMessageToTelemetry(config *api.MinionConfig, sourceAddress string, sourcePort uint32, data [][]byte) []byte {
+	return wrapTelemetryMessages(config, sourceAddress, sourcePort, newTelemetryMessages(data))
+}
+
+func newTelemetryMessages(data [][]byte) []*telemetry.TelemetryMessage {
 	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	messages := make([]*telemetry.TelemetryMessage, len(data))
+	for i := 0; i < len(data); i++ {
+		messages[i] = &telemetry.TelemetryMessage{
+			Timestamp: &now,
+			Bytes:     data[i],
+		}
+	}
+	return messages
+}
+
+func wrapTelemetryMessages(config *api.MinionConfig, sourceAddress string, sourcePort uint32, messages []*telemetry.TelemetryMessage) []byte {
 	logMsg := &telemetry.TelemetryMessageLog{
 		SystemId:      &config.ID,
 		Location:      &config.Location,
 		SourceAddress: &sourceAddress,
 		SourcePort:    &sourcePort,
-		Message:       make([]*telemetry.TelemetryMessage, len(data)),
-	}
-	for i := 0; i < len(data); i++ {
-		logMsg.Message[i] = &telemetry.TelemetryMessage{
-			Timestamp: &now,
-			Bytes:     data[i],
-		}
+		Message:       messages,
 	}
 	bytes, err := proto.Marshal(logMsg)
 	if err != nil {
@@ -62,6 +78,38 @@ func wrapMessageToTelemetry(config *api.MinionConfig, sourceAddress string, sour
 	return bytes
 }
 
+// telemetrySource represents the source of a telemetry message
+type telemetrySource struct {
+	address string
+	port    uint32
+}
+
+// Creates an aggregator that sends the telemetry messages from the same source within a single TelemetryMessageLog.
+// The aggregation settings are based on the module ID, while the messages are sent to the given Sink module ID.
+func newTelemetryAggregator(moduleID string, sinkModuleID string, config *api.MinionConfig, sink api.Sink) *aggregator[telemetrySource, *telemetry.TelemetryMessage] {
+	return newAggregator(moduleID, config, func(source telemetrySource, messages []*telemetry.TelemetryMessage) {
+		if bytes := wrapTelemetryMessages(config, source.address, source.port, messages); bytes != nil {
+			sendBytes(sinkModuleID, config, sink, bytes)
+		}
+	})
+}
+
+// Adds the telemetry messages received on a given packet to an aggregator, through a given rate limiter.
+// Each packet counts as a single message for the rate limits.
+func addTelemetryMessages(limiter *rateLimiter, aggregator *aggregator[telemetrySource, *telemetry.TelemetryMessage], source telemetrySource, data [][]byte) {
+	messages := newTelemetryMessages(data)
+	limiter.submit(source.address, func() {
+		aggregator.add(source, messages...)
+	})
+}
+
+// Returns true when the aggregation, the rate limits or the rules of a given Sink module changed
+func sinkSettingsChanged(moduleID string, current *api.MinionConfig, updated *api.MinionConfig) bool {
+	return !reflect.DeepEqual(current.GetAggregation(moduleID), updated.GetAggregation(moduleID)) ||
+		!reflect.DeepEqual(current.GetRateLimit(moduleID), updated.GetRateLimit(moduleID)) ||
+		!reflect.DeepEqual(current.GetRules(moduleID), updated.GetRules(moduleID))
+}
+
 func createUDPListener(port int) (*net.UDPConn, error) {
 	udpAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -73,3 +121,70 @@ func createUDPListener(port int) (*net.UDPConn, error) {
 	}
 	return conn, nil
 }
+
+// Creates the TLS configuration for a server; when a CA is provided, the clients must present a certificate signed by it
+func newServerTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load server certificate: %v", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		data, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA certificate: %v", err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("invalid client CA certificate %s", clientCAFile)
+		}
+		cfg.ClientCAs = certPool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// listenerState tracks a listener that binds its port or serves requests asynchronously, to report its readiness
+type listenerState struct {
+	name  string
+	bound bool
+	err   error
+	mutex *sync.Mutex
+}
+
+func newListenerState(name string) *listenerState {
+	return &listenerState{name: name, mutex: new(sync.Mutex)}
+}
+
+// Marks the listener as bound to its port.
+func (s *listenerState) setBound() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bound = true
+}
+
+// Marks the listener as failed.
+func (s *listenerState) setError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.err = err
+}
+
+// Returns an error when the listener failed or is not bound to its port yet.
+func (s *listenerState) check() error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return fmt.Errorf("%s failed: %v", s.name, s.err)
+	}
+	if !s.bound {
+		return fmt.Errorf("%s not listening yet", s.name)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package sink
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/agalue/gominion/api"
+)
+
+// Parsers represents all the supported listener parsers
+var Parsers = []string{UDPNetflow5Parser, UDPNetflow9Parser, UDPIpfixParser, UDPSFlowParser, UDPForwardParser, NxosGrpcParser}
+
+// listenerParsers represents the parser expected by each Sink module that handles a listener by name
+var listenerParsers = map[string]string{
+	"Netflow-5": UDPNetflow5Parser,
+	"Netflow-9": UDPNetflow9Parser,
+	"IPFIX":     UDPIpfixParser,
+	"SFlow":     UDPSFlowParser,
+	"Graphite":  UDPForwardParser,
+}
+
+// ValidateListeners returns all the problems found with the listeners, and with the ports used by the Sink modules and the HTTP servers
+func ValidateListeners(config *api.MinionConfig) []error {
+	errs := make([]error, 0)
+	owners := make(map[string]string)
+	usePort := func(protocol string, port int, owner string) {
+		key := fmt.Sprintf("%d/%s", port, protocol)
+		if current, ok := owners[key]; ok {
+			errs = append(errs, fmt.Errorf("port %s used by %s and %s", key, current, owner))
+			return
+		}
+		owners[key] = owner
+	}
+	if config.SyslogPort > 0 {
+		usePort("udp", config.SyslogPort, "syslogPort")
+		usePort("tcp", config.SyslogPort, "syslogPort")
+	}
+	if config.Syslog != nil {
+		if config.Syslog.TLSPort > 0 {
+			usePort("tcp", config.Syslog.TLSPort, "syslog.tlsPort")
+		}
+		if config.Syslog.RELPPort > 0 {
+			usePort("tcp", config.Syslog.RELPPort, "syslog.relpPort")
+		}
+	}
+	if config.TrapPort > 0 {
+		usePort("udp", config.TrapPort, "trapPort")
+	}
+	if config.StatsPort > 0 {
+		usePort("tcp", config.StatsPort, "statsPort")
+	}
+	if config.AdminPort > 0 && config.AdminPort != config.StatsPort {
+		usePort("tcp", config.AdminPort, "adminPort")
+	}
+	names := slices.Sorted(maps.Keys(listenerParsers))
+	nxos := 0
+	for _, listener := range config.Listeners {
+		if !slices.ContainsFunc(Parsers, listener.Is) {
+			errs = append(errs, fmt.Errorf("unknown parser %s on listener %s%s", listener.Parser, listener.Name, api.Suggestion(listener.GetParser(), Parsers)))
+			continue
+		}
+		if listener.Is(NxosGrpcParser) {
+			if nxos++; nxos > 1 {
+				errs = append(errs, fmt.Errorf("listener %s ignored, only one %s listener is supported", listener.Name, NxosGrpcParser))
+			}
+			usePort("tcp", listener.Port, "listener "+listener.Name)
+			continue
+		}
+		parser, ok := listenerParsers[listener.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("listener %s is not handled by any Sink module%s", listener.Name, api.Suggestion(listener.Name, names)))
+			continue
+		}
+		if !listener.Is(parser) {
+			errs = append(errs, fmt.Errorf("listener %s requires parser %s", listener.Name, parser))
+		}
+		usePort("udp", listener.Port, "listener "+listener.Name)
+	}
+	return errs
+}
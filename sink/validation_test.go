@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"testing"
+
+	"github.com/agalue/gominion/api"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateListeners(t *testing.T) {
+	config := &api.MinionConfig{
+		TrapPort:   1162,
+		SyslogPort: 1514,
+		StatsPort:  8181,
+		AdminPort:  8181,
+		Listeners: []api.MinionListener{
+			{Name: "Netflow-5", Port: 8877, Parser: "Netflow5UdpParser"},
+			{Name: "IPFIX", Port: 4730, Parser: "org.opennms.netmgt.telemetry.protocols.netflow.parser.IpfixUdpParser"},
+			{Name: "NXOS", Port: 50000, Parser: "NxosGrpcParser"},
+		},
+	}
+	assert.Equal(t, 0, len(ValidateListeners(config)))
+
+	config.Listeners = append(config.Listeners,
+		api.MinionListener{Name: "Graphite", Port: 1162, Parser: "ForwardParser"},
+		api.MinionListener{Name: "Netflow-9", Port: 4729, Parser: "Netflow9UdpParsr"},
+		api.MinionListener{Name: "SFLow", Port: 6343, Parser: "SFlowUdpParser"},
+		api.MinionListener{Name: "Netflow-9", Port: 4729, Parser: "IpfixUdpParser"},
+	)
+	errs := ValidateListeners(config)
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	assert.DeepEqual(t, []string{
+		"port 1162/udp used by trapPort and listener Graphite",
+		"unknown parser Netflow9UdpParsr on listener Netflow-9 (did you mean Netflow9UdpParser?)",
+		"listener SFLow is not handled by any Sink module (did you mean SFlow?)",
+		"listener Netflow-9 requires parser Netflow9UdpParser",
+	}, messages)
+
+	config.Listeners = config.Listeners[:3]
+	config.Syslog = &api.SyslogConfig{TLSPort: 6514, RELPPort: 50000}
+	errs = ValidateListeners(config)
+	assert.Equal(t, 1, len(errs))
+	assert.Error(t, errs[0], "port 50000/tcp used by syslog.relpPort and listener NXOS")
+}
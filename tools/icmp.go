@@ -1,13 +1,16 @@
 package tools
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-ping/ping"
 )
 
+// Ping sends an ICMP echo request to a given address, and returns the round trip time.
+// The ping is stopped when the context is cancelled.
 // TODO add logic for handling retries
-func Ping(addr string, timeout time.Duration) (time.Duration, error) {
+func Ping(ctx context.Context, addr string, timeout time.Duration) (time.Duration, error) {
 	pinger, err := ping.NewPinger(addr)
 	if err != nil {
 		return 0, err
@@ -15,9 +18,21 @@ func Ping(addr string, timeout time.Duration) (time.Duration, error) {
 	pinger.Count = 1
 	pinger.Timeout = timeout
 	pinger.SetPrivileged(true)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pinger.Stop()
+		case <-done:
+		}
+	}()
 	if err := pinger.Run(); err != nil {
 		return 0, err
 	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	stats := pinger.Statistics()
 	return stats.AvgRtt, err
 }
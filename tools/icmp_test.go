@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -12,7 +13,7 @@ func TestPing(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping TestPing")
 	}
-	duration, err := Ping("8.8.8.8", 2*time.Second)
+	duration, err := Ping(context.Background(), "8.8.8.8", 2*time.Second)
 	assert.NilError(t, err)
 	fmt.Printf("Duration %d microseconds\n", duration.Microseconds())
 	assert.Assert(t, duration.Microseconds() > 0)
@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonPatchOperation represents a single RFC 6902 operation
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to a given JSON document and returns the patched document
+func ApplyJSONPatch(document []byte, patch []byte) ([]byte, error) {
+	var doc interface{}
+	if err := decodeJSON(document, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON document: %v", err)
+	}
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON patch: %v", err)
+	}
+	var err error
+	for _, op := range operations {
+		if doc, err = applyJSONPatchOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("cannot apply %s operation on %s: %v", op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func applyJSONPatchOperation(doc interface{}, op jsonPatchOperation) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		var value interface{}
+		if err := decodeJSON(op.Value, &value); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, op.Path)
+		return doc, err
+	case "replace":
+		var value interface{}
+		if err := decodeJSON(op.Value, &value); err != nil {
+			return nil, err
+		}
+		doc, _, err := jsonPointerRemove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "move":
+		doc, value, err := jsonPointerRemove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "copy":
+		value, err := jsonPointerGet(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, deepCopyJSON(value))
+	case "test":
+		var expected interface{}
+		if err := decodeJSON(op.Value, &expected); err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unsupported operation")
+}
+
+// Decodes JSON preserving numbers as they were received
+func decodeJSON(data []byte, value *interface{}) error {
+	if len(data) == 0 {
+		*value = nil
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(value)
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func parseArrayIndex(token string, size int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return size, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %s", token)
+	}
+	if index > size || (!allowEnd && index == size) {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+func jsonPointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %s not found", token)
+			}
+			current = value
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse %s", token)
+		}
+	}
+	return current, nil
+}
+
+// Adds a value on the given location, and returns the updated document (as arrays might be reallocated)
+func jsonPointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonUpdateParent(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("cannot add member %s", token)
+	})
+}
+
+// Removes the value from the given location, and returns the updated document and the removed value
+func jsonPointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err = jsonUpdateParent(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %s not found", token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove member %s", token)
+	})
+	return doc, removed, err
+}
+
+// Walks the document to the parent of the last token, applies the update, and re-attaches the result to the tree
+func jsonUpdateParent(doc interface{}, tokens []string, update func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(doc, tokens[0])
+	}
+	token := tokens[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("member %s not found", token)
+		}
+		updated, err := jsonUpdateParent(child, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = updated
+		return node, nil
+	case []interface{}:
+		index, err := parseArrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := jsonUpdateParent(node[index], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	}
+	return nil, fmt.Errorf("cannot traverse %s", token)
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(node))
+		for k, v := range node {
+			result[k] = deepCopyJSON(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(node))
+		for i, v := range node {
+			result[i] = deepCopyJSON(v)
+		}
+		return result
+	}
+	return value
+}
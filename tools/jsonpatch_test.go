@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := []byte(`{"name":"trapd","port":1162,"users":[{"name":"u1"},{"name":"u2"}],"tags":{"a":"b"}}`)
+	patch := []byte(`[
+		{"op":"replace","path":"/port","value":10162},
+		{"op":"add","path":"/users/1","value":{"name":"u3"}},
+		{"op":"remove","path":"/users/0"},
+		{"op":"add","path":"/users/-","value":{"name":"u4"}},
+		{"op":"copy","from":"/name","path":"/tags/module"},
+		{"op":"move","from":"/tags/a","path":"/tags/c"},
+		{"op":"test","path":"/tags/c","value":"b"}
+	]`)
+	result, err := ApplyJSONPatch(document, patch)
+	assert.NilError(t, err)
+
+	var actual, expected interface{}
+	assert.NilError(t, json.Unmarshal(result, &actual))
+	assert.NilError(t, json.Unmarshal([]byte(`{"name":"trapd","port":10162,"users":[{"name":"u3"},{"name":"u2"},{"name":"u4"}],"tags":{"c":"b","module":"trapd"}}`), &expected))
+	assert.DeepEqual(t, expected, actual)
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	document := []byte(`{"users":[]}`)
+
+	_, err := ApplyJSONPatch(document, []byte(`[{"op":"remove","path":"/missing"}]`))
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = ApplyJSONPatch(document, []byte(`[{"op":"replace","path":"/users/0","value":1}]`))
+	assert.ErrorContains(t, err, "out of bounds")
+
+	_, err = ApplyJSONPatch(document, []byte(`[{"op":"test","path":"/users","value":[1]}]`))
+	assert.ErrorContains(t, err, "test failed")
+
+	_, err = ApplyJSONPatch(document, []byte(`[{"op":"unknown","path":"/users"}]`))
+	assert.ErrorContains(t, err, "unsupported")
+
+	result, err := ApplyJSONPatch(document, []byte(`[{"op":"replace","path":"","value":{"id":1}}]`))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"id":1}`, string(result))
+}